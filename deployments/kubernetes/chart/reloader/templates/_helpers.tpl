@@ -161,6 +161,25 @@ the rule set is defined once. Expects the root context ($) as its argument.
       - get
       - update
       - patch
+  - apiGroups:
+      - "apps"
+    resources:
+      - replicasets
+    verbs:
+      - get
+      - update
+      - patch
+{{- end }}
+{{- if .Capabilities.APIVersions.Has "serving.knative.dev/v1" }}
+  - apiGroups:
+      - "serving.knative.dev"
+    resources:
+      - services
+    verbs:
+      - list
+      - get
+      - update
+      - patch
 {{- end }}
   - apiGroups:
       - "apps"
@@ -180,6 +199,8 @@ the rule set is defined once. Expects the root context ($) as its argument.
     verbs:
       - list
       - get
+      - update
+      - patch
   - apiGroups:
       - "batch"
     resources:
@@ -189,6 +210,7 @@ the rule set is defined once. Expects the root context ($) as its argument.
       - delete
       - list
       - get
+      - patch
 {{- if .Values.reloader.enableHA }}
   - apiGroups:
       - "coordination.k8s.io"
@@ -209,6 +231,142 @@ the rule set is defined once. Expects the root context ($) as its argument.
       - list
       - get
       - watch
+{{- end}}
+{{- if .Values.reloader.enableExternalSecretsIntegration }}
+  - apiGroups:
+      - "external-secrets.io"
+    resources:
+      - externalsecrets
+    verbs:
+      - list
+      - get
+      - watch
+{{- end}}
+{{- if .Values.reloader.enableCertManagerIntegration }}
+  - apiGroups:
+      - "cert-manager.io"
+    resources:
+      - certificates
+    verbs:
+      - list
+      - get
+      - watch
+{{- end}}
+{{- if .Values.reloader.enableReloaderPolicies }}
+  - apiGroups:
+      - "reloader.stakater.com"
+    resources:
+      - reloaderpolicies
+    verbs:
+      - list
+      - get
+      - watch
+  - apiGroups:
+      - "reloader.stakater.com"
+    resources:
+      - reloaderpolicies/status
+    verbs:
+      - update
+      - patch
+{{- end}}
+{{- if .Values.reloader.watchImagePullSecrets }}
+  - apiGroups:
+      - ""
+    resources:
+      - serviceaccounts
+    verbs:
+      - list
+      - get
+      - watch
+{{- end}}
+{{- if or .Values.reloader.enablePodExec .Values.reloader.enablePodEviction }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods
+    verbs:
+      - list
+      - get
+{{- end}}
+{{- if .Values.reloader.enablePodExec }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods/exec
+    verbs:
+      - create
+{{- end}}
+{{- if .Values.reloader.enablePodEviction }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods/eviction
+    verbs:
+      - create
+{{- end}}
+{{- if .Values.reloader.reloadUnmanagedWorkloads }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods
+    verbs:
+      - list
+      - get
+      - watch
+      - create
+  - apiGroups:
+      - ""
+    resources:
+      - pods/eviction
+    verbs:
+      - create
+  - apiGroups:
+      - "apps"
+    resources:
+      - replicasets
+    verbs:
+      - list
+      - get
+      - update
+      - patch
+{{- end}}
+{{- if .Values.reloader.gitopsSync }}
+  - apiGroups:
+      - "helm.toolkit.fluxcd.io"
+    resources:
+      - helmreleases
+    verbs:
+      - get
+      - patch
+  - apiGroups:
+      - "argoproj.io"
+    resources:
+      - applications
+    verbs:
+      - get
+      - update
+{{- end}}
+{{- if and .Values.reloader.followGenerations .Values.reloader.pruneGenerations }}
+  - apiGroups:
+      - ""
+    resources:
+      - configmaps
+      - secrets
+    verbs:
+      - delete
+  - apiGroups:
+      - ""
+    resources:
+      - pods
+      - serviceaccounts
+    verbs:
+      - list
+  - apiGroups:
+      - "apps"
+    resources:
+      - replicasets
+    verbs:
+      - list
 {{- end}}
   - apiGroups:
       - ""
@@ -2,11 +2,12 @@ package alert
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/parnurzeal/gorequest"
 	"github.com/sirupsen/logrus"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
 )
 
 type AlertSink string
@@ -20,23 +21,18 @@ const (
 
 // function to send alert msg to webhook service
 func SendWebhookAlert(msg string) {
-	webhook_url, ok := os.LookupEnv("ALERT_WEBHOOK_URL")
-	if !ok {
-		logrus.Error("ALERT_WEBHOOK_URL env variable not provided")
+	webhook_url := strings.TrimSpace(options.AlertWebhookURL)
+	if webhook_url == "" {
+		logrus.Error("alert-webhook-url not provided")
 		return
 	}
-	webhook_url = strings.TrimSpace(webhook_url)
-	alert_sink := os.Getenv("ALERT_SINK")
-	alert_sink = strings.ToLower(strings.TrimSpace(alert_sink))
+	alert_sink := strings.ToLower(strings.TrimSpace(options.AlertSink))
 
 	// Provision to add Proxy to reach webhook server if required
-	webhook_proxy := os.Getenv("ALERT_WEBHOOK_PROXY")
-	webhook_proxy = strings.TrimSpace(webhook_proxy)
+	webhook_proxy := strings.TrimSpace(options.AlertWebhookProxy)
 
 	// Provision to add Additional information in the alert. e.g ClusterName
-	alert_additional_info, ok := os.LookupEnv("ALERT_ADDITIONAL_INFO")
-	if ok {
-		alert_additional_info = strings.TrimSpace(alert_additional_info)
+	if alert_additional_info := strings.TrimSpace(options.AlertAdditionalInfo); alert_additional_info != "" {
 		msg = fmt.Sprintf("%s : %s", alert_additional_info, msg)
 	}
 
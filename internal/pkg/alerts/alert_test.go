@@ -9,6 +9,8 @@ import (
 	"github.com/sirupsen/logrus"
 	logrustest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
 )
 
 // TestSendWebhookAlert_LogsSendErrors is a regression test for #949: a failing
@@ -24,8 +26,10 @@ func TestSendWebhookAlert_LogsSendErrors(t *testing.T) {
 	hook := logrustest.NewGlobal()
 	defer hook.Reset()
 
-	t.Setenv("ALERT_WEBHOOK_URL", server.URL)
-	t.Setenv("ALERT_SINK", string(AlertSinkTeams))
+	webhookURL, sink := options.AlertWebhookURL, options.AlertSink
+	defer func() { options.AlertWebhookURL, options.AlertSink = webhookURL, sink }()
+	options.AlertWebhookURL = server.URL
+	options.AlertSink = string(AlertSinkTeams)
 
 	SendWebhookAlert("test message")
 
@@ -0,0 +1,116 @@
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	patchtypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+var (
+	// HelmReleaseResource is the API resource of Flux HelmReleases
+	HelmReleaseResource = schema.GroupVersionResource{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"}
+	// ArgoCDApplicationResource is the API resource of Argo CD Applications
+	ArgoCDApplicationResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+)
+
+// FluxReconcileRequestAnnotation is the annotation requesting Flux to reconcile a resource right away
+const FluxReconcileRequestAnnotation = "reconcile.fluxcd.io/requestedAt"
+
+// RequestHelmReleaseReconcile sets the given value in the Helm values of a Flux HelmRelease and requests its
+// reconciliation, so that helm-controller upgrades the release with the new value
+func RequestHelmReleaseReconcile(clients kube.Clients, namespace, name, valuesKey, valueName, value string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{FluxReconcileRequestAnnotation: time.Now().Format(time.RFC3339Nano)},
+		},
+		"spec": map[string]any{
+			"values": map[string]any{valuesKey: map[string]string{valueName: value}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clients.DynamicClient.Resource(HelmReleaseResource).Namespace(namespace).Patch(context.TODO(), name, patchtypes.MergePatchType, patch, meta_v1.PatchOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// SyncArgoCDApplication sets the given value as a Helm parameter of an Argo CD Application and starts a sync of the
+// application unless an operation is already running. Applications without Helm source can't be given the value.
+func SyncArgoCDApplication(clients kube.Clients, namespace, name, valuesKey, valueName, value string) error {
+	resource := clients.DynamicClient.Resource(ArgoCDApplicationResource).Namespace(namespace)
+	application, err := resource.Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if err := setArgoCDHelmParameter(application, valuesKey+"."+valueName, value); err != nil {
+		return fmt.Errorf("application '%s' in namespace '%s': %w", name, namespace, err)
+	}
+
+	if _, found := application.Object["operation"]; !found {
+		application.Object["operation"] = map[string]any{
+			"initiatedBy": map[string]any{"username": "Reloader"},
+			"sync":        map[string]any{},
+		}
+	}
+
+	_, err = resource.Update(context.TODO(), application, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// setArgoCDHelmParameter sets a Helm parameter in the first Helm source of an Argo CD Application, which is either
+// its single source or one of its multiple sources
+func setArgoCDHelmParameter(application *unstructured.Unstructured, name, value string) error {
+	if source, found, _ := unstructured.NestedMap(application.Object, "spec", "source"); found {
+		if !isArgoCDHelmSource(source) {
+			return fmt.Errorf("source is not a Helm chart")
+		}
+		setHelmParameter(source, name, value)
+		return unstructured.SetNestedMap(application.Object, source, "spec", "source")
+	}
+
+	sources, _, _ := unstructured.NestedSlice(application.Object, "spec", "sources")
+	for i := range sources {
+		source, ok := sources[i].(map[string]any)
+		if !ok || !isArgoCDHelmSource(source) {
+			continue
+		}
+		setHelmParameter(source, name, value)
+		return unstructured.SetNestedSlice(application.Object, sources, "spec", "sources")
+	}
+	return fmt.Errorf("no source is a Helm chart")
+}
+
+// isArgoCDHelmSource checks whether an Argo CD Application source is rendered with Helm
+func isArgoCDHelmSource(source map[string]any) bool {
+	_, isChart := source["chart"]
+	_, hasHelm := source["helm"]
+	return isChart || hasHelm
+}
+
+// setHelmParameter adds or replaces a Helm parameter of an Argo CD Application source
+func setHelmParameter(source map[string]any, name, value string) {
+	helm, _ := source["helm"].(map[string]any)
+	if helm == nil {
+		helm = make(map[string]any)
+		source["helm"] = helm
+	}
+	parameters, _ := helm["parameters"].([]any)
+
+	parameter := map[string]any{"name": name, "value": value, "forceString": true}
+	for i := range parameters {
+		if existing, ok := parameters[i].(map[string]any); ok && existing["name"] == name {
+			parameters[i] = parameter
+			return
+		}
+	}
+	helm["parameters"] = append(parameters, parameter)
+}
@@ -0,0 +1,100 @@
+package callbacks_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func createTestArgoCDApplication(spec map[string]any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]any{"name": "web", "namespace": "argocd"},
+		"spec":       spec,
+	}}
+}
+
+func TestSyncArgoCDApplication(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       map[string]any
+		sourcePath []string
+		wantErr    bool
+	}{
+		{
+			name: "Helm chart source",
+			spec: map[string]any{
+				"source": map[string]any{
+					"chart": "web",
+					"helm": map[string]any{
+						"parameters": []any{
+							map[string]any{"name": "replicas", "value": "2"},
+							map[string]any{"name": "reloader.STAKATER_WEB_CONFIG_CONFIGMAP", "value": "old"},
+						},
+					},
+				},
+			},
+			sourcePath: []string{"spec", "source"},
+		},
+		{
+			name: "Multiple sources",
+			spec: map[string]any{
+				"sources": []any{
+					map[string]any{"path": "manifests"},
+					map[string]any{"chart": "web"},
+				},
+			},
+		},
+		{
+			name:    "Directory source",
+			spec:    map[string]any{"source": map[string]any{"path": "manifests"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := kube.Clients{DynamicClient: fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), createTestArgoCDApplication(tt.spec))}
+
+			err := callbacks.SyncArgoCDApplication(clients, "argocd", "web", "reloader", "STAKATER_WEB_CONFIG_CONFIGMAP", "sha")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			result, err := clients.DynamicClient.Resource(callbacks.ArgoCDApplicationResource).Namespace("argocd").Get(context.TODO(), "web", metav1.GetOptions{})
+			assert.NoError(t, err)
+
+			var source map[string]any
+			if tt.sourcePath != nil {
+				source, _, _ = unstructured.NestedMap(result.Object, tt.sourcePath...)
+			} else {
+				sources, _, _ := unstructured.NestedSlice(result.Object, "spec", "sources")
+				assert.NotContains(t, sources[0], "helm", "Sources not rendered with Helm should be left untouched")
+				source = sources[1].(map[string]any)
+			}
+			parameters, _, _ := unstructured.NestedSlice(source, "helm", "parameters")
+			found := 0
+			for _, parameter := range parameters {
+				if parameter.(map[string]any)["name"] == "reloader.STAKATER_WEB_CONFIG_CONFIGMAP" {
+					assert.Equal(t, "sha", parameter.(map[string]any)["value"])
+					found++
+				}
+			}
+			assert.Equal(t, 1, found, "Parameter should be set exactly once")
+
+			_, syncStarted, _ := unstructured.NestedMap(result.Object, "operation", "sync")
+			assert.True(t, syncStarted, "Application sync should be started")
+		})
+	}
+}
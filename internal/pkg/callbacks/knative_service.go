@@ -0,0 +1,289 @@
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	patchtypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// KnativeServiceResource is the API resource of Knative Services
+var KnativeServiceResource = schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "services"}
+
+// KnativeService is the part of a Knative Service used by Reloader. Knative Serving has no typed client in this
+// project, services are read with the dynamic client and only their revision template is written back.
+type KnativeService struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	Spec               KnativeServiceSpec `json:"spec"`
+}
+
+// KnativeServiceSpec holds the revision template of a Knative Service. The revision spec inlines a pod spec, its
+// Knative specific fields are not needed to find references and are ignored.
+type KnativeServiceSpec struct {
+	Template v1.PodTemplateSpec `json:"template"`
+}
+
+// DeepCopyObject implements runtime.Object
+func (s *KnativeService) DeepCopyObject() runtime.Object {
+	out := &KnativeService{TypeMeta: s.TypeMeta}
+	s.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	s.Spec.Template.DeepCopyInto(&out.Spec.Template)
+	return out
+}
+
+// toKnativeService converts a Knative Service returned by the dynamic client
+func toKnativeService(item *unstructured.Unstructured) (*KnativeService, error) {
+	service := &KnativeService{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), service); err != nil {
+		return nil, err
+	}
+	if service.Spec.Template.Annotations == nil {
+		service.Spec.Template.Annotations = make(map[string]string)
+	}
+	return service, nil
+}
+
+// GetKnativeServiceItem returns the Knative Service in given namespace
+func GetKnativeServiceItem(clients kube.Clients, name string, namespace string) (runtime.Object, error) {
+	item, err := clients.DynamicClient.Resource(KnativeServiceResource).Namespace(namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		logrus.Errorf("Failed to get Knative Service %v", err)
+		return nil, err
+	}
+
+	return toKnativeService(item)
+}
+
+// GetKnativeServiceItems returns the Knative Services in given namespace
+func GetKnativeServiceItems(clients kube.Clients, namespace string) []runtime.Object {
+	list, err := clients.DynamicClient.Resource(KnativeServiceResource).Namespace(namespace).List(context.TODO(), meta_v1.ListOptions{})
+	if err != nil {
+		logrus.Errorf("Failed to list Knative Services %v", err)
+		return []runtime.Object{}
+	}
+
+	items := make([]runtime.Object, 0, len(list.Items))
+	for i := range list.Items {
+		service, err := toKnativeService(&list.Items[i])
+		if err != nil {
+			logrus.Errorf("Failed to read Knative Service '%s' in namespace '%s': %v", list.Items[i].GetName(), namespace, err)
+			continue
+		}
+		items = append(items, service)
+	}
+
+	return items
+}
+
+// GetKnativeServiceAnnotations returns the annotations of given Knative Service
+func GetKnativeServiceAnnotations(item runtime.Object) map[string]string {
+	service, ok := item.(*KnativeService)
+	if !ok {
+		return nil
+	}
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string)
+	}
+	return service.Annotations
+}
+
+// GetKnativeServicePodAnnotations returns the revision template annotations of given Knative Service
+func GetKnativeServicePodAnnotations(item runtime.Object) map[string]string {
+	service, ok := item.(*KnativeService)
+	if !ok {
+		return nil
+	}
+	if service.Spec.Template.Annotations == nil {
+		service.Spec.Template.Annotations = make(map[string]string)
+	}
+	return service.Spec.Template.Annotations
+}
+
+// GetKnativeServiceContainers returns the containers of given Knative Service
+func GetKnativeServiceContainers(item runtime.Object) []v1.Container {
+	service, ok := item.(*KnativeService)
+	if !ok {
+		return []v1.Container{}
+	}
+	return service.Spec.Template.Spec.Containers
+}
+
+// GetKnativeServiceInitContainers returns the initContainers of given Knative Service
+func GetKnativeServiceInitContainers(item runtime.Object) []v1.Container {
+	service, ok := item.(*KnativeService)
+	if !ok {
+		return []v1.Container{}
+	}
+	return service.Spec.Template.Spec.InitContainers
+}
+
+// GetKnativeServiceVolumes returns the Volumes of given Knative Service
+func GetKnativeServiceVolumes(item runtime.Object) []v1.Volume {
+	service, ok := item.(*KnativeService)
+	if !ok {
+		return []v1.Volume{}
+	}
+	return service.Spec.Template.Spec.Volumes
+}
+
+// GetKnativeServicePodSpec returns the revision template pod spec of given Knative Service
+func GetKnativeServicePodSpec(item runtime.Object) *v1.PodSpec {
+	service, ok := item.(*KnativeService)
+	if !ok {
+		return nil
+	}
+	return &service.Spec.Template.Spec
+}
+
+// UpdateKnativeService writes the annotations and env vars of the revision template of a Knative Service, which
+// creates a new revision. The service is only partially known to Reloader, so only these are merged into the service.
+func UpdateKnativeService(clients kube.Clients, namespace string, resource runtime.Object) error {
+	service, ok := resource.(*KnativeService)
+	if !ok {
+		return errors.New("resource is not a Knative Service")
+	}
+
+	template := map[string]any{"metadata": map[string]any{"annotations": service.Spec.Template.Annotations}}
+	var containers []map[string]any
+	for _, container := range service.Spec.Template.Spec.Containers {
+		if len(container.Env) > 0 {
+			containers = append(containers, map[string]any{"name": container.Name, "env": container.Env})
+		}
+	}
+	if len(containers) > 0 {
+		template["spec"] = map[string]any{"containers": containers}
+	}
+	bytes, err := json.Marshal(map[string]any{"spec": map[string]any{"template": template}})
+	if err != nil {
+		return err
+	}
+	return PatchKnativeService(clients, namespace, resource, patchtypes.StrategicMergePatchType, bytes)
+}
+
+// PatchKnativeService patches the revision template of a Knative Service, which creates a new revision
+func PatchKnativeService(clients kube.Clients, namespace string, resource runtime.Object, patchType patchtypes.PatchType, bytes []byte) error {
+	service, ok := resource.(*KnativeService)
+	if !ok {
+		return errors.New("resource is not a Knative Service")
+	}
+
+	// Knative Services are custom resources, which don't support strategic merge patches, so the patch of the reload
+	// strategy is turned into a merge patch of the same fields
+	if patchType == patchtypes.StrategicMergePatchType {
+		var err error
+		if bytes, err = toKnativeServiceMergePatch(clients, namespace, service, bytes); err != nil {
+			return err
+		}
+		patchType = patchtypes.MergePatchType
+	}
+	_, err := clients.DynamicClient.Resource(KnativeServiceResource).Namespace(namespace).Patch(context.TODO(), service.Name, patchType, bytes, meta_v1.PatchOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// toKnativeServiceMergePatch turns a strategic merge patch of annotations and container env vars into a merge patch.
+// Merge patches replace lists, so the containers of a patch setting env vars are the current containers of the
+// service with these env vars set. The patch fails with a conflict if the service changed since it was read.
+func toKnativeServiceMergePatch(clients kube.Clients, namespace string, service *KnativeService, bytes []byte) ([]byte, error) {
+	var patch map[string]any
+	if err := json.Unmarshal(bytes, &patch); err != nil {
+		return nil, err
+	}
+
+	patchContainers, found, err := unstructured.NestedSlice(patch, "spec", "template", "spec", "containers")
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		current, err := clients.DynamicClient.Resource(KnativeServiceResource).Namespace(namespace).Get(context.TODO(), service.Name, meta_v1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		containers, _, err := unstructured.NestedSlice(current.Object, "spec", "template", "spec", "containers")
+		if err != nil {
+			return nil, err
+		}
+		if err := setKnativeContainerEnvVars(containers, patchContainers); err != nil {
+			return nil, err
+		}
+		if err := unstructured.SetNestedSlice(patch, containers, "spec", "template", "spec", "containers"); err != nil {
+			return nil, err
+		}
+	}
+
+	if service.ResourceVersion != "" {
+		if err := unstructured.SetNestedField(patch, service.ResourceVersion, "metadata", "resourceVersion"); err != nil {
+			return nil, err
+		}
+	}
+	// Knative rejects a changed revision template keeping the revision name set by the user, a name is generated instead
+	if service.Spec.Template.Name != "" {
+		if err := unstructured.SetNestedField(patch, nil, "spec", "template", "metadata", "name"); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(patch)
+}
+
+// setKnativeContainerEnvVars sets the env vars of the containers of a patch in the containers of a Knative Service,
+// matched by name
+func setKnativeContainerEnvVars(containers, patchContainers []any) error {
+	for _, item := range patchContainers {
+		patchContainer, ok := item.(map[string]any)
+		if !ok {
+			return errors.New("invalid container in patch")
+		}
+		name, _, _ := unstructured.NestedString(patchContainer, "name")
+		index := slices.IndexFunc(containers, func(item any) bool {
+			container, ok := item.(map[string]any)
+			if !ok {
+				return false
+			}
+			containerName, _, _ := unstructured.NestedString(container, "name")
+			return containerName == name
+		})
+		if index < 0 {
+			return fmt.Errorf("container '%s' not found", name)
+		}
+		container := containers[index].(map[string]any)
+
+		env, _, err := unstructured.NestedSlice(container, "env")
+		if err != nil {
+			return err
+		}
+		patchEnv, _, err := unstructured.NestedSlice(patchContainer, "env")
+		if err != nil {
+			return err
+		}
+		for _, item := range patchEnv {
+			envVar, ok := item.(map[string]any)
+			if !ok {
+				return errors.New("invalid env var in patch")
+			}
+			envIndex := slices.IndexFunc(env, func(item any) bool {
+				current, ok := item.(map[string]any)
+				return ok && current["name"] == envVar["name"]
+			})
+			if envIndex < 0 {
+				env = append(env, envVar)
+			} else {
+				env[envIndex] = envVar
+			}
+		}
+		if err := unstructured.SetNestedSlice(container, env, "env"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package callbacks_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	patchtypes "k8s.io/apimachinery/pkg/types"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func createTestKnativeService(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containerConcurrency": int64(10),
+					"containers": []any{map[string]any{
+						"name":    "app",
+						"image":   "app:1",
+						"envFrom": []any{map[string]any{"configMapRef": map[string]any{"name": "app-config"}}},
+					}},
+				},
+			},
+			"traffic": []any{map[string]any{"latestRevision": true, "percent": int64(100)}},
+		},
+	}}
+}
+
+func newKnativeClients(objects ...runtime.Object) kube.Clients {
+	listKinds := map[schema.GroupVersionResource]string{callbacks.KnativeServiceResource: "ServiceList"}
+	return kube.Clients{DynamicClient: fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)}
+}
+
+func TestGetKnativeServiceItems(t *testing.T) {
+	namespace := "test-ns"
+	fakeClients := newKnativeClients(createTestKnativeService(namespace, "web"))
+
+	items := callbacks.GetKnativeServiceItems(fakeClients, namespace)
+	assert.Len(t, items, 1)
+
+	containers := callbacks.GetKnativeServiceContainers(items[0])
+	assert.Len(t, containers, 1)
+	assert.Equal(t, "app-config", containers[0].EnvFrom[0].ConfigMapRef.Name)
+	assert.NotNil(t, callbacks.GetKnativeServicePodAnnotations(items[0]), "Revision template annotations should be initialized")
+
+	item, err := callbacks.GetKnativeServiceItem(fakeClients, "web", namespace)
+	assert.NoError(t, err)
+	assert.Equal(t, "web", item.(*callbacks.KnativeService).Name)
+}
+
+func TestPatchKnativeService(t *testing.T) {
+	namespace := "test-ns"
+	annotation := "reloader.stakater.com/last-reloaded-from"
+	patch := fmt.Appendf(nil, callbacks.GetPatchTemplates().AnnotationTemplate, annotation, "sha")
+
+	tests := []struct {
+		name   string
+		reload func(kube.Clients, runtime.Object) error
+	}{
+		{
+			name: "Patch",
+			reload: func(clients kube.Clients, service runtime.Object) error {
+				return callbacks.PatchKnativeService(clients, namespace, service, patchtypes.StrategicMergePatchType, patch)
+			},
+		},
+		{
+			name: "Update",
+			reload: func(clients kube.Clients, service runtime.Object) error {
+				return callbacks.UpdateKnativeService(clients, namespace, service)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClients := newKnativeClients(createTestKnativeService(namespace, "web"))
+			service, err := callbacks.GetKnativeServiceItem(fakeClients, "web", namespace)
+			assert.NoError(t, err)
+
+			// The reload strategy updates the revision template before patching the service
+			callbacks.GetKnativeServicePodAnnotations(service)[annotation] = "sha"
+			assert.NoError(t, tt.reload(fakeClients, service))
+
+			result, err := fakeClients.DynamicClient.Resource(callbacks.KnativeServiceResource).Namespace(namespace).Get(context.TODO(), "web", metav1.GetOptions{})
+			assert.NoError(t, err)
+			annotations, _, _ := unstructured.NestedStringMap(result.Object, "spec", "template", "metadata", "annotations")
+			assert.Equal(t, "sha", annotations[annotation])
+
+			// Fields unknown to Reloader are kept
+			concurrency, _, _ := unstructured.NestedInt64(result.Object, "spec", "template", "spec", "containerConcurrency")
+			assert.Equal(t, int64(10), concurrency)
+			traffic, _, _ := unstructured.NestedSlice(result.Object, "spec", "traffic")
+			assert.Len(t, traffic, 1)
+			containers, _, _ := unstructured.NestedSlice(result.Object, "spec", "template", "spec", "containers")
+			assert.Equal(t, "app:1", containers[0].(map[string]any)["image"])
+		})
+	}
+}
+
+func TestKnativeServiceDeepCopy(t *testing.T) {
+	service := &callbacks.KnativeService{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{"a": "b"}},
+		Spec: callbacks.KnativeServiceSpec{
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+		},
+	}
+
+	copied := service.DeepCopyObject().(*callbacks.KnativeService)
+	copied.Annotations["a"] = "c"
+	copied.Spec.Template.Spec.Containers[0].Name = "changed"
+
+	assert.Equal(t, "b", service.Annotations["a"])
+	assert.Equal(t, "app", service.Spec.Template.Spec.Containers[0].Name)
+}
+
+func TestPatchKnativeServiceOnlyPatchesChangedFields(t *testing.T) {
+	namespace := "test-ns"
+	annotation := "reloader.stakater.com/last-reloaded-from"
+	templates := callbacks.GetPatchTemplates()
+
+	tests := []struct {
+		name  string
+		patch []byte
+		check func(t *testing.T, template map[string]any)
+	}{
+		{
+			name:  "Annotation",
+			patch: fmt.Appendf(nil, templates.AnnotationTemplate, annotation, "sha"),
+			check: func(t *testing.T, template map[string]any) {
+				annotations, _, _ := unstructured.NestedStringMap(template, "metadata", "annotations")
+				assert.Equal(t, map[string]string{"team": "web", annotation: "sha"}, annotations, "Existing annotations should be kept")
+			},
+		},
+		{
+			name:  "Env var",
+			patch: fmt.Appendf(nil, templates.EnvVarTemplate, "app", "STAKATER_APP_CONFIG_CONFIGMAP", "sha"),
+			check: func(t *testing.T, template map[string]any) {
+				containers, _, _ := unstructured.NestedSlice(template, "spec", "containers")
+				container := containers[0].(map[string]any)
+				assert.Equal(t, []any{
+					map[string]any{"name": "EXISTING", "value": "value"},
+					map[string]any{"name": "STAKATER_APP_CONFIG_CONFIGMAP", "value": "sha"},
+				}, container["env"])
+				assert.NotNil(t, container["envFrom"], "Other fields of the container should be kept")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := createTestKnativeService(namespace, "web")
+			object.SetResourceVersion("1")
+			_ = unstructured.SetNestedStringMap(object.Object, map[string]string{"team": "web"}, "spec", "template", "metadata", "annotations")
+			_ = unstructured.SetNestedField(object.Object, "web-v1", "spec", "template", "metadata", "name")
+			containers, _, _ := unstructured.NestedSlice(object.Object, "spec", "template", "spec", "containers")
+			containers[0].(map[string]any)["env"] = []any{map[string]any{"name": "EXISTING", "value": "value"}}
+			_ = unstructured.SetNestedSlice(object.Object, containers, "spec", "template", "spec", "containers")
+			fakeClients := newKnativeClients(object)
+			service, err := callbacks.GetKnativeServiceItem(fakeClients, "web", namespace)
+			assert.NoError(t, err)
+
+			assert.NoError(t, callbacks.PatchKnativeService(fakeClients, namespace, service, patchtypes.StrategicMergePatchType, tt.patch))
+
+			result, err := fakeClients.DynamicClient.Resource(callbacks.KnativeServiceResource).Namespace(namespace).Get(context.TODO(), "web", metav1.GetOptions{})
+			assert.NoError(t, err)
+			template, _, _ := unstructured.NestedMap(result.Object, "spec", "template")
+			tt.check(t, template)
+			_, found, _ := unstructured.NestedString(template, "metadata", "name")
+			assert.False(t, found, "Revision name set by the user should be cleared")
+			concurrency, _, _ := unstructured.NestedInt64(template, "spec", "containerConcurrency")
+			assert.Equal(t, int64(10), concurrency, "Fields unknown to Reloader should be kept")
+		})
+	}
+}
+
+func TestPatchKnativeServiceResourceVersion(t *testing.T) {
+	namespace := "test-ns"
+	object := createTestKnativeService(namespace, "web")
+	object.SetResourceVersion("1")
+	fakeClients := newKnativeClients(object)
+	service, err := callbacks.GetKnativeServiceItem(fakeClients, "web", namespace)
+	assert.NoError(t, err)
+
+	patch := fmt.Appendf(nil, callbacks.GetPatchTemplates().AnnotationTemplate, "reloader.stakater.com/last-reloaded-from", "sha")
+	assert.NoError(t, callbacks.PatchKnativeService(fakeClients, namespace, service, patchtypes.StrategicMergePatchType, patch))
+
+	// The API server rejects the patch with a conflict if the service changed since it was read
+	actions := fakeClients.DynamicClient.(*fakedynamic.FakeDynamicClient).Actions()
+	patchAction := actions[len(actions)-1].(k8stesting.PatchAction)
+	assert.Equal(t, patchtypes.MergePatchType, patchAction.GetPatchType())
+	assert.JSONEq(t, `{"metadata":{"resourceVersion":"1"},"spec":{"template":{"metadata":{"annotations":{"reloader.stakater.com/last-reloaded-from":"sha"}}}}}`, string(patchAction.GetPatch()))
+}
@@ -2,6 +2,7 @@ package callbacks
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -10,10 +11,14 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	patchtypes "k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
 
+	"github.com/stakater/Reloader/internal/pkg/constants"
 	"github.com/stakater/Reloader/internal/pkg/options"
 	"github.com/stakater/Reloader/pkg/kube"
 
@@ -37,6 +42,9 @@ type InitContainersFunc func(runtime.Object) []v1.Container
 // VolumesFunc is a generic func to return volumes
 type VolumesFunc func(runtime.Object) []v1.Volume
 
+// PodSpecFunc is a generic func to return the pod spec
+type PodSpecFunc func(runtime.Object) *v1.PodSpec
+
 // UpdateFunc performs the resource update
 type UpdateFunc func(kube.Clients, string, runtime.Object) error
 
@@ -65,6 +73,7 @@ type RollingUpgradeFuncs struct {
 	PatchFunc              PatchFunc
 	PatchTemplatesFunc     PatchTemplatesFunc
 	VolumesFunc            VolumesFunc
+	PodSpecFunc            PodSpecFunc
 	ResourceType           string
 	SupportsPatch          bool
 }
@@ -174,6 +183,40 @@ func GetJobItems(clients kube.Clients, namespace string) []runtime.Object {
 	return items
 }
 
+// resolveRolloutWorkloadRef copies the pod template of the workload referenced by the rollout, so that references of
+// rollouts using a workloadRef are detected like the ones of rollouts defining their own pod template
+func resolveRolloutWorkloadRef(clients kube.Clients, rollout *argorolloutv1alpha1.Rollout) error {
+	ref := rollout.Spec.WorkloadRef
+	if ref == nil {
+		return nil
+	}
+
+	var template *v1.PodTemplateSpec
+	var selector *meta_v1.LabelSelector
+	switch ref.Kind {
+	case "Deployment":
+		deployment, err := clients.KubernetesClient.AppsV1().Deployments(rollout.Namespace).Get(context.TODO(), ref.Name, meta_v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		template, selector = &deployment.Spec.Template, deployment.Spec.Selector
+	case "ReplicaSet":
+		replicaSet, err := clients.KubernetesClient.AppsV1().ReplicaSets(rollout.Namespace).Get(context.TODO(), ref.Name, meta_v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		template, selector = &replicaSet.Spec.Template, replicaSet.Spec.Selector
+	default:
+		return fmt.Errorf("unsupported workloadRef kind '%s'", ref.Kind)
+	}
+
+	rollout.Spec.Template = *template
+	if rollout.Spec.Selector == nil {
+		rollout.Spec.Selector = selector
+	}
+	return nil
+}
+
 // GetDaemonSetItem returns the daemonSet in given namespace
 func GetDaemonSetItem(clients kube.Clients, name string, namespace string) (runtime.Object, error) {
 	daemonSet, err := clients.KubernetesClient.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
@@ -241,6 +284,9 @@ func GetRolloutItem(clients kube.Clients, name string, namespace string) (runtim
 		logrus.Errorf("Failed to get Rollout %v", err)
 		return nil, err
 	}
+	if err := resolveRolloutWorkloadRef(clients, rollout); err != nil {
+		logrus.Errorf("Failed to resolve workloadRef of Rollout '%s' in namespace '%s': %v", rollout.Name, namespace, err)
+	}
 
 	return rollout, nil
 }
@@ -254,8 +300,11 @@ func GetRolloutItems(clients kube.Clients, namespace string) []runtime.Object {
 
 	items := make([]runtime.Object, len(rollouts.Items))
 	// Ensure we always have pod annotations to add to
-	for i, v := range rollouts.Items {
-		if v.Spec.Template.Annotations == nil {
+	for i := range rollouts.Items {
+		if err := resolveRolloutWorkloadRef(clients, &rollouts.Items[i]); err != nil {
+			logrus.Errorf("Failed to resolve workloadRef of Rollout '%s' in namespace '%s': %v", rollouts.Items[i].Name, namespace, err)
+		}
+		if rollouts.Items[i].Spec.Template.Annotations == nil {
 			rollouts.Items[i].Spec.Template.Annotations = make(map[string]string)
 		}
 		items[i] = &rollouts.Items[i]
@@ -264,6 +313,103 @@ func GetRolloutItems(clients kube.Clients, namespace string) []runtime.Object {
 	return items
 }
 
+// GetReplicaSetItem returns the replicaSet in given namespace
+func GetReplicaSetItem(clients kube.Clients, name string, namespace string) (runtime.Object, error) {
+	replicaSet, err := clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		logrus.Errorf("Failed to get replicaSet %v", err)
+		return nil, err
+	}
+
+	return replicaSet, nil
+}
+
+// GetReplicaSetItems returns the replicaSets in given namespace that have no managing controller and opted in to be reloaded
+func GetReplicaSetItems(clients kube.Clients, namespace string) []runtime.Object {
+	replicaSets, err := clients.KubernetesClient.AppsV1().ReplicaSets(namespace).List(context.TODO(), meta_v1.ListOptions{})
+	if err != nil {
+		logrus.Errorf("Failed to list replicaSets %v", err)
+		return []runtime.Object{}
+	}
+
+	items := make([]runtime.Object, 0)
+	for i, v := range replicaSets.Items {
+		if !isUnmanagedReloadEnabled(&replicaSets.Items[i]) {
+			continue
+		}
+		// Ensure we always have pod annotations to add to
+		if v.Spec.Template.Annotations == nil {
+			replicaSets.Items[i].Spec.Template.Annotations = make(map[string]string)
+		}
+		items = append(items, &replicaSets.Items[i])
+	}
+
+	return items
+}
+
+// GetPodItem returns the pod in given namespace
+func GetPodItem(clients kube.Clients, name string, namespace string) (runtime.Object, error) {
+	pod, err := clients.KubernetesClient.CoreV1().Pods(namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		logrus.Errorf("Failed to get pod %v", err)
+		return nil, err
+	}
+
+	return pod, nil
+}
+
+// podLister lists the pods of a namespace from the caches of informers, set by SetPodLister
+var podLister = func(namespace string) ([]*v1.Pod, bool) { return nil, false }
+
+// SetPodLister sets the func listing the pods of a namespace from the caches of informers
+func SetPodLister(lister func(namespace string) ([]*v1.Pod, bool)) {
+	podLister = lister
+}
+
+// GetPodItems returns the running pods in given namespace that have no managing controller and opted in to be reloaded.
+// The pods are read from the informer caches and only listed from the API server while these haven't synced.
+func GetPodItems(clients kube.Clients, namespace string) []runtime.Object {
+	pods, cached := podLister(namespace)
+	if !cached {
+		podList, err := clients.KubernetesClient.CoreV1().Pods(namespace).List(context.TODO(), meta_v1.ListOptions{})
+		if err != nil {
+			logrus.Errorf("Failed to list pods %v", err)
+			return []runtime.Object{}
+		}
+		pods = make([]*v1.Pod, 0, len(podList.Items))
+		for i := range podList.Items {
+			pods = append(pods, &podList.Items[i])
+		}
+	}
+
+	items := make([]runtime.Object, 0)
+	for _, pod := range pods {
+		// Mirror pods of static pods are managed by the kubelet and can't be evicted
+		if _, mirror := pod.Annotations[v1.MirrorPodAnnotationKey]; mirror || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if !isUnmanagedReloadEnabled(pod) {
+			continue
+		}
+		// Cached pods are shared with the informers and must not be modified by the reload
+		if cached {
+			pod = pod.DeepCopy()
+		}
+		items = append(items, pod)
+	}
+
+	return items
+}
+
+// isUnmanagedReloadEnabled checks whether a pod or replicaSet has no managing controller and opted in to be reloaded
+func isUnmanagedReloadEnabled(obj meta_v1.Object) bool {
+	if meta_v1.GetControllerOf(obj) != nil {
+		return false
+	}
+	value := obj.GetAnnotations()[options.ReloadUnmanagedAnnotation]
+	return value == "true" || value == constants.RecreateUnmanagedPod
+}
+
 // GetDeploymentAnnotations returns the annotations of given deployment
 func GetDeploymentAnnotations(item runtime.Object) map[string]string {
 	deployment, ok := item.(*appsv1.Deployment)
@@ -336,6 +482,30 @@ func GetRolloutAnnotations(item runtime.Object) map[string]string {
 	return rollout.Annotations
 }
 
+// GetReplicaSetAnnotations returns the annotations of given replicaSet
+func GetReplicaSetAnnotations(item runtime.Object) map[string]string {
+	replicaSet, ok := item.(*appsv1.ReplicaSet)
+	if !ok {
+		return nil
+	}
+	if replicaSet.Annotations == nil {
+		replicaSet.Annotations = make(map[string]string)
+	}
+	return replicaSet.Annotations
+}
+
+// GetPodAnnotations returns the annotations of given pod
+func GetPodAnnotations(item runtime.Object) map[string]string {
+	pod, ok := item.(*v1.Pod)
+	if !ok {
+		return nil
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	return pod.Annotations
+}
+
 // GetDeploymentPodAnnotations returns the pod's annotations of given deployment
 func GetDeploymentPodAnnotations(item runtime.Object) map[string]string {
 	deployment, ok := item.(*appsv1.Deployment)
@@ -408,6 +578,18 @@ func GetRolloutPodAnnotations(item runtime.Object) map[string]string {
 	return rollout.Spec.Template.Annotations
 }
 
+// GetReplicaSetPodAnnotations returns the pod's annotations of given replicaSet
+func GetReplicaSetPodAnnotations(item runtime.Object) map[string]string {
+	replicaSet, ok := item.(*appsv1.ReplicaSet)
+	if !ok {
+		return nil
+	}
+	if replicaSet.Spec.Template.Annotations == nil {
+		replicaSet.Spec.Template.Annotations = make(map[string]string)
+	}
+	return replicaSet.Spec.Template.Annotations
+}
+
 // GetDeploymentContainers returns the containers of given deployment
 func GetDeploymentContainers(item runtime.Object) []v1.Container {
 	deployment, ok := item.(*appsv1.Deployment)
@@ -462,6 +644,24 @@ func GetRolloutContainers(item runtime.Object) []v1.Container {
 	return rollout.Spec.Template.Spec.Containers
 }
 
+// GetReplicaSetContainers returns the containers of given replicaSet
+func GetReplicaSetContainers(item runtime.Object) []v1.Container {
+	replicaSet, ok := item.(*appsv1.ReplicaSet)
+	if !ok {
+		return nil
+	}
+	return replicaSet.Spec.Template.Spec.Containers
+}
+
+// GetPodContainers returns the containers of given pod
+func GetPodContainers(item runtime.Object) []v1.Container {
+	pod, ok := item.(*v1.Pod)
+	if !ok {
+		return nil
+	}
+	return pod.Spec.Containers
+}
+
 // GetDeploymentInitContainers returns the containers of given deployment
 func GetDeploymentInitContainers(item runtime.Object) []v1.Container {
 	deployment, ok := item.(*appsv1.Deployment)
@@ -516,6 +716,24 @@ func GetRolloutInitContainers(item runtime.Object) []v1.Container {
 	return rollout.Spec.Template.Spec.InitContainers
 }
 
+// GetReplicaSetInitContainers returns the containers of given replicaSet
+func GetReplicaSetInitContainers(item runtime.Object) []v1.Container {
+	replicaSet, ok := item.(*appsv1.ReplicaSet)
+	if !ok {
+		return nil
+	}
+	return replicaSet.Spec.Template.Spec.InitContainers
+}
+
+// GetPodInitContainers returns the containers of given pod
+func GetPodInitContainers(item runtime.Object) []v1.Container {
+	pod, ok := item.(*v1.Pod)
+	if !ok {
+		return nil
+	}
+	return pod.Spec.InitContainers
+}
+
 // GetPatchTemplates returns patch templates
 func GetPatchTemplates() PatchTemplates {
 	return PatchTemplates{
@@ -525,6 +743,15 @@ func GetPatchTemplates() PatchTemplates {
 	}
 }
 
+// GetCronJobPatchTemplates returns patch templates for the job template of a cronjob
+func GetCronJobPatchTemplates() PatchTemplates {
+	return PatchTemplates{
+		AnnotationTemplate:   `{"spec":{"jobTemplate":{"spec":{"template":{"metadata":{"annotations":{"%s":"%s"}}}}}}}`,                                   // strategic merge patch
+		EnvVarTemplate:       `{"spec":{"jobTemplate":{"spec":{"template":{"spec":{"containers":[{"name":"%s","env":[{"name":"%s","value":"%s"}]}]}}}}}}`, // strategic merge patch
+		DeleteEnvVarTemplate: `[{"op":"remove","path":"/spec/jobTemplate/spec/template/spec/containers/%d/env/%d"}]`,                                      // JSON patch
+	}
+}
+
 // UpdateDeployment performs rolling upgrade on deployment
 func UpdateDeployment(clients kube.Clients, namespace string, resource runtime.Object) error {
 	deployment, ok := resource.(*appsv1.Deployment)
@@ -570,25 +797,120 @@ func CreateJobFromCronjob(clients kube.Clients, namespace string, resource runti
 	return err
 }
 
+// UpdateCronJob performs rolling upgrade on cronjob according to its reload policy
+func UpdateCronJob(clients kube.Clients, namespace string, resource runtime.Object) error {
+	return reloadCronJob(clients, namespace, resource, func(cronJob *batchv1.CronJob) error {
+		_, err := clients.KubernetesClient.BatchV1().CronJobs(namespace).Update(context.TODO(), cronJob, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+		return err
+	})
+}
+
+// UpdateCronJobTemplate updates the job template of cronjob without creating a job, regardless of its reload policy
+func UpdateCronJobTemplate(clients kube.Clients, namespace string, resource runtime.Object) error {
+	cronJob, ok := resource.(*batchv1.CronJob)
+	if !ok {
+		return errors.New("resource is not a CronJob")
+	}
+	_, err := clients.KubernetesClient.BatchV1().CronJobs(namespace).Update(context.TODO(), cronJob, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// PatchCronJob performs rolling upgrade on cronjob according to its reload policy
 func PatchCronJob(clients kube.Clients, namespace string, resource runtime.Object, patchType patchtypes.PatchType, bytes []byte) error {
-	return errors.New("not supported patching: CronJob")
+	return reloadCronJob(clients, namespace, resource, func(cronJob *batchv1.CronJob) error {
+		_, err := clients.KubernetesClient.BatchV1().CronJobs(namespace).Patch(context.TODO(), cronJob.Name, patchType, bytes, meta_v1.PatchOptions{FieldManager: "Reloader"})
+		return err
+	})
 }
 
-// ReCreateJobFromjob performs rolling upgrade on job
+// reloadCronJob updates the job template of the cronjob with the given func and creates a job from it, as defined by
+// its reload policy
+func reloadCronJob(clients kube.Clients, namespace string, resource runtime.Object, updateJobTemplate func(*batchv1.CronJob) error) error {
+	cronJob, ok := resource.(*batchv1.CronJob)
+	if !ok {
+		return errors.New("resource is not a CronJob")
+	}
+	policy, err := GetCronJobReloadPolicy(cronJob)
+	if err != nil {
+		return err
+	}
+
+	if policy != constants.TriggerCronJobReloadPolicy {
+		if err := updateJobTemplate(cronJob); err != nil {
+			return err
+		}
+	}
+	if policy != constants.UpdateTemplateCronJobReloadPolicy {
+		return CreateJobFromCronjob(clients, namespace, cronJob)
+	}
+	return nil
+}
+
+// GetCronJobReloadPolicy returns how the given cronjob is reloaded, defaulting to creating a job without updating
+// its job template
+func GetCronJobReloadPolicy(cronJob *batchv1.CronJob) (string, error) {
+	policy, found := cronJob.Annotations[options.CronJobReloadPolicyAnnotation]
+	if !found {
+		return constants.TriggerCronJobReloadPolicy, nil
+	}
+	switch policy {
+	case constants.TriggerCronJobReloadPolicy, constants.UpdateTemplateCronJobReloadPolicy, constants.UpdateAndTriggerCronJobReloadPolicy:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid value '%s' for annotation '%s'", policy, options.CronJobReloadPolicyAnnotation)
+}
+
+// ReCreateJobFromjob performs rolling upgrade on job, rerunning it according to its reload policy
 func ReCreateJobFromjob(clients kube.Clients, namespace string, resource runtime.Object) error {
 	oldJob, ok := resource.(*batchv1.Job)
 	if !ok {
 		return errors.New("resource is not a Job")
 	}
-	job := oldJob.DeepCopy()
+	policy, err := GetJobReloadPolicy(oldJob)
+	if err != nil {
+		return err
+	}
+	job := newJobFromJob(oldJob)
+
+	if policy == constants.CreateNewJobReloadPolicy || policy == constants.RerunFinishedJobReloadPolicy {
+		return createSupersedingJob(clients, namespace, oldJob, job)
+	}
 
 	// Delete the old job
-	policy := meta_v1.DeletePropagationBackground
-	err := clients.KubernetesClient.BatchV1().Jobs(namespace).Delete(context.TODO(), job.Name, meta_v1.DeleteOptions{PropagationPolicy: &policy})
+	propagation := meta_v1.DeletePropagationBackground
+	err = clients.KubernetesClient.BatchV1().Jobs(namespace).Delete(context.TODO(), job.Name, meta_v1.DeleteOptions{PropagationPolicy: &propagation})
 	if err != nil {
 		return err
 	}
 
+	// Create the new job with same spec
+	_, err = clients.KubernetesClient.BatchV1().Jobs(namespace).Create(context.TODO(), job, meta_v1.CreateOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// GetJobReloadPolicy returns how the given job is rerun, defaulting to recreating it under the same name
+func GetJobReloadPolicy(job *batchv1.Job) (string, error) {
+	policy, found := job.Annotations[options.JobReloadPolicyAnnotation]
+	if !found {
+		return constants.RecreateJobReloadPolicy, nil
+	}
+	switch policy {
+	case constants.RecreateJobReloadPolicy, constants.CreateNewJobReloadPolicy, constants.RerunFinishedJobReloadPolicy, constants.SkipRunningJobReloadPolicy:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid value '%s' for annotation '%s'", policy, options.JobReloadPolicyAnnotation)
+}
+
+// IsSupersededJob returns whether the given job was already rerun by a new job
+func IsSupersededJob(job *batchv1.Job) bool {
+	_, superseded := job.Annotations[getReloaderAnnotationKey(constants.JobSupersededByAnnotation)]
+	return superseded
+}
+
+// newJobFromJob returns a job with the spec of the given job that can be created
+func newJobFromJob(oldJob *batchv1.Job) *batchv1.Job {
+	job := oldJob.DeepCopy()
+
 	// Remove fields that should not be specified when creating a new Job
 	job.ResourceVersion = ""
 	job.UID = ""
@@ -603,10 +925,69 @@ func ReCreateJobFromjob(clients kube.Clients, namespace string, resource runtime
 
 	// Remove the selector to allow it to be auto-generated
 	job.Spec.Selector = nil
+	return job
+}
 
-	// Create the new job with same spec
-	_, err = clients.KubernetesClient.BatchV1().Jobs(namespace).Create(context.TODO(), job, meta_v1.CreateOptions{FieldManager: "Reloader"})
-	return err
+// createSupersedingJob creates the given job under a new name and marks the old job as superseded, so that it is
+// cleaned up once its superseded job TTL expired
+func createSupersedingJob(clients kube.Clients, namespace string, oldJob *batchv1.Job, job *batchv1.Job) error {
+	// Reruns of reruns are named after the original job rather than piling up suffixes
+	rerunOfKey := getReloaderAnnotationKey(constants.JobRerunOfAnnotation)
+	originalName := oldJob.Name
+	if name, found := oldJob.Annotations[rerunOfKey]; found {
+		originalName = name
+	}
+	// Job names are used as pod label values, which are limited to 63 characters
+	job.Name = fmt.Sprintf("%.57s-%s", originalName, utilrand.String(5))
+	if job.Annotations == nil {
+		job.Annotations = make(map[string]string)
+	}
+	job.Annotations[rerunOfKey] = originalName
+
+	created, err := clients.KubernetesClient.BatchV1().Jobs(namespace).Create(context.TODO(), job, meta_v1.CreateOptions{FieldManager: "Reloader"})
+	if err != nil {
+		return err
+	}
+
+	spec := map[string]any{}
+	if ttl := getSupersededJobTTL(oldJob); ttl > 0 {
+		seconds := int32(ttl.Seconds())
+		if current := oldJob.Spec.TTLSecondsAfterFinished; current == nil || *current > seconds {
+			spec["ttlSecondsAfterFinished"] = seconds
+		}
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{getReloaderAnnotationKey(constants.JobSupersededByAnnotation): created.Name},
+		},
+		"spec": spec,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clients.KubernetesClient.BatchV1().Jobs(namespace).Patch(context.TODO(), oldJob.Name, patchtypes.MergePatchType, patch, meta_v1.PatchOptions{FieldManager: "Reloader"})
+	if err != nil {
+		return fmt.Errorf("created job '%s' but failed to mark job '%s' as superseded: %w", created.Name, oldJob.Name, err)
+	}
+	return nil
+}
+
+// getSupersededJobTTL returns how long the given job is kept after it finished once superseded
+func getSupersededJobTTL(job *batchv1.Job) time.Duration {
+	value, found := job.Annotations[options.SupersededJobTTLAnnotation]
+	if !found {
+		return options.SupersededJobTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		logrus.Errorf("Invalid value '%s' for annotation '%s' on job '%s' in namespace '%s', using %s", value, options.SupersededJobTTLAnnotation, job.Name, job.Namespace, options.SupersededJobTTL)
+		return options.SupersededJobTTL
+	}
+	return ttl
+}
+
+func getReloaderAnnotationKey(name string) string {
+	return fmt.Sprintf("%s/%s", constants.ReloaderAnnotationPrefix, name)
 }
 
 func PatchJob(clients kube.Clients, namespace string, resource runtime.Object, patchType patchtypes.PatchType, bytes []byte) error {
@@ -661,15 +1042,176 @@ func UpdateRollout(clients kube.Clients, namespace string, resource runtime.Obje
 	var err error
 	switch options.ToArgoRolloutStrategy(strategy) {
 	case options.RestartStrategy:
-		_, err = clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Patch(context.TODO(), rollout.Name, patchtypes.MergePatchType, []byte(fmt.Sprintf(`{"spec": {"restartAt": "%s"}}`, time.Now().Format(time.RFC3339))), meta_v1.PatchOptions{FieldManager: "Reloader"})
+		err = RestartRollout(clients, namespace, rollout.Name)
 	case options.RolloutStrategy:
+		if rollout.Spec.WorkloadRef != nil {
+			return updateRolloutWorkload(clients, namespace, rollout)
+		}
 		_, err = clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Update(context.TODO(), rollout, meta_v1.UpdateOptions{FieldManager: "Reloader"})
 	}
 	return err
 }
 
+// UpdateRolloutTemplate updates the pod template of a rollout, or of the workload it references, regardless of its
+// rollout strategy
+func UpdateRolloutTemplate(clients kube.Clients, namespace string, resource runtime.Object) error {
+	rollout, ok := resource.(*argorolloutv1alpha1.Rollout)
+	if !ok {
+		return errors.New("resource is not a Rollout")
+	}
+	if rollout.Spec.WorkloadRef != nil {
+		return updateRolloutWorkload(clients, namespace, rollout)
+	}
+	_, err := clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Update(context.TODO(), rollout, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// PatchRollout performs rolling upgrade on rollout. Rollouts using a workloadRef are rolled out by patching the pod
+// template of the referenced workload.
 func PatchRollout(clients kube.Clients, namespace string, resource runtime.Object, patchType patchtypes.PatchType, bytes []byte) error {
-	return errors.New("not supported patching: Rollout")
+	rollout, ok := resource.(*argorolloutv1alpha1.Rollout)
+	if !ok {
+		return errors.New("resource is not a Rollout")
+	}
+	if options.ToArgoRolloutStrategy(rollout.GetAnnotations()[options.RolloutStrategyAnnotation]) == options.RestartStrategy {
+		return RestartRollout(clients, namespace, rollout.Name)
+	}
+
+	var err error
+	if ref := rollout.Spec.WorkloadRef; ref != nil {
+		switch ref.Kind {
+		case "Deployment":
+			_, err = clients.KubernetesClient.AppsV1().Deployments(namespace).Patch(context.TODO(), ref.Name, patchType, bytes, meta_v1.PatchOptions{FieldManager: "Reloader"})
+		case "ReplicaSet":
+			_, err = clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Patch(context.TODO(), ref.Name, patchType, bytes, meta_v1.PatchOptions{FieldManager: "Reloader"})
+		default:
+			err = fmt.Errorf("unsupported workloadRef kind '%s'", ref.Kind)
+		}
+		return err
+	}
+
+	// Rollouts are custom resources, which don't support strategic merge patches, and a merge patch would replace the
+	// containers with the ones read before. The rollout updated by the reload strategy is updated instead, which fails
+	// on changes made in the meantime and is retried with the latest rollout.
+	if patchType == patchtypes.StrategicMergePatchType {
+		_, err = clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Update(context.TODO(), rollout, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+		return err
+	}
+	_, err = clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Patch(context.TODO(), rollout.Name, patchType, bytes, meta_v1.PatchOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// RestartRollout restarts the pods of a rollout by setting its restartAt field
+func RestartRollout(clients kube.Clients, namespace string, name string) error {
+	_, err := clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Patch(context.TODO(), name, patchtypes.MergePatchType, []byte(fmt.Sprintf(`{"spec": {"restartAt": "%s"}}`, time.Now().Format(time.RFC3339))), meta_v1.PatchOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// updateRolloutWorkload updates the pod template of the workload referenced by the rollout, which the rollout
+// controller rolls out
+func updateRolloutWorkload(clients kube.Clients, namespace string, rollout *argorolloutv1alpha1.Rollout) error {
+	ref := rollout.Spec.WorkloadRef
+	switch ref.Kind {
+	case "Deployment":
+		deployment, err := clients.KubernetesClient.AppsV1().Deployments(namespace).Get(context.TODO(), ref.Name, meta_v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		deployment.Spec.Template = rollout.Spec.Template
+		_, err = clients.KubernetesClient.AppsV1().Deployments(namespace).Update(context.TODO(), deployment, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+		return err
+	case "ReplicaSet":
+		replicaSet, err := clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Get(context.TODO(), ref.Name, meta_v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		replicaSet.Spec.Template = rollout.Spec.Template
+		_, err = clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Update(context.TODO(), replicaSet, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+		return err
+	}
+	return fmt.Errorf("unsupported workloadRef kind '%s'", ref.Kind)
+}
+
+// UpdateReplicaSet updates the pod template of a replicaSet. Its pods are not replaced by the update and have to be deleted.
+func UpdateReplicaSet(clients kube.Clients, namespace string, resource runtime.Object) error {
+	replicaSet, ok := resource.(*appsv1.ReplicaSet)
+	if !ok {
+		return errors.New("resource is not a ReplicaSet")
+	}
+	_, err := clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Update(context.TODO(), replicaSet, meta_v1.UpdateOptions{FieldManager: "Reloader"})
+	return err
+}
+
+func PatchReplicaSet(clients kube.Clients, namespace string, resource runtime.Object, patchType patchtypes.PatchType, bytes []byte) error {
+	replicaSet, ok := resource.(*appsv1.ReplicaSet)
+	if !ok {
+		return errors.New("resource is not a ReplicaSet")
+	}
+	_, err := clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Patch(context.TODO(), replicaSet.Name, patchType, bytes, meta_v1.PatchOptions{FieldManager: "Reloader"})
+	return err
+}
+
+// ReloadPod evicts a pod that has no managing controller. Pods annotated to be recreated are first created again
+// from their spec, including the changes of the reload strategy, under a generated name, so the pod is never lost
+// if Reloader stops before the evicted pod is gone.
+func ReloadPod(clients kube.Clients, namespace string, resource runtime.Object) error {
+	pod, ok := resource.(*v1.Pod)
+	if !ok {
+		return errors.New("resource is not a Pod")
+	}
+
+	var replacement *v1.Pod
+	if pod.Annotations[options.ReloadUnmanagedAnnotation] == constants.RecreateUnmanagedPod {
+		var err error
+		replacement, err = clients.KubernetesClient.CoreV1().Pods(namespace).Create(context.TODO(), newPodFromSpec(pod), meta_v1.CreateOptions{FieldManager: "Reloader"})
+		if err != nil {
+			return fmt.Errorf("failed to create the replacement of pod '%s': %w", pod.Name, err)
+		}
+		logrus.Infof("Created pod '%s' in namespace '%s' to replace pod '%s'", replacement.Name, namespace, pod.Name)
+	}
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    meta_v1.ObjectMeta{Name: pod.Name, Namespace: namespace},
+		DeleteOptions: &meta_v1.DeleteOptions{Preconditions: &meta_v1.Preconditions{UID: &pod.UID}},
+	}
+	err := clients.KubernetesClient.PolicyV1().Evictions(namespace).Evict(context.TODO(), eviction)
+	if err != nil && replacement != nil {
+		// The pod is still running, so its replacement would only duplicate it
+		deleteErr := clients.KubernetesClient.CoreV1().Pods(namespace).Delete(context.TODO(), replacement.Name, meta_v1.DeleteOptions{})
+		if deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+			logrus.Errorf("Failed to delete pod '%s' in namespace '%s' replacing pod '%s' that could not be evicted: %v", replacement.Name, namespace, pod.Name, deleteErr)
+		}
+	}
+	return err
+}
+
+func PatchPod(clients kube.Clients, namespace string, resource runtime.Object, patchType patchtypes.PatchType, bytes []byte) error {
+	return errors.New("not supported patching: Pod")
+}
+
+// newPodFromSpec returns a pod with the metadata and spec of a pod to be evicted, to be scheduled again under a
+// name generated from the name of the first pod it replaces
+func newPodFromSpec(evicted *v1.Pod) *v1.Pod {
+	recreatedFromKey := getReloaderAnnotationKey(constants.RecreatedFromAnnotation)
+	annotations := maps.Clone(evicted.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotations[recreatedFromKey] == "" {
+		annotations[recreatedFromKey] = evicted.Name
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			GenerateName: annotations[recreatedFromKey] + "-",
+			Namespace:    evicted.Namespace,
+			Labels:       evicted.Labels,
+			Annotations:  annotations,
+		},
+		Spec: *evicted.Spec.DeepCopy(),
+	}
+	pod.Spec.NodeName = ""
+	return pod
 }
 
 // GetDeploymentVolumes returns the Volumes of given deployment
@@ -725,3 +1267,93 @@ func GetRolloutVolumes(item runtime.Object) []v1.Volume {
 	}
 	return rollout.Spec.Template.Spec.Volumes
 }
+
+// GetReplicaSetVolumes returns the Volumes of given replicaSet
+func GetReplicaSetVolumes(item runtime.Object) []v1.Volume {
+	replicaSet, ok := item.(*appsv1.ReplicaSet)
+	if !ok {
+		return []v1.Volume{}
+	}
+	return replicaSet.Spec.Template.Spec.Volumes
+}
+
+// GetPodVolumes returns the Volumes of given pod
+func GetPodVolumes(item runtime.Object) []v1.Volume {
+	pod, ok := item.(*v1.Pod)
+	if !ok {
+		return []v1.Volume{}
+	}
+	return pod.Spec.Volumes
+}
+
+// GetDeploymentPodSpec returns the pod spec of given deployment
+func GetDeploymentPodSpec(item runtime.Object) *v1.PodSpec {
+	deployment, ok := item.(*appsv1.Deployment)
+	if !ok {
+		return nil
+	}
+	return &deployment.Spec.Template.Spec
+}
+
+// GetCronJobPodSpec returns the pod spec of given cronJob
+func GetCronJobPodSpec(item runtime.Object) *v1.PodSpec {
+	cronJob, ok := item.(*batchv1.CronJob)
+	if !ok {
+		return nil
+	}
+	return &cronJob.Spec.JobTemplate.Spec.Template.Spec
+}
+
+// GetJobPodSpec returns the pod spec of given job
+func GetJobPodSpec(item runtime.Object) *v1.PodSpec {
+	job, ok := item.(*batchv1.Job)
+	if !ok {
+		return nil
+	}
+	return &job.Spec.Template.Spec
+}
+
+// GetDaemonSetPodSpec returns the pod spec of given daemonSet
+func GetDaemonSetPodSpec(item runtime.Object) *v1.PodSpec {
+	daemonSet, ok := item.(*appsv1.DaemonSet)
+	if !ok {
+		return nil
+	}
+	return &daemonSet.Spec.Template.Spec
+}
+
+// GetStatefulSetPodSpec returns the pod spec of given statefulSet
+func GetStatefulSetPodSpec(item runtime.Object) *v1.PodSpec {
+	statefulSet, ok := item.(*appsv1.StatefulSet)
+	if !ok {
+		return nil
+	}
+	return &statefulSet.Spec.Template.Spec
+}
+
+// GetRolloutPodSpec returns the pod spec of given rollout
+func GetRolloutPodSpec(item runtime.Object) *v1.PodSpec {
+	rollout, ok := item.(*argorolloutv1alpha1.Rollout)
+	if !ok {
+		return nil
+	}
+	return &rollout.Spec.Template.Spec
+}
+
+// GetReplicaSetPodSpec returns the pod spec of given replicaSet
+func GetReplicaSetPodSpec(item runtime.Object) *v1.PodSpec {
+	replicaSet, ok := item.(*appsv1.ReplicaSet)
+	if !ok {
+		return nil
+	}
+	return &replicaSet.Spec.Template.Spec
+}
+
+// GetPodPodSpec returns the pod spec of given pod
+func GetPodPodSpec(item runtime.Object) *v1.PodSpec {
+	pod, ok := item.(*v1.Pod)
+	if !ok {
+		return nil
+	}
+	return &pod.Spec
+}
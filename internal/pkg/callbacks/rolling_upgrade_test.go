@@ -2,7 +2,9 @@ package callbacks_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"testing"
 	"time"
@@ -11,17 +13,22 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	watch "k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 
 	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	fakeargoclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
 	patchtypes "k8s.io/apimachinery/pkg/types"
 
 	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
 	"github.com/stakater/Reloader/internal/pkg/options"
 	"github.com/stakater/Reloader/internal/pkg/testutil"
 	"github.com/stakater/Reloader/pkg/kube"
@@ -115,9 +122,152 @@ func TestUpdateRollout(t *testing.T) {
 
 func TestPatchRollout(t *testing.T) {
 	namespace := "test-ns"
-	rollout := testutil.GetRollout(namespace, "test", map[string]string{options.RolloutStrategyAnnotation: ""})
-	err := callbacks.PatchRollout(clients, namespace, rollout, patchtypes.StrategicMergePatchType, []byte(`{"spec": {}}`))
-	assert.EqualError(t, err, "not supported patching: Rollout")
+	envVar := v1.EnvVar{Name: "STAKATER_TEST_CONFIGMAP", Value: "sha"}
+	patch := fmt.Appendf(nil, callbacks.GetPatchTemplates().EnvVarTemplate, "test", envVar.Name, envVar.Value)
+
+	t.Run("Pod template", func(t *testing.T) {
+		rollout := testutil.GetRollout(namespace, "test", map[string]string{})
+		rollout.Spec.Template.Spec.Containers = []v1.Container{{Name: "test", Image: "app:1"}}
+		argoClient := fakeargoclientset.NewSimpleClientset(rollout.DeepCopy())
+		fakeClients := kube.Clients{ArgoRolloutClient: argoClient}
+
+		// The reload strategy updates the pod template of the rollout before patching it
+		rollout.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{envVar}
+		err := callbacks.PatchRollout(fakeClients, namespace, rollout, patchtypes.StrategicMergePatchType, patch)
+		assert.NoError(t, err)
+
+		result, err := argoClient.ArgoprojV1alpha1().Rollouts(namespace).Get(context.TODO(), "test", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []v1.Container{{Name: "test", Image: "app:1", Env: []v1.EnvVar{envVar}}}, result.Spec.Template.Spec.Containers)
+	})
+
+	t.Run("Changed in the meantime", func(t *testing.T) {
+		rollout := testutil.GetRollout(namespace, "test", map[string]string{})
+		rollout.ResourceVersion = "1"
+		rollout.Spec.Template.Spec.Containers = []v1.Container{{Name: "test", Image: "app:1"}}
+		changed := rollout.DeepCopy()
+		changed.ResourceVersion = "2"
+		changed.Spec.Template.Spec.Containers[0].Image = "app:2"
+		argoClient := fakeargoclientset.NewSimpleClientset(changed)
+		// The fake client doesn't check resource versions like the API server
+		argoClient.PrependReactor("update", "rollouts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			updated := action.(k8stesting.UpdateAction).GetObject().(*argorolloutv1alpha1.Rollout)
+			if updated.ResourceVersion != changed.ResourceVersion {
+				return true, nil, apierrors.NewConflict(argorolloutv1alpha1.Resource("rollouts"), updated.Name, errors.New("the object has been modified"))
+			}
+			return false, nil, nil
+		})
+		fakeClients := kube.Clients{ArgoRolloutClient: argoClient}
+
+		// The containers read before the change must not replace the changed ones
+		rollout.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{envVar}
+		err := callbacks.PatchRollout(fakeClients, namespace, rollout, patchtypes.StrategicMergePatchType, patch)
+		assert.True(t, apierrors.IsConflict(err), "Expected a conflict, got %v", err)
+
+		result, err := argoClient.ArgoprojV1alpha1().Rollouts(namespace).Get(context.TODO(), "test", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "app:2", result.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("Workload reference", func(t *testing.T) {
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+			Spec: appsv1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "test", Image: "app:1"}}}},
+			},
+		}
+		rollout := &argorolloutv1alpha1.Rollout{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace},
+			Spec: argorolloutv1alpha1.RolloutSpec{
+				WorkloadRef: &argorolloutv1alpha1.ObjectRef{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+			},
+		}
+		kubeClient := fake.NewClientset(deployment)
+		argoClient := fakeargoclientset.NewSimpleClientset(rollout)
+		fakeClients := kube.Clients{KubernetesClient: kubeClient, ArgoRolloutClient: argoClient}
+
+		err := callbacks.PatchRollout(fakeClients, namespace, rollout, patchtypes.StrategicMergePatchType, patch)
+		assert.NoError(t, err)
+
+		result, err := kubeClient.AppsV1().Deployments(namespace).Get(context.TODO(), "web", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []v1.EnvVar{envVar}, result.Spec.Template.Spec.Containers[0].Env, "Referenced workload should be patched")
+		assert.Equal(t, "app:1", result.Spec.Template.Spec.Containers[0].Image)
+
+		unchanged, err := argoClient.ArgoprojV1alpha1().Rollouts(namespace).Get(context.TODO(), "test", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, unchanged.Spec.Template.Spec.Containers, "Rollout using a workloadRef should not get a pod template")
+	})
+
+	t.Run("Restart strategy", func(t *testing.T) {
+		rollout := testutil.GetRollout(namespace, "test", map[string]string{options.RolloutStrategyAnnotation: "restart"})
+		argoClient := fakeargoclientset.NewSimpleClientset(rollout.DeepCopy())
+		fakeClients := kube.Clients{ArgoRolloutClient: argoClient}
+
+		err := callbacks.PatchRollout(fakeClients, namespace, rollout, patchtypes.StrategicMergePatchType, patch)
+		assert.NoError(t, err)
+
+		result, err := argoClient.ArgoprojV1alpha1().Rollouts(namespace).Get(context.TODO(), "test", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.NotNil(t, result.Spec.RestartAt)
+	})
+}
+
+func TestGetRolloutItemWorkloadRef(t *testing.T) {
+	namespace := "test-ns"
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	template := v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: "test",
+					EnvFrom: []v1.EnvFromSource{
+						{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}}},
+					},
+				},
+			},
+			Volumes: []v1.Volume{{Name: "config", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "my-secret"}}}},
+		},
+	}
+	kubeClient := fake.NewClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace}, Spec: appsv1.DeploymentSpec{Selector: selector, Template: template}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace}, Spec: appsv1.ReplicaSetSpec{Selector: selector, Template: template}},
+	)
+	workloadRefRollout := func(name, kind string) *argorolloutv1alpha1.Rollout {
+		return &argorolloutv1alpha1.Rollout{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: argorolloutv1alpha1.RolloutSpec{
+				WorkloadRef: &argorolloutv1alpha1.ObjectRef{APIVersion: "apps/v1", Kind: kind, Name: "web"},
+			},
+		}
+	}
+	argoClient := fakeargoclientset.NewSimpleClientset(
+		workloadRefRollout("deployment-ref", "Deployment"),
+		workloadRefRollout("replicaset-ref", "ReplicaSet"),
+		workloadRefRollout("unsupported-ref", "PodTemplate"),
+	)
+	fakeClients := kube.Clients{KubernetesClient: kubeClient, ArgoRolloutClient: argoClient}
+
+	for _, name := range []string{"deployment-ref", "replicaset-ref"} {
+		t.Run(name, func(t *testing.T) {
+			item, err := callbacks.GetRolloutItem(fakeClients, name, namespace)
+			assert.NoError(t, err)
+			assert.Equal(t, template.Spec.Containers, callbacks.GetRolloutContainers(item))
+			assert.Equal(t, template.Spec.Volumes, callbacks.GetRolloutVolumes(item))
+			assert.Equal(t, selector, item.(*argorolloutv1alpha1.Rollout).Spec.Selector)
+		})
+	}
+
+	items := callbacks.GetRolloutItems(fakeClients, namespace)
+	assert.Len(t, items, 3, "Rollouts with unresolved workloadRef should still be listed")
+	for _, item := range items {
+		rollout := item.(*argorolloutv1alpha1.Rollout)
+		if rollout.Name == "unsupported-ref" {
+			assert.Empty(t, callbacks.GetRolloutContainers(item))
+			continue
+		}
+		assert.Equal(t, template.Spec.Containers, callbacks.GetRolloutContainers(item))
+	}
 }
 
 func TestResourceItem(t *testing.T) {
@@ -250,6 +400,8 @@ func TestGetAnnotations(t *testing.T) {
 		{"DaemonSet", &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Annotations: testAnnotations}}, callbacks.GetDaemonSetAnnotations},
 		{"StatefulSet", &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Annotations: testAnnotations}}, callbacks.GetStatefulSetAnnotations},
 		{"Rollout", &argorolloutv1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Annotations: testAnnotations}}, callbacks.GetRolloutAnnotations},
+		{"ReplicaSet", &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Annotations: testAnnotations}}, callbacks.GetReplicaSetAnnotations},
+		{"Pod", &v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: testAnnotations}}, callbacks.GetPodAnnotations},
 	}
 
 	for _, tt := range tests {
@@ -273,6 +425,7 @@ func TestGetPodAnnotations(t *testing.T) {
 		{"DaemonSet", createResourceWithPodAnnotations(&appsv1.DaemonSet{}, testAnnotations), callbacks.GetDaemonSetPodAnnotations},
 		{"StatefulSet", createResourceWithPodAnnotations(&appsv1.StatefulSet{}, testAnnotations), callbacks.GetStatefulSetPodAnnotations},
 		{"Rollout", createResourceWithPodAnnotations(&argorolloutv1alpha1.Rollout{}, testAnnotations), callbacks.GetRolloutPodAnnotations},
+		{"ReplicaSet", createResourceWithPodAnnotations(&appsv1.ReplicaSet{}, testAnnotations), callbacks.GetReplicaSetPodAnnotations},
 	}
 
 	for _, tt := range tests {
@@ -296,6 +449,8 @@ func TestGetContainers(t *testing.T) {
 		{"CronJob", createResourceWithContainers(&batchv1.CronJob{}, fixtures.defaultContainers), callbacks.GetCronJobContainers},
 		{"Job", createResourceWithContainers(&batchv1.Job{}, fixtures.defaultContainers), callbacks.GetJobContainers},
 		{"Rollout", createResourceWithContainers(&argorolloutv1alpha1.Rollout{}, fixtures.defaultContainers), callbacks.GetRolloutContainers},
+		{"ReplicaSet", createResourceWithContainers(&appsv1.ReplicaSet{}, fixtures.defaultContainers), callbacks.GetReplicaSetContainers},
+		{"Pod", createResourceWithContainers(&v1.Pod{}, fixtures.defaultContainers), callbacks.GetPodContainers},
 	}
 
 	for _, tt := range tests {
@@ -319,6 +474,8 @@ func TestGetInitContainers(t *testing.T) {
 		{"CronJob", createResourceWithInitContainers(&batchv1.CronJob{}, fixtures.defaultInitContainers), callbacks.GetCronJobInitContainers},
 		{"Job", createResourceWithInitContainers(&batchv1.Job{}, fixtures.defaultInitContainers), callbacks.GetJobInitContainers},
 		{"Rollout", createResourceWithInitContainers(&argorolloutv1alpha1.Rollout{}, fixtures.defaultInitContainers), callbacks.GetRolloutInitContainers},
+		{"ReplicaSet", createResourceWithInitContainers(&appsv1.ReplicaSet{}, fixtures.defaultInitContainers), callbacks.GetReplicaSetInitContainers},
+		{"Pod", createResourceWithInitContainers(&v1.Pod{}, fixtures.defaultInitContainers), callbacks.GetPodInitContainers},
 	}
 
 	for _, tt := range tests {
@@ -340,6 +497,7 @@ func TestUpdateResources(t *testing.T) {
 		{"Deployment", createTestDeploymentWithAnnotations, callbacks.UpdateDeployment, deleteTestDeployment},
 		{"DaemonSet", createTestDaemonSetWithAnnotations, callbacks.UpdateDaemonSet, deleteTestDaemonSet},
 		{"StatefulSet", createTestStatefulSetWithAnnotations, callbacks.UpdateStatefulSet, deleteTestStatefulSet},
+		{"ReplicaSet", createTestReplicaSetWithAnnotations, callbacks.UpdateReplicaSet, deleteTestReplicaSet},
 	}
 
 	for _, tt := range tests {
@@ -387,8 +545,17 @@ func TestPatchResources(t *testing.T) {
 			assert.NoError(t, err)
 			assert.Equal(t, "test", patchedResource.(*appsv1.StatefulSet).Annotations["test"])
 		}},
-		{"CronJob", createTestCronJobWithAnnotations, callbacks.PatchCronJob, deleteTestCronJob, func(err error) {
-			assert.EqualError(t, err, "not supported patching: CronJob")
+		{"ReplicaSet", createTestReplicaSetWithAnnotations, callbacks.PatchReplicaSet, deleteTestReplicaSet, func(err error) {
+			assert.NoError(t, err)
+			patchedResource, err := callbacks.GetReplicaSetItem(clients, "test-replicaset", fixtures.namespace)
+			assert.NoError(t, err)
+			assert.Equal(t, "test", patchedResource.(*appsv1.ReplicaSet).Annotations["test"])
+		}},
+		{"CronJob", createTestUpdateTemplateCronJob, callbacks.PatchCronJob, deleteTestCronJob, func(err error) {
+			assert.NoError(t, err)
+			patchedResource, err := callbacks.GetCronJobItem(clients, "test-cronjob", fixtures.namespace)
+			assert.NoError(t, err)
+			assert.Equal(t, "test", patchedResource.(*batchv1.CronJob).Annotations["test"])
 		}},
 		{"Job", createTestJobWithAnnotations, callbacks.PatchJob, deleteTestJob, func(err error) {
 			assert.EqualError(t, err, "not supported patching: Job")
@@ -438,6 +605,49 @@ func TestCreateJobFromCronjob(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestReloadCronJob(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          string
+		expectedPatched bool
+		expectedJobs    int
+		wantErr         bool
+	}{
+		{name: "Trigger by default", expectedJobs: 1},
+		{name: "Trigger", policy: constants.TriggerCronJobReloadPolicy, expectedJobs: 1},
+		{name: "Update template", policy: constants.UpdateTemplateCronJobReloadPolicy, expectedPatched: true},
+		{name: "Update template and trigger", policy: constants.UpdateAndTriggerCronJobReloadPolicy, expectedPatched: true, expectedJobs: 1},
+		{name: "Invalid policy", policy: "never", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default", UID: "nightly-uid"}}
+			if tt.policy != "" {
+				cronJob.Annotations = map[string]string{options.CronJobReloadPolicyAnnotation: tt.policy}
+			}
+			fakeClient := fake.NewClientset(cronJob)
+			patch := fmt.Appendf(nil, callbacks.GetCronJobPatchTemplates().AnnotationTemplate, "reloaded", "true")
+
+			err := callbacks.PatchCronJob(kube.Clients{KubernetesClient: fakeClient}, "default", cronJob, patchtypes.StrategicMergePatchType, patch)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			result, err := fakeClient.BatchV1().CronJobs("default").Get(context.TODO(), "nightly", metav1.GetOptions{})
+			assert.NoError(t, err)
+			_, patched := result.Spec.JobTemplate.Spec.Template.Annotations["reloaded"]
+			assert.Equal(t, tt.expectedPatched, patched)
+
+			jobs, err := fakeClient.BatchV1().Jobs("default").List(context.TODO(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, jobs.Items, tt.expectedJobs)
+		})
+	}
+}
+
 func TestReCreateJobFromJob(t *testing.T) {
 	fixtures := newTestFixtures()
 
@@ -451,6 +661,222 @@ func TestReCreateJobFromJob(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestReCreateJobFromJobCreateNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		currentTTL  *int32
+		expectedTTL *int32
+	}{
+		{
+			name:        "Superseded job TTL",
+			annotations: map[string]string{options.SupersededJobTTLAnnotation: "1h"},
+			expectedTTL: ptr.To[int32](3600),
+		},
+		{
+			name:        "Lower job TTL is kept",
+			annotations: map[string]string{options.SupersededJobTTLAnnotation: "1h"},
+			currentTTL:  ptr.To[int32](60),
+			expectedTTL: ptr.To[int32](60),
+		},
+		{
+			name: "Superseded job kept without TTL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{options.JobReloadPolicyAnnotation: constants.CreateNewJobReloadPolicy}
+			maps.Copy(annotations, tt.annotations)
+			oldJob := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default", UID: "migrate-uid", Annotations: annotations},
+				Spec: batchv1.JobSpec{
+					Selector:                &metav1.LabelSelector{MatchLabels: map[string]string{batchv1.ControllerUidLabel: "migrate-uid"}},
+					TTLSecondsAfterFinished: tt.currentTTL,
+				},
+			}
+			fakeClient := fake.NewClientset(oldJob)
+			fakeClients := kube.Clients{KubernetesClient: fakeClient}
+
+			err := callbacks.ReCreateJobFromjob(fakeClients, "default", oldJob)
+			assert.NoError(t, err)
+
+			jobs, err := fakeClient.BatchV1().Jobs("default").List(context.TODO(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, jobs.Items, 2, "Old job should be kept")
+
+			superseded, err := fakeClient.BatchV1().Jobs("default").Get(context.TODO(), "migrate", metav1.GetOptions{})
+			assert.NoError(t, err)
+			newName := superseded.Annotations["reloader.stakater.com/superseded-by"]
+			assert.Regexp(t, "^migrate-[a-z0-9]{5}$", newName)
+			assert.True(t, callbacks.IsSupersededJob(superseded))
+			assert.Equal(t, tt.expectedTTL, superseded.Spec.TTLSecondsAfterFinished)
+
+			newJob, err := fakeClient.BatchV1().Jobs("default").Get(context.TODO(), newName, metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "migrate", newJob.Annotations["reloader.stakater.com/rerun-of"])
+			assert.Nil(t, newJob.Spec.Selector, "Selector should be generated for the new job")
+
+			// Reruns of the new job are named after the original job
+			err = callbacks.ReCreateJobFromjob(fakeClients, "default", newJob)
+			assert.NoError(t, err)
+			rerun, err := fakeClient.BatchV1().Jobs("default").Get(context.TODO(), newName, metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Regexp(t, "^migrate-[a-z0-9]{5}$", rerun.Annotations["reloader.stakater.com/superseded-by"])
+		})
+	}
+}
+
+func TestGetJobReloadPolicy(t *testing.T) {
+	policy, err := callbacks.GetJobReloadPolicy(&batchv1.Job{})
+	assert.NoError(t, err)
+	assert.Equal(t, constants.RecreateJobReloadPolicy, policy)
+
+	policy, err = callbacks.GetJobReloadPolicy(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{options.JobReloadPolicyAnnotation: constants.RerunFinishedJobReloadPolicy},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, constants.RerunFinishedJobReloadPolicy, policy)
+
+	_, err = callbacks.GetJobReloadPolicy(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{options.JobReloadPolicyAnnotation: "never"},
+	}})
+	assert.Error(t, err)
+}
+
+func TestGetUnmanagedItems(t *testing.T) {
+	namespace := "unmanaged"
+	owner := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "owner", UID: "owner-uid"}}
+	optIn := map[string]string{options.ReloadUnmanagedAnnotation: "true"}
+	recreate := map[string]string{options.ReloadUnmanagedAnnotation: "recreate"}
+	mirror := map[string]string{options.ReloadUnmanagedAnnotation: "true", v1.MirrorPodAnnotationKey: "hash"}
+	ownerRefs := []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind("Deployment"))}
+
+	fakeClients := kube.Clients{KubernetesClient: fake.NewClientset(
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "opted-in", Namespace: namespace, Annotations: optIn}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "recreate", Namespace: namespace, Annotations: recreate}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "not-opted-in", Namespace: namespace}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owned", Namespace: namespace, Annotations: optIn, OwnerReferences: ownerRefs}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "mirror", Namespace: namespace, Annotations: mirror}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "opted-in", Namespace: namespace, Annotations: optIn}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "owned", Namespace: namespace, Annotations: optIn, OwnerReferences: ownerRefs}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "disabled", Namespace: namespace, Annotations: map[string]string{options.ReloadUnmanagedAnnotation: "false"}}},
+	)}
+
+	names := func(items []runtime.Object) []string {
+		var result []string
+		for _, item := range items {
+			accessor, err := meta.Accessor(item)
+			assert.NoError(t, err)
+			result = append(result, accessor.GetName())
+		}
+		return result
+	}
+
+	assert.ElementsMatch(t, []string{"opted-in", "recreate"}, names(callbacks.GetPodItems(fakeClients, namespace)))
+	assert.ElementsMatch(t, []string{"opted-in"}, names(callbacks.GetReplicaSetItems(fakeClients, namespace)))
+}
+
+func TestGetPodItemsFromCache(t *testing.T) {
+	namespace := "unmanaged"
+	cached := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "cached", Namespace: namespace, Annotations: map[string]string{options.ReloadUnmanagedAnnotation: "true"}}}
+	notOptedIn := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "not-opted-in", Namespace: namespace}}
+	callbacks.SetPodLister(func(string) ([]*v1.Pod, bool) { return []*v1.Pod{cached, notOptedIn}, true })
+	defer callbacks.SetPodLister(func(string) ([]*v1.Pod, bool) { return nil, false })
+
+	// The API server knows none of the cached pods, so they can only be found in the cache
+	items := callbacks.GetPodItems(kube.Clients{KubernetesClient: fake.NewClientset()}, namespace)
+
+	assert.Len(t, items, 1)
+	pod, ok := items[0].(*v1.Pod)
+	assert.True(t, ok)
+	assert.Equal(t, "cached", pod.Name)
+	assert.NotSame(t, cached, pod, "cached pods should be copied before they are reloaded")
+}
+
+func TestReloadPod(t *testing.T) {
+	recreatedFromKey := "reloader.stakater.com/recreated-from"
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		evictionErr         error
+		expectedRecreate    bool
+		expectedRecreatedOf string
+	}{
+		{name: "Evict", annotations: map[string]string{options.ReloadUnmanagedAnnotation: "true"}},
+		{name: "Evict and recreate", annotations: map[string]string{options.ReloadUnmanagedAnnotation: "recreate"}, expectedRecreate: true, expectedRecreatedOf: "legacy"},
+		{name: "Recreate a recreated pod", annotations: map[string]string{options.ReloadUnmanagedAnnotation: "recreate", recreatedFromKey: "first"}, expectedRecreate: true, expectedRecreatedOf: "first"},
+		{name: "Eviction blocked", annotations: map[string]string{options.ReloadUnmanagedAnnotation: "recreate"}, evictionErr: apierrors.NewTooManyRequests("disruption budget", 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "legacy",
+					Namespace:   "default",
+					UID:         "legacy-uid",
+					Annotations: tt.annotations,
+				},
+				Spec: v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{{Name: "app"}}},
+			}
+			fakeClient := fake.NewClientset(pod)
+			var createdBeforeEviction bool
+			fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				pods, err := fakeClient.Tracker().List(v1.SchemeGroupVersion.WithResource("pods"), v1.SchemeGroupVersion.WithKind("Pod"), "default")
+				if err != nil {
+					return true, nil, err
+				}
+				createdBeforeEviction = len(pods.(*v1.PodList).Items) > 1
+				if tt.evictionErr != nil {
+					return true, nil, tt.evictionErr
+				}
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				return true, nil, fakeClient.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+			})
+			// The fake client doesn't generate names
+			fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if created, ok := action.(k8stesting.CreateAction).GetObject().(*v1.Pod); ok && created.Name == "" {
+					created.Name = created.GenerateName + "abcde"
+				}
+				return false, nil, nil
+			})
+			fakeClients := kube.Clients{KubernetesClient: fakeClient}
+
+			reloaded := pod.DeepCopy()
+			reloaded.Spec.Containers[0].Env = []v1.EnvVar{{Name: "STAKATER_TEST_CONFIGMAP", Value: "sha"}}
+			err := callbacks.ReloadPod(fakeClients, "default", reloaded)
+
+			pods, listErr := fakeClient.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{})
+			assert.NoError(t, listErr)
+
+			if tt.evictionErr != nil {
+				assert.Error(t, err)
+				assert.Len(t, pods.Items, 1, "Replacement should be deleted when the pod can't be evicted")
+				assert.Equal(t, "legacy", pods.Items[0].Name)
+				return
+			}
+			assert.NoError(t, err)
+
+			if !tt.expectedRecreate {
+				assert.Empty(t, pods.Items, "Evicted pod should not be recreated")
+				return
+			}
+
+			assert.True(t, createdBeforeEviction, "Replacement should be created before the pod is evicted")
+			assert.Len(t, pods.Items, 1)
+			recreated := pods.Items[0]
+			assert.Equal(t, tt.expectedRecreatedOf+"-abcde", recreated.Name)
+			assert.Equal(t, tt.expectedRecreatedOf, recreated.Annotations[recreatedFromKey])
+			assert.Equal(t, reloaded.Spec.Containers, recreated.Spec.Containers, "Pod should be recreated with the changes of the reload strategy")
+			assert.Empty(t, recreated.Spec.NodeName, "Recreated pod should be scheduled again")
+		})
+	}
+}
+
 func TestGetVolumes(t *testing.T) {
 	fixtures := newTestFixtures()
 
@@ -464,6 +890,8 @@ func TestGetVolumes(t *testing.T) {
 		{"Job", createResourceWithVolumes(&batchv1.Job{}, fixtures.defaultVolumes), callbacks.GetJobVolumes},
 		{"DaemonSet", createResourceWithVolumes(&appsv1.DaemonSet{}, fixtures.defaultVolumes), callbacks.GetDaemonSetVolumes},
 		{"StatefulSet", createResourceWithVolumes(&appsv1.StatefulSet{}, fixtures.defaultVolumes), callbacks.GetStatefulSetVolumes},
+		{"ReplicaSet", createResourceWithVolumes(&appsv1.ReplicaSet{}, fixtures.defaultVolumes), callbacks.GetReplicaSetVolumes},
+		{"Pod", createResourceWithVolumes(&v1.Pod{}, fixtures.defaultVolumes), callbacks.GetPodVolumes},
 	}
 
 	for _, tt := range tests {
@@ -473,6 +901,33 @@ func TestGetVolumes(t *testing.T) {
 	}
 }
 
+func TestGetPodSpec(t *testing.T) {
+	fixtures := newTestFixtures()
+
+	tests := []struct {
+		name     string
+		resource runtime.Object
+		getFunc  func(runtime.Object) *v1.PodSpec
+	}{
+		{"Deployment", createResourceWithVolumes(&appsv1.Deployment{}, fixtures.defaultVolumes), callbacks.GetDeploymentPodSpec},
+		{"CronJob", createResourceWithVolumes(&batchv1.CronJob{}, fixtures.defaultVolumes), callbacks.GetCronJobPodSpec},
+		{"Job", createResourceWithVolumes(&batchv1.Job{}, fixtures.defaultVolumes), callbacks.GetJobPodSpec},
+		{"DaemonSet", createResourceWithVolumes(&appsv1.DaemonSet{}, fixtures.defaultVolumes), callbacks.GetDaemonSetPodSpec},
+		{"StatefulSet", createResourceWithVolumes(&appsv1.StatefulSet{}, fixtures.defaultVolumes), callbacks.GetStatefulSetPodSpec},
+		{"ReplicaSet", createResourceWithVolumes(&appsv1.ReplicaSet{}, fixtures.defaultVolumes), callbacks.GetReplicaSetPodSpec},
+		{"Pod", createResourceWithVolumes(&v1.Pod{}, fixtures.defaultVolumes), callbacks.GetPodPodSpec},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.getFunc(tt.resource)
+			assert.NotNil(t, spec)
+			assert.Equal(t, fixtures.defaultVolumes, spec.Volumes)
+			assert.Nil(t, tt.getFunc(&v1.ConfigMap{}), "Other resources have no pod spec")
+		})
+	}
+}
+
 func TesGetPatchTemplateAnnotation(t *testing.T) {
 	templates := callbacks.GetPatchTemplates()
 	assert.NotEmpty(t, templates.AnnotationTemplate)
@@ -632,6 +1087,8 @@ func createResourceWithPodAnnotations(obj runtime.Object, annotations map[string
 		v.Spec.Template.Annotations = annotations
 	case *argorolloutv1alpha1.Rollout:
 		v.Spec.Template.Annotations = annotations
+	case *appsv1.ReplicaSet:
+		v.Spec.Template.Annotations = annotations
 	}
 	return obj
 }
@@ -650,6 +1107,10 @@ func createResourceWithContainers(obj runtime.Object, containers []v1.Container)
 		v.Spec.Template.Spec.Containers = containers
 	case *argorolloutv1alpha1.Rollout:
 		v.Spec.Template.Spec.Containers = containers
+	case *appsv1.ReplicaSet:
+		v.Spec.Template.Spec.Containers = containers
+	case *v1.Pod:
+		v.Spec.Containers = containers
 	}
 	return obj
 }
@@ -668,6 +1129,10 @@ func createResourceWithInitContainers(obj runtime.Object, initContainers []v1.Co
 		v.Spec.Template.Spec.InitContainers = initContainers
 	case *argorolloutv1alpha1.Rollout:
 		v.Spec.Template.Spec.InitContainers = initContainers
+	case *appsv1.ReplicaSet:
+		v.Spec.Template.Spec.InitContainers = initContainers
+	case *v1.Pod:
+		v.Spec.InitContainers = initContainers
 	}
 	return obj
 }
@@ -684,6 +1149,10 @@ func createResourceWithVolumes(obj runtime.Object, volumes []v1.Volume) runtime.
 		v.Spec.Template.Spec.Volumes = volumes
 	case *appsv1.StatefulSet:
 		v.Spec.Template.Spec.Volumes = volumes
+	case *appsv1.ReplicaSet:
+		v.Spec.Template.Spec.Volumes = volumes
+	case *v1.Pod:
+		v.Spec.Volumes = volumes
 	}
 	return obj
 }
@@ -744,6 +1213,20 @@ func createTestCronJobWithAnnotations(clients kube.Clients, namespace, version s
 	return clients.KubernetesClient.BatchV1().CronJobs(namespace).Create(context.TODO(), cronJob, metav1.CreateOptions{})
 }
 
+func createTestUpdateTemplateCronJob(clients kube.Clients, namespace, version string) (runtime.Object, error) {
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cronjob",
+			Namespace: namespace,
+			Annotations: map[string]string{
+				"version":                             version,
+				options.CronJobReloadPolicyAnnotation: constants.UpdateTemplateCronJobReloadPolicy,
+			},
+		},
+	}
+	return clients.KubernetesClient.BatchV1().CronJobs(namespace).Create(context.TODO(), cronJob, metav1.CreateOptions{})
+}
+
 func deleteTestCronJob(clients kube.Clients, namespace, name string) error {
 	return clients.KubernetesClient.BatchV1().CronJobs(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
 }
@@ -763,6 +1246,21 @@ func deleteTestJob(clients kube.Clients, namespace, name string) error {
 	return clients.KubernetesClient.BatchV1().Jobs(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
 }
 
+func createTestReplicaSetWithAnnotations(clients kube.Clients, namespace, version string) (runtime.Object, error) {
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-replicaset",
+			Namespace:   namespace,
+			Annotations: map[string]string{"version": version},
+		},
+	}
+	return clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Create(context.TODO(), replicaSet, metav1.CreateOptions{})
+}
+
+func deleteTestReplicaSet(clients kube.Clients, namespace, name string) error {
+	return clients.KubernetesClient.AppsV1().ReplicaSets(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
 func isControllerOwner(kind, name string, ownerRefs []metav1.OwnerReference) bool {
 	for _, ownerRef := range ownerRefs {
 		if *ownerRef.Controller && ownerRef.Kind == kind && ownerRef.Name == name {
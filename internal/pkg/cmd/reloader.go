@@ -7,6 +7,7 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/stakater/Reloader/internal/pkg/constants"
@@ -14,9 +15,18 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/config"
 	"github.com/stakater/Reloader/internal/pkg/controller"
+	"github.com/stakater/Reloader/internal/pkg/crypto"
+	"github.com/stakater/Reloader/internal/pkg/handler"
 	"github.com/stakater/Reloader/internal/pkg/metrics"
 	"github.com/stakater/Reloader/internal/pkg/options"
 	"github.com/stakater/Reloader/internal/pkg/util"
@@ -39,10 +49,28 @@ func NewReloaderCommand() *cobra.Command {
 	return cmd
 }
 
-func validateFlags(*cobra.Command, []string) error {
+// configFile is the config file applied to the options, nil if there is none
+var configFile *config.File
+
+func validateFlags(cmd *cobra.Command, _ []string) error {
+	// Flags take precedence over environment variables, which take precedence over the config file
+	if err := util.SetFlagsFromEnv(cmd.Flags()); err != nil {
+		return err
+	}
+	if options.ConfigFile != "" {
+		var err error
+		if configFile, err = config.Load(options.ConfigFile, cmd.Flags()); err != nil {
+			return err
+		}
+	}
+	return validateOptions()
+}
+
+// validateOptions validates the options set by flags and the config file
+func validateOptions() error {
 	// Ensure the reload strategy is one of the following...
 	var validReloadStrategy bool
-	valid := []string{constants.EnvVarsReloadStrategy, constants.AnnotationsReloadStrategy}
+	valid := handler.ReloadStrategies
 	for _, s := range valid {
 		if s == options.ReloadStrategy {
 			validReloadStrategy = true
@@ -54,6 +82,24 @@ func validateFlags(*cobra.Command, []string) error {
 		return errors.New(err)
 	}
 
+	if !slices.Contains(crypto.HashAlgorithms, options.HashAlgorithm) {
+		return fmt.Errorf("hash-algorithm must be one of: %s", strings.Join(crypto.HashAlgorithms, ", "))
+	}
+	if options.HashAlgorithm == crypto.HMACSHA256Algorithm && options.HashKeySecret == "" {
+		return fmt.Errorf("hash-key-secret is required by hash algorithm %s", crypto.HMACSHA256Algorithm)
+	}
+
+	if _, err := common.GetNamespaceLabelSelector(slices.Clone(options.NamespaceSelectors)); err != nil {
+		return fmt.Errorf("invalid namespace-selector: %w", err)
+	}
+	if _, err := common.GetResourceLabelSelector(slices.Clone(options.ResourceSelectors)); err != nil {
+		return fmt.Errorf("invalid resource-label-selector: %w", err)
+	}
+
+	if options.GitOpsSync && options.GitOpsValuesKey == "" {
+		return errors.New("gitops-values-key is required by gitops-sync, owners can't roll the pods without the hashes")
+	}
+
 	// Validate that HA options are correct
 	if options.EnableHA {
 		if err := validateHAEnvs(); err != nil {
@@ -117,6 +163,116 @@ func resolveWatchNamespaces(namespaces []string, kubernetesNamespace string) ([]
 	return []string{v1.NamespaceAll}, true
 }
 
+// serviceAccountLister starts informers of the ServiceAccounts in the watched namespaces and returns a func reading
+// them from their caches. ServiceAccounts are only found once the informer of their namespace synced.
+func serviceAccountLister(client kubernetes.Interface, watchNamespaces []string, stopCh <-chan struct{}) func(namespace, name string) (*corev1.ServiceAccount, bool) {
+	namespaceInformers := startNamespaceInformers(client, watchNamespaces, stopCh, func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().ServiceAccounts().Informer()
+	})
+
+	return func(namespace, name string) (*corev1.ServiceAccount, bool) {
+		informer, found := syncedNamespaceInformer(namespaceInformers, namespace)
+		if !found {
+			return nil, false
+		}
+		obj, exists, err := informer.GetStore().GetByKey(namespace + "/" + name)
+		if err != nil || !exists {
+			return nil, false
+		}
+		serviceAccount, ok := obj.(*corev1.ServiceAccount)
+		return serviceAccount, ok
+	}
+}
+
+// podLister starts informers of the pods in the watched namespaces and returns a func listing the pods of a namespace
+// from their caches. Only unmanaged pods are cached in full, pods of a controller are trimmed to their owner references.
+func podLister(client kubernetes.Interface, watchNamespaces []string, stopCh <-chan struct{}) func(namespace string) ([]*corev1.Pod, bool) {
+	namespaceInformers := startNamespaceInformers(client, watchNamespaces, stopCh, func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+		informer := factory.Core().V1().Pods().Informer()
+		if err := informer.SetTransform(trimControlledPod); err != nil {
+			logrus.Warnf("Failed to trim the cached pods: %v", err)
+		}
+		return informer
+	})
+
+	return func(namespace string) ([]*corev1.Pod, bool) {
+		informer, found := syncedNamespaceInformer(namespaceInformers, namespace)
+		if !found {
+			return nil, false
+		}
+		objs, err := informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		if err != nil {
+			return nil, false
+		}
+		pods := make([]*corev1.Pod, 0, len(objs))
+		for _, obj := range objs {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				pods = append(pods, pod)
+			}
+		}
+		return pods, true
+	}
+}
+
+// trimControlledPod drops the managed fields of pods, and everything but the identity and owner references of pods
+// having a managing controller, as these are never reloaded as unmanaged pods
+func trimControlledPod(obj any) (any, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	if v1.GetControllerOf(pod) == nil {
+		pod.ManagedFields = nil
+		return pod, nil
+	}
+	return &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+			OwnerReferences: pod.OwnerReferences,
+		},
+	}, nil
+}
+
+// startNamespaceInformers starts the informer created by newInformer for each of the watched namespaces
+func startNamespaceInformers(client kubernetes.Interface, watchNamespaces []string, stopCh <-chan struct{}, newInformer func(factory informers.SharedInformerFactory) cache.SharedIndexInformer) map[string]cache.SharedIndexInformer {
+	namespaceInformers := make(map[string]cache.SharedIndexInformer, len(watchNamespaces))
+	for _, namespace := range watchNamespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(namespace))
+		namespaceInformers[namespace] = newInformer(factory)
+		factory.Start(stopCh)
+	}
+	return namespaceInformers
+}
+
+// syncedNamespaceInformer returns the informer of a namespace, or of all namespaces when watching globally, once it
+// synced
+func syncedNamespaceInformer(namespaceInformers map[string]cache.SharedIndexInformer, namespace string) (cache.SharedIndexInformer, bool) {
+	informer, found := namespaceInformers[namespace]
+	if !found {
+		informer, found = namespaceInformers[v1.NamespaceAll]
+	}
+	if !found || !informer.HasSynced() {
+		return nil, false
+	}
+	return informer, true
+}
+
+// namespaceFilter returns the check whether Reloader watches a namespace: one of the watched namespaces, or when watching
+// globally any namespace that isn't ignored and matches the namespace selector. The options are read on every call, as
+// they change when the config file is reloaded.
+func namespaceFilter(watchNamespaces []string, isGlobal bool) func(namespace string) bool {
+	if !isGlobal {
+		return func(namespace string) bool { return slices.Contains(watchNamespaces, namespace) }
+	}
+	return func(namespace string) bool {
+		return !slices.Contains(options.NamespacesToIgnore, namespace) &&
+			(len(options.NamespaceSelectors) == 0 || controller.InSelectedNamespaces(namespace))
+	}
+}
+
 // namespaceWatchScopeMessage returns the startup log message describing the
 // namespace scope Reloader will watch when KUBERNETES_NAMESPACE is unset
 // (global mode). It reflects --namespaces-to-ignore so the log is not
@@ -131,6 +287,33 @@ func namespaceWatchScopeMessage(ignoredNamespaces []string) string {
 	return "KUBERNETES_NAMESPACE is unset, will detect changes in all namespaces."
 }
 
+// configureHashAlgorithm sets the algorithm of the hashes written into workloads, reading the key of the hmac-sha256
+// algorithm from the hash key secret
+func configureHashAlgorithm(client kubernetes.Interface) error {
+	var key []byte
+	if options.HashAlgorithm == crypto.HMACSHA256Algorithm {
+		namespace, name, found := strings.Cut(options.HashKeySecret, "/")
+		if !found {
+			namespace, name = os.Getenv(constants.PodNamespaceEnv), options.HashKeySecret
+		}
+		secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), name, v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get hash key secret '%s': %w", options.HashKeySecret, err)
+		}
+		key = secret.Data[constants.HashKeySecretKey]
+		if len(key) == 0 {
+			return fmt.Errorf("hash key secret '%s' has no '%s' entry", options.HashKeySecret, constants.HashKeySecretKey)
+		}
+	}
+	if err := crypto.SetHashAlgorithm(options.HashAlgorithm, key); err != nil {
+		return err
+	}
+	if options.HashAlgorithm != crypto.SHA1Algorithm {
+		logrus.Infof("Hashing resources with %s", options.HashAlgorithm)
+	}
+	return nil
+}
+
 func startReloader(cmd *cobra.Command, args []string) {
 	common.GetCommandLineOptions()
 	err := configureLogging(options.LogFormat, options.LogLevel)
@@ -143,6 +326,7 @@ func startReloader(cmd *cobra.Command, args []string) {
 	if !isGlobal && len(options.Namespaces) > 0 {
 		logrus.Infof("Watching scoped namespaces: %s", strings.Join(watchNamespaces, ", "))
 	}
+	handler.SetNamespaceFilter(namespaceFilter(watchNamespaces, isGlobal))
 
 	// create the clientset
 	clientset, err := kube.GetKubernetesClient()
@@ -150,6 +334,10 @@ func startReloader(cmd *cobra.Command, args []string) {
 		logrus.Fatal(err)
 	}
 
+	if err := configureHashAlgorithm(clientset); err != nil {
+		logrus.Fatal(err)
+	}
+
 	ignoredResourcesList, err := util.GetIgnoredResourcesList()
 	if err != nil {
 		logrus.Fatal(err)
@@ -168,8 +356,14 @@ func startReloader(cmd *cobra.Command, args []string) {
 		if err != nil {
 			logrus.Fatal(err)
 		}
-	} else if len(options.NamespacesToIgnore) > 0 {
-		logrus.Warnf("namespaces-to-ignore is set but is only honored in global mode (watchGlobally=true); ignoring it.")
+	} else {
+		if len(options.NamespacesToIgnore) > 0 {
+			logrus.Warnf("namespaces-to-ignore is set but is only honored in global mode (watchGlobally=true); ignoring it.")
+		}
+		if options.EnableNamespaceAnnotations {
+			logrus.Warnf("enable-namespace-annotations is set but is only honored in global mode (watchGlobally=true); ignoring it.")
+			options.EnableNamespaceAnnotations = false
+		}
 	}
 
 	resourceLabelSelector, err := common.GetResourceLabelSelector(options.ResourceSelectors)
@@ -198,7 +392,27 @@ func startReloader(cmd *cobra.Command, args []string) {
 				continue
 			}
 
-			if ignoredResourcesList.Contains(k) || (len(namespaceLabelSelector) == 0 && k == "namespaces") {
+			if k == constants.ExternalSecretController && !shouldRunExternalSecretController() {
+				continue
+			}
+
+			if k == constants.CertificateController && !shouldRunCertificateController() {
+				continue
+			}
+
+			if k == constants.ReloaderPolicyController && !shouldRunReloaderPolicyController() {
+				continue
+			}
+
+			if k == constants.ClusterReloaderPolicyController && !shouldRunClusterReloaderPolicyController(isGlobal) {
+				continue
+			}
+
+			// Namespaces are watched for their labels selecting them, as well as the annotations and labels read by the
+			// namespace annotations and ClusterReloaderPolicies. With a config file, the namespace selector may be
+			// set later on.
+			watchNamespaceMetadata := options.EnableNamespaceAnnotations || (isGlobal && (options.EnableReloaderPolicies || configFile != nil))
+			if ignoredResourcesList.Contains(k) || (len(namespaceLabelSelector) == 0 && !watchNamespaceMetadata && k == "namespaces") {
 				continue
 			}
 
@@ -208,15 +422,25 @@ func startReloader(cmd *cobra.Command, args []string) {
 			}
 
 			controllers = append(controllers, c)
+		}
+	}
 
-			// If HA is enabled we only run the controller when
-			if options.EnableHA {
-				continue
-			}
+	// Handlers read secrets from the caches of all secrets controllers, so the lister is set before any of them runs
+	handler.SetSecretLister(controller.SecretLister(controllers))
+	if options.WatchImagePullSecrets {
+		handler.SetServiceAccountLister(serviceAccountLister(clientset, watchNamespaces, wait.NeverStop))
+	}
+	if options.ReloadUnmanagedWorkloads {
+		callbacks.SetPodLister(podLister(clientset, watchNamespaces, wait.NeverStop))
+	}
+
+	// If HA is enabled we only run the controllers when leading
+	if !options.EnableHA {
+		for _, c := range controllers {
 			// Now let's start the controller
 			stop := make(chan struct{})
 			defer close(stop)
-			logrus.Infof("Starting Controller to watch resource type: %s in namespace: %s", k, currentNamespace)
+			logrus.Infof("Starting Controller to watch resource type: %s in namespace: %s", c.Resource(), c.Namespace())
 			go c.Run(1, stop)
 		}
 	}
@@ -228,18 +452,70 @@ func startReloader(cmd *cobra.Command, args []string) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		leadership.RunLeaderElection(lock, ctx, cancel, podName, controllers)
+	} else {
+		// Resume timers of workloads paused before a restart only lived in the memory of the previous process
+		go handler.RestorePauseTimers(watchNamespaces, wait.NeverStop, controller.PauseTimersSynced(controllers)...)
 	}
 
 	common.PublishMetaInfoConfigmap(clientset)
 
+	if configFile != nil {
+		go configFile.Watch(config.WatchPeriod, wait.NeverStop, validateOptions, func() {
+			applyReloadedOptions(clientset, controllers, isGlobal)
+		})
+	}
+
 	if options.EnablePProf {
 		go startPProfServer()
 	}
 
+	if options.EnableReloaderPolicies && options.EnablePolicyDebug {
+		handler.SetupPolicyDebugEndpoint()
+	}
+
 	leadership.SetupLivenessEndpoint()
 	logrus.Fatal(http.ListenAndServe(constants.DefaultHttpListenAddr, nil))
 }
 
+// applyReloadedOptions applies the options changed by the config file that aren't read on every event. Only the
+// informers listing resources with a changed resource selector are restarted.
+func applyReloadedOptions(clientset kubernetes.Interface, controllers []*controller.Controller, isGlobal bool) {
+	if err := configureLogging(options.LogFormat, options.LogLevel); err != nil {
+		logrus.Warn(err)
+	}
+	if isGlobal {
+		namespaceLabelSelector, err := common.GetNamespaceLabelSelector(slices.Clone(options.NamespaceSelectors))
+		if err != nil {
+			logrus.Errorf("Ignoring invalid namespace-selector: %v", err)
+		}
+		for _, c := range controllers {
+			c.SetIgnoredNamespaces(options.NamespacesToIgnore)
+		}
+		if err == nil {
+			// The namespace controller selects the namespaces first, which the other controllers then filter by
+			for _, c := range controllers {
+				if c.Resource() == "namespaces" {
+					c.SetNamespaceSelector(namespaceLabelSelector)
+				}
+			}
+			for _, c := range controllers {
+				if c.Resource() != "namespaces" {
+					c.SetNamespaceSelector(namespaceLabelSelector)
+				}
+			}
+		}
+	}
+	resourceLabelSelector, err := common.GetResourceLabelSelector(slices.Clone(options.ResourceSelectors))
+	if err != nil {
+		logrus.Errorf("Ignoring invalid resource-label-selector: %v", err)
+	} else {
+		for _, c := range controllers {
+			c.SetResourceSelector(resourceLabelSelector)
+		}
+	}
+	common.PublishMetaInfoConfigmap(clientset)
+}
+
 func startPProfServer() {
 	logrus.Infof("Starting pprof server on %s", options.PProfAddr)
 	if err := http.ListenAndServe(options.PProfAddr, nil); err != nil {
@@ -258,3 +534,55 @@ func shouldRunCSIController() bool {
 	}
 	return true
 }
+
+func shouldRunExternalSecretController() bool {
+	if !options.EnableExternalSecretsIntegration {
+		logrus.Info("Skipping externalsecrets controller: EnableExternalSecretsIntegration is disabled")
+		return false
+	}
+	if !kube.IsExternalSecretsInstalled {
+		logrus.Info("Skipping externalsecrets controller: External Secrets Operator CRDs not installed")
+		return false
+	}
+	return true
+}
+
+func shouldRunCertificateController() bool {
+	if !options.EnableCertManagerIntegration {
+		logrus.Info("Skipping certificates controller: EnableCertManagerIntegration is disabled")
+		return false
+	}
+	if !kube.IsCertManagerInstalled {
+		logrus.Info("Skipping certificates controller: cert-manager CRDs not installed")
+		return false
+	}
+	return true
+}
+
+func shouldRunReloaderPolicyController() bool {
+	if !options.EnableReloaderPolicies {
+		logrus.Info("Skipping reloaderpolicies controller: EnableReloaderPolicies is disabled")
+		return false
+	}
+	if !kube.IsReloaderPolicyInstalled {
+		logrus.Info("Skipping reloaderpolicies controller: ReloaderPolicy CRD not installed")
+		return false
+	}
+	return true
+}
+
+func shouldRunClusterReloaderPolicyController(isGlobal bool) bool {
+	if !options.EnableReloaderPolicies {
+		logrus.Info("Skipping clusterreloaderpolicies controller: EnableReloaderPolicies is disabled")
+		return false
+	}
+	if !isGlobal {
+		logrus.Info("Skipping clusterreloaderpolicies controller: ClusterReloaderPolicies are only honored in global mode (watchGlobally=true)")
+		return false
+	}
+	if !kube.IsReloaderPolicyInstalled {
+		logrus.Info("Skipping clusterreloaderpolicies controller: ReloaderPolicy CRD not installed")
+		return false
+	}
+	return true
+}
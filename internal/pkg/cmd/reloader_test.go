@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/crypto"
+	"github.com/stakater/Reloader/internal/pkg/options"
 )
 
 func TestResolveWatchNamespaces(t *testing.T) {
@@ -97,3 +105,137 @@ func TestNamespaceWatchScopeMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestNamespaceFilter(t *testing.T) {
+	defer func(ignored, selectors []string) {
+		options.NamespacesToIgnore, options.NamespaceSelectors = ignored, selectors
+	}(options.NamespacesToIgnore, options.NamespaceSelectors)
+	options.NamespacesToIgnore = []string{"kube-system"}
+	options.NamespaceSelectors = []string{}
+
+	scoped := namespaceFilter([]string{"team-a", "kube-system"}, false)
+	assert.True(t, scoped("team-a"))
+	assert.True(t, scoped("kube-system"), "Ignored namespaces only apply when watching globally")
+	assert.False(t, scoped("team-b"))
+
+	global := namespaceFilter([]string{v1.NamespaceAll}, true)
+	assert.True(t, global("team-b"))
+	assert.False(t, global("kube-system"))
+
+	// Namespaces are matched by the namespace selector once it is set, e.g. by reloading the config file
+	options.NamespaceSelectors = []string{"env=prod"}
+	assert.False(t, global("team-b"), "Namespaces not matching the namespace selector should not be watched")
+}
+
+func TestConfigureHashAlgorithm(t *testing.T) {
+	defer func() {
+		options.HashAlgorithm, options.HashKeySecret = crypto.SHA1Algorithm, ""
+		_ = crypto.SetHashAlgorithm(crypto.SHA1Algorithm, nil)
+	}()
+	t.Setenv(constants.PodNamespaceEnv, "reloader")
+	client := testclient.NewClientset(
+		&corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Name: "hash-key", Namespace: "reloader"},
+			Data:       map[string][]byte{constants.HashKeySecretKey: []byte("key")},
+		},
+		&corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Name: "empty", Namespace: "other"},
+		},
+	)
+
+	tests := []struct {
+		name          string
+		algorithm     string
+		keySecret     string
+		expectedError bool
+	}{
+		{name: "SHA-256", algorithm: crypto.SHA256Algorithm},
+		{name: "HMAC-SHA256 key in the namespace of Reloader", algorithm: crypto.HMACSHA256Algorithm, keySecret: "hash-key"},
+		{name: "HMAC-SHA256 key in another namespace", algorithm: crypto.HMACSHA256Algorithm, keySecret: "reloader/hash-key"},
+		{name: "HMAC-SHA256 missing key secret", algorithm: crypto.HMACSHA256Algorithm, keySecret: "missing", expectedError: true},
+		{name: "HMAC-SHA256 key secret without key", algorithm: crypto.HMACSHA256Algorithm, keySecret: "other/empty", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options.HashAlgorithm, options.HashKeySecret = tt.algorithm, tt.keySecret
+			err := configureHashAlgorithm(client)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(crypto.GenerateSHA("data"), tt.algorithm+":"))
+		})
+	}
+}
+
+func TestServiceAccountLister(t *testing.T) {
+	client := testclient.NewClientset(
+		&corev1.ServiceAccount{ObjectMeta: v1.ObjectMeta{Name: "app", Namespace: "team-a"}},
+		&corev1.ServiceAccount{ObjectMeta: v1.ObjectMeta{Name: "app", Namespace: "team-b"}},
+	)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	lister := serviceAccountLister(client, []string{"team-a"}, stop)
+
+	assert.Eventually(t, func() bool {
+		_, found := lister("team-a", "app")
+		return found
+	}, 5*time.Second, 10*time.Millisecond)
+	_, found := lister("team-a", "missing")
+	assert.False(t, found)
+	_, found = lister("team-b", "app")
+	assert.False(t, found, "ServiceAccounts of namespaces not watched should not be cached")
+}
+
+func TestPodLister(t *testing.T) {
+	owner := v1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "owner", UID: "owner-uid", Controller: &[]bool{true}[0]}
+	client := testclient.NewClientset(
+		&corev1.Pod{ObjectMeta: v1.ObjectMeta{Name: "unmanaged", Namespace: "team-a", Annotations: map[string]string{options.ReloadUnmanagedAnnotation: "true"}}},
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "owned", Namespace: "team-a", Labels: map[string]string{"app": "owned"}, OwnerReferences: []v1.OwnerReference{owner}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+		},
+		&corev1.Pod{ObjectMeta: v1.ObjectMeta{Name: "unmanaged", Namespace: "team-b"}},
+	)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	lister := podLister(client, []string{"team-a"}, stop)
+
+	var pods []*corev1.Pod
+	assert.Eventually(t, func() bool {
+		var found bool
+		pods, found = lister("team-a")
+		return found && len(pods) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	for _, pod := range pods {
+		switch pod.Name {
+		case "unmanaged":
+			assert.Equal(t, "true", pod.Annotations[options.ReloadUnmanagedAnnotation])
+		case "owned":
+			assert.Equal(t, []v1.OwnerReference{owner}, pod.OwnerReferences)
+			assert.Empty(t, pod.Labels, "pods of a controller should be trimmed")
+			assert.Empty(t, pod.Spec.Containers, "pods of a controller should be trimmed")
+		}
+	}
+	_, found := lister("team-b")
+	assert.False(t, found, "pods of namespaces not watched should not be cached")
+}
+
+func TestValidateOptionsGitOpsValuesKey(t *testing.T) {
+	defer func(gitOpsSync bool, valuesKey string) {
+		options.GitOpsSync, options.GitOpsValuesKey = gitOpsSync, valuesKey
+	}(options.GitOpsSync, options.GitOpsValuesKey)
+
+	options.GitOpsSync, options.GitOpsValuesKey = true, "reloader"
+	assert.NoError(t, validateOptions())
+
+	options.GitOpsValuesKey = ""
+	assert.Error(t, validateOptions(), "GitOps sync should be refused without values key")
+
+	options.GitOpsSync = false
+	assert.NoError(t, validateOptions())
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/options"
+)
+
+// WatchPeriod is how often the config file is read to detect changes
+const WatchPeriod = 10 * time.Second
+
+// option binds a key of the config file to the variable of the option and the flag setting it
+type option struct {
+	// key is the name of the option in ReloaderOptions
+	key string
+	// flag is the name of the flag setting the option, empty if there is none
+	flag string
+	// value points to the variable of the option in the options package
+	value any
+	// restart is set for options only read at startup, such as the resources to ignore
+	restart bool
+}
+
+// reloaderOptions are the options settable by the config file, one for every field of ReloaderOptions
+var reloaderOptions = []option{
+	{key: "autoReloadAll", flag: "auto-reload-all", value: &options.AutoReloadAll},
+	{key: "configmapUpdateOnChangeAnnotation", flag: "configmap-annotation", value: &options.ConfigmapUpdateOnChangeAnnotation},
+	{key: "secretUpdateOnChangeAnnotation", flag: "secret-annotation", value: &options.SecretUpdateOnChangeAnnotation},
+	{key: "secretProviderClassUpdateOnChangeAnnotation", value: &options.SecretProviderClassUpdateOnChangeAnnotation},
+	{key: "externalSecretUpdateOnChangeAnnotation", value: &options.ExternalSecretUpdateOnChangeAnnotation},
+	{key: "certificateUpdateOnChangeAnnotation", value: &options.CertificateUpdateOnChangeAnnotation},
+	{key: "reloaderAutoAnnotation", flag: "auto-annotation", value: &options.ReloaderAutoAnnotation},
+	{key: "ignoreResourceAnnotation", flag: "ignore-annotation", value: &options.IgnoreResourceAnnotation},
+	{key: "configmapReloaderAutoAnnotation", flag: "configmap-auto-annotation", value: &options.ConfigmapReloaderAutoAnnotation},
+	{key: "secretReloaderAutoAnnotation", flag: "secret-auto-annotation", value: &options.SecretReloaderAutoAnnotation},
+	{key: "secretProviderClassReloaderAutoAnnotation", value: &options.SecretProviderClassReloaderAutoAnnotation},
+	{key: "configmapExcludeReloaderAnnotation", value: &options.ConfigmapExcludeReloaderAnnotation},
+	{key: "secretExcludeReloaderAnnotation", value: &options.SecretExcludeReloaderAnnotation},
+	{key: "secretProviderClassExcludeReloaderAnnotation", value: &options.SecretProviderClassExcludeReloaderAnnotation},
+	{key: "autoSearchAnnotation", flag: "auto-search-annotation", value: &options.AutoSearchAnnotation},
+	{key: "searchMatchAnnotation", flag: "search-match-annotation", value: &options.SearchMatchAnnotation},
+	{key: "contentTypeAnnotation", value: &options.ContentTypeAnnotation},
+	{key: "rolloutStrategyAnnotation", value: &options.RolloutStrategyAnnotation},
+	{key: "rolloutInProgressAnnotation", value: &options.RolloutInProgressAnnotation},
+	{key: "reloadStrategyAnnotation", value: &options.ReloadStrategyAnnotation},
+	{key: "signalCommandAnnotation", value: &options.SignalCommandAnnotation},
+	{key: "httpReloadAnnotation", value: &options.HTTPReloadAnnotation},
+	{key: "httpReloadDelayAnnotation", value: &options.HTTPReloadDelayAnnotation},
+	{key: "reloadUnmanagedAnnotation", value: &options.ReloadUnmanagedAnnotation},
+	{key: "jobReloadPolicyAnnotation", value: &options.JobReloadPolicyAnnotation},
+	{key: "supersededJobTTLAnnotation", value: &options.SupersededJobTTLAnnotation},
+	{key: "cronJobReloadPolicyAnnotation", value: &options.CronJobReloadPolicyAnnotation},
+	{key: "gitOpsSyncAnnotation", value: &options.GitOpsSyncAnnotation},
+	{key: "watchAnnotation", value: &options.WatchAnnotation},
+	{key: "allowWatchFromAnnotation", value: &options.AllowWatchFromAnnotation},
+	{key: "generationOfLabel", value: &options.GenerationOfLabel},
+	{key: "pauseDeploymentAnnotation", flag: "pause-deployment-annotation", value: &options.PauseDeploymentAnnotation},
+	{key: "pauseDeploymentTimeAnnotation", flag: "pause-deployment-time-annotation", value: &options.PauseDeploymentTimeAnnotation},
+	{key: "logFormat", flag: "log-format", value: &options.LogFormat},
+	{key: "logLevel", flag: "log-level", value: &options.LogLevel},
+	{key: "isArgoRollouts", flag: "is-Argo-Rollouts", value: &options.IsArgoRollouts},
+	{key: "reloadStrategy", flag: constants.ReloadStrategyFlag, value: &options.ReloadStrategy},
+	{key: "signalTimeout", flag: "signal-timeout", value: &options.SignalTimeout},
+	{key: "deletePodsTimeout", flag: "delete-pods-timeout", value: &options.DeletePodsTimeout},
+	{key: "supersededJobTTL", flag: "superseded-job-ttl", value: &options.SupersededJobTTL},
+	{key: "reloadOnCreate", flag: "reload-on-create", value: &options.ReloadOnCreate},
+	{key: "reloadOnDelete", flag: "reload-on-delete", value: &options.ReloadOnDelete},
+	{key: "syncAfterRestart", flag: "sync-after-restart", value: &options.SyncAfterRestart, restart: true},
+	{key: "enableHA", flag: "enable-ha", value: &options.EnableHA, restart: true},
+	{key: "enableNamespaceAnnotations", flag: "enable-namespace-annotations", value: &options.EnableNamespaceAnnotations, restart: true},
+	{key: "watchImagePullSecrets", flag: "watch-image-pull-secrets", value: &options.WatchImagePullSecrets},
+	{key: "hashAlgorithm", flag: "hash-algorithm", value: &options.HashAlgorithm, restart: true},
+	{key: "hashKeySecret", flag: "hash-key-secret", value: &options.HashKeySecret, restart: true},
+	{key: "enableCSIIntegration", flag: "enable-csi-integration", value: &options.EnableCSIIntegration, restart: true},
+	{key: "enableExternalSecretsIntegration", flag: "enable-external-secrets-integration", value: &options.EnableExternalSecretsIntegration, restart: true},
+	{key: "enableCertManagerIntegration", flag: "enable-cert-manager-integration", value: &options.EnableCertManagerIntegration, restart: true},
+	{key: "enableReloaderPolicies", flag: "enable-reloader-policies", value: &options.EnableReloaderPolicies, restart: true},
+	{key: "enablePolicyDebug", flag: "enable-policy-debug", value: &options.EnablePolicyDebug, restart: true},
+	{key: "reloadUnmanagedWorkloads", flag: "reload-unmanaged-workloads", value: &options.ReloadUnmanagedWorkloads},
+	{key: "gitOpsSync", flag: "gitops-sync", value: &options.GitOpsSync},
+	{key: "gitOpsValuesKey", flag: "gitops-values-key", value: &options.GitOpsValuesKey},
+	{key: "argoCDNamespace", flag: "argocd-namespace", value: &options.ArgoCDNamespace},
+	{key: "followGenerations", flag: "follow-generations", value: &options.FollowGenerations},
+	{key: "pruneGenerations", flag: "prune-generations", value: &options.PruneGenerations},
+	{key: "webhookUrl", flag: "webhook-url", value: &options.WebhookUrl},
+	{key: "resourcesToIgnore", flag: "resources-to-ignore", value: &options.ResourcesToIgnore, restart: true},
+	{key: "workloadTypesToIgnore", flag: "ignored-workload-types", value: &options.WorkloadTypesToIgnore},
+	{key: "namespaceSelectors", flag: "namespace-selector", value: &options.NamespaceSelectors},
+	{key: "resourceSelectors", flag: "resource-label-selector", value: &options.ResourceSelectors},
+	{key: "namespacesToIgnore", flag: "namespaces-to-ignore", value: &options.NamespacesToIgnore},
+	{key: "alertOnReload", flag: "alert-on-reload", value: &options.AlertOnReload},
+	{key: "alertSink", flag: "alert-sink", value: &options.AlertSink},
+	{key: "alertWebhookUrl", flag: "alert-webhook-url", value: &options.AlertWebhookURL},
+	{key: "alertWebhookProxy", flag: "alert-webhook-proxy", value: &options.AlertWebhookProxy},
+	{key: "alertAdditionalInfo", flag: "alert-additional-info", value: &options.AlertAdditionalInfo},
+	{key: "enablePProf", flag: "enable-pprof", value: &options.EnablePProf, restart: true},
+	{key: "pprofAddr", flag: "pprof-addr", value: &options.PProfAddr, restart: true},
+}
+
+// File is a YAML config file of Reloader whose keys are the names of the ReloaderOptions. The values of the file
+// override the defaults of the options but not the options set by flags.
+type File struct {
+	path  string
+	flags *pflag.FlagSet
+	mutex sync.Mutex
+	// content is the content of the file last applied
+	content []byte
+	// defaults are the values of the options before the file was applied
+	defaults map[string]any
+}
+
+// Load reads the config file at path and applies it to the options not set by flags
+func Load(path string, flags *pflag.FlagSet) (*File, error) {
+	f := &File{path: path, flags: flags, defaults: snapshot()}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	values, err := parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	for _, option := range reloaderOptions {
+		raw, found := values[option.key]
+		if !found || f.isSetByFlag(option) {
+			continue
+		}
+		if err := setValue(option.value, raw); err != nil {
+			return nil, fmt.Errorf("invalid value of %s in config file %s: %w", option.key, path, err)
+		}
+	}
+	f.content = content
+	return f, nil
+}
+
+// Reload applies the config file again if its content changed and returns whether any option changed. Options only
+// read at startup keep their values until Reloader is restarted. If validate rejects the new options, the previous
+// options are restored. The options are changed while holding their lock, validate is called with it held.
+func (f *File) Reload(validate func() error) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file %s: %w", f.path, err)
+	}
+	if bytes.Equal(content, f.content) {
+		return false, nil
+	}
+	// Remember the content even if it is invalid to only report it once
+	f.content = content
+	values, err := parse(content)
+	if err != nil {
+		return false, fmt.Errorf("invalid config file %s: %w", f.path, err)
+	}
+
+	options.Lock()
+	defer options.Unlock()
+	previous := snapshot()
+	var changed bool
+	var restartKeys []string
+	for _, option := range reloaderOptions {
+		if f.isSetByFlag(option) {
+			continue
+		}
+		next := reflect.New(reflect.TypeOf(option.value).Elem())
+		next.Elem().Set(reflect.ValueOf(copyValue(f.defaults[option.key])))
+		if raw, found := values[option.key]; found {
+			if err := setValue(next.Interface(), raw); err != nil {
+				restore(previous)
+				return false, fmt.Errorf("invalid value of %s in config file %s: %w", option.key, f.path, err)
+			}
+		}
+		current := reflect.ValueOf(option.value).Elem()
+		if reflect.DeepEqual(current.Interface(), next.Elem().Interface()) {
+			continue
+		}
+		if option.restart {
+			restartKeys = append(restartKeys, option.key)
+			continue
+		}
+		current.Set(next.Elem())
+		changed = true
+	}
+	if len(restartKeys) > 0 {
+		logrus.Warnf("Changes of %s in config file %s only apply after restarting Reloader", strings.Join(restartKeys, ", "), f.path)
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := validate(); err != nil {
+		restore(previous)
+		return false, fmt.Errorf("invalid options in config file %s: %w", f.path, err)
+	}
+	return true, nil
+}
+
+// Watch reloads the config file every period until stopCh is closed and calls onChange once options changed. It
+// polls the content of the file as the files of mounted ConfigMaps are replaced by swapping symlinks.
+func (f *File) Watch(period time.Duration, stopCh <-chan struct{}, validate func() error, onChange func()) {
+	wait.Until(func() {
+		changed, err := f.Reload(validate)
+		if err != nil {
+			logrus.Errorf("Failed to reload config file: %v", err)
+			return
+		}
+		if changed {
+			logrus.Infof("Applied changed options of config file %s", f.path)
+			onChange()
+		}
+	}, period, stopCh)
+}
+
+func (f *File) isSetByFlag(option option) bool {
+	return option.flag != "" && f.flags != nil && f.flags.Changed(option.flag)
+}
+
+// parse returns the values of the config file by key, failing on keys which aren't options
+func parse(content []byte) (map[string]json.RawMessage, error) {
+	data, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	var unknown []string
+	for key := range values {
+		if !slices.ContainsFunc(reloaderOptions, func(option option) bool { return option.key == key }) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown options %s", strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// setValue sets the option value points to from its JSON value
+func setValue(value any, raw json.RawMessage) error {
+	switch value := value.(type) {
+	case *bool:
+		return json.Unmarshal(raw, value)
+	case *string:
+		// Options like reloadOnCreate are strings set to "true" or "false"
+		var enabled bool
+		if err := json.Unmarshal(raw, &enabled); err == nil {
+			*value = strconv.FormatBool(enabled)
+			return nil
+		}
+		return json.Unmarshal(raw, value)
+	case *[]string:
+		var list []string
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return err
+		}
+		*value = list
+		return nil
+	case *time.Duration:
+		var duration string
+		if err := json.Unmarshal(raw, &duration); err != nil {
+			return err
+		}
+		parsed, err := time.ParseDuration(duration)
+		if err != nil {
+			return err
+		}
+		*value = parsed
+		return nil
+	}
+	return fmt.Errorf("unsupported option type %T", value)
+}
+
+// snapshot returns the current values of the options by key
+func snapshot() map[string]any {
+	values := make(map[string]any, len(reloaderOptions))
+	for _, option := range reloaderOptions {
+		values[option.key] = copyValue(reflect.ValueOf(option.value).Elem().Interface())
+	}
+	return values
+}
+
+// restore sets the options to the values of a snapshot
+func restore(values map[string]any) {
+	for _, option := range reloaderOptions {
+		reflect.ValueOf(option.value).Elem().Set(reflect.ValueOf(copyValue(values[option.key])))
+	}
+}
+
+func copyValue(value any) any {
+	if list, ok := value.([]string); ok {
+		return slices.Clone(list)
+	}
+	return value
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
+)
+
+// restoreOptions restores the options changed by a test once it finished
+func restoreOptions(t *testing.T) {
+	t.Helper()
+	values := snapshot()
+	t.Cleanup(func() { restore(values) })
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error writing config file: %v", err)
+	}
+}
+
+func newTestFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	flags := pflag.NewFlagSet("reloader", pflag.ContinueOnError)
+	flags.StringVar(&options.LogLevel, "log-level", options.LogLevel, "")
+	flags.StringVar(&options.ReloadOnCreate, "reload-on-create", options.ReloadOnCreate, "")
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("Unexpected error parsing flags: %v", err)
+	}
+	return flags
+}
+
+func TestLoad(t *testing.T) {
+	restoreOptions(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `
+logLevel: debug
+reloadOnCreate: true
+autoReloadAll: true
+namespacesToIgnore: [kube-system, kube-public]
+signalTimeout: 45s
+`)
+
+	if _, err := Load(path, newTestFlags(t, "--log-level=warning")); err != nil {
+		t.Fatalf("Unexpected error loading config file: %v", err)
+	}
+	if options.LogLevel != "warning" {
+		t.Errorf("Expected the log level set by flag to win, got %q", options.LogLevel)
+	}
+	if options.ReloadOnCreate != "true" || !options.AutoReloadAll {
+		t.Errorf("Expected reloadOnCreate and autoReloadAll of the config file, got %q and %v", options.ReloadOnCreate, options.AutoReloadAll)
+	}
+	if !slices.Equal(options.NamespacesToIgnore, []string{"kube-system", "kube-public"}) {
+		t.Errorf("Expected namespacesToIgnore of the config file, got %v", options.NamespacesToIgnore)
+	}
+	if options.SignalTimeout != 45*time.Second {
+		t.Errorf("Expected signalTimeout of 45s, got %v", options.SignalTimeout)
+	}
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "Unknown option", content: "autoReloadEverything: true"},
+		{name: "Invalid type", content: "autoReloadAll: [true]"},
+		{name: "Invalid duration", content: "signalTimeout: soon"},
+		{name: "Invalid YAML", content: "logLevel: [debug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreOptions(t)
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfigFile(t, path, tt.content)
+			if _, err := Load(path, nil); err == nil {
+				t.Errorf("Expected an error loading %q", tt.content)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	restoreOptions(t)
+	options.AutoReloadAll = false
+	options.EnableHA = false
+	options.ReloadStrategy = "env-vars"
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "autoReloadAll: true\nreloadStrategy: annotations\n")
+	file, err := Load(path, newTestFlags(t))
+	if err != nil {
+		t.Fatalf("Unexpected error loading config file: %v", err)
+	}
+	validate := func() error { return nil }
+
+	if changed, err := file.Reload(validate); changed || err != nil {
+		t.Errorf("Expected no changes of an unchanged file, got %v, %v", changed, err)
+	}
+
+	// Removed options are reset to their defaults, options only read at startup are kept
+	writeConfigFile(t, path, "reloadStrategy: restarted-at\nenableHA: true\n")
+	if changed, err := file.Reload(validate); !changed || err != nil {
+		t.Fatalf("Expected changed options, got %v, %v", changed, err)
+	}
+	if options.AutoReloadAll || options.ReloadStrategy != "restarted-at" {
+		t.Errorf("Expected autoReloadAll false and strategy restarted-at, got %v and %q", options.AutoReloadAll, options.ReloadStrategy)
+	}
+	if options.EnableHA {
+		t.Errorf("Expected enableHA to only change after a restart")
+	}
+
+	// Rejected options are restored
+	writeConfigFile(t, path, "reloadStrategy: invalid\nautoReloadAll: true\n")
+	if _, err := file.Reload(func() error { return errors.New("invalid") }); err == nil {
+		t.Fatalf("Expected the error of the validation")
+	}
+	if options.AutoReloadAll || options.ReloadStrategy != "restarted-at" {
+		t.Errorf("Expected the previous options to be restored, got %v and %q", options.AutoReloadAll, options.ReloadStrategy)
+	}
+}
+
+// TestReload_ConcurrentReaders changes options while readers holding the read lock use them, run with -race to detect
+// unguarded changes
+func TestReload_ConcurrentReaders(t *testing.T) {
+	restoreOptions(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "autoReloadAll: false\n")
+	file, err := Load(path, newTestFlags(t))
+	if err != nil {
+		t.Fatalf("Unexpected error loading config file: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				options.RLock()
+				_ = options.AutoReloadAll
+				_ = slices.Clone(options.NamespacesToIgnore)
+				options.RUnlock()
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		writeConfigFile(t, path, "autoReloadAll: "+strconv.FormatBool(i%2 == 0)+"\nnamespacesToIgnore: [ns-"+strconv.Itoa(i)+"]\n")
+		if _, err := file.Reload(func() error { return nil }); err != nil {
+			t.Errorf("Unexpected error reloading config file: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	options.RLock()
+	defer options.RUnlock()
+	if options.AutoReloadAll || !slices.Equal(options.NamespacesToIgnore, []string{"ns-19"}) {
+		t.Errorf("Expected the options of the last config file, got %v and %v", options.AutoReloadAll, options.NamespacesToIgnore)
+	}
+}
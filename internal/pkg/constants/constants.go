@@ -10,6 +10,12 @@ const (
 	SecretEnvVarPostfix = "SECRET"
 	// SecretProviderClassEnvVarPostfix is a postfix for secretproviderclasspodstatus envVar
 	SecretProviderClassEnvVarPostfix = "SECRETPROVIDERCLASS"
+	// ExternalSecretEnvVarPostfix is a postfix for externalsecret envVar
+	ExternalSecretEnvVarPostfix = "EXTERNALSECRET"
+	// CertificateEnvVarPostfix is a postfix for certificate envVar
+	CertificateEnvVarPostfix = "CERTIFICATE"
+	// HashKeySecretKey is the entry of the hash key secret containing the key of the hmac-sha256 hash algorithm
+	HashKeySecretKey = "key"
 	// EnvVarPrefix is a Prefix for environment variable
 	EnvVarPrefix = "STAKATER_"
 
@@ -17,6 +23,8 @@ const (
 	ReloaderAnnotationPrefix = "reloader.stakater.com"
 	// LastReloadedFromAnnotation is an annotation used to describe the last resource that triggered a reload
 	LastReloadedFromAnnotation = "last-reloaded-from"
+	// PausedUpdateStrategyAnnotation is an annotation used to remember the update strategy of a workload paused by reloader
+	PausedUpdateStrategyAnnotation = "paused-update-strategy"
 
 	// 	ReloadStrategyFlag The reload strategy flag name
 	ReloadStrategyFlag = "reload-strategy"
@@ -24,10 +32,64 @@ const (
 	EnvVarsReloadStrategy = "env-vars"
 	// AnnotationsReloadStrategy instructs Reloader to add pod template annotations to facilitate a restart
 	AnnotationsReloadStrategy = "annotations"
+	// RestartedAtReloadStrategy instructs Reloader to set the pod template restartedAt annotation like `kubectl rollout restart`
+	RestartedAtReloadStrategy = "restarted-at"
+	// RestartedAtAnnotation is the pod template annotation set by `kubectl rollout restart`
+	RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// RestartedForAnnotation is a workload annotation used to remember the hashes of the resources a workload was
+	// restarted for by the restarted-at strategy
+	RestartedForAnnotation = "restarted-for"
+	// SignalReloadStrategy instructs Reloader to run a command in the pods, e.g. to send a SIGHUP, once mounted files are updated
+	SignalReloadStrategy = "signal"
+	// DefaultSignalCommand is the command run in the pods by the signal reload strategy
+	DefaultSignalCommand = "kill -HUP 1"
+	// HTTPReloadStrategy instructs Reloader to call a reload endpoint of the pods once mounted files are updated
+	HTTPReloadStrategy = "http"
+	// DeletePodsReloadStrategy instructs Reloader to delete the pods of StatefulSets and DaemonSets using the OnDelete update strategy one by one
+	DeletePodsReloadStrategy = "delete-pods"
+	// RecreateUnmanagedPod is the value of the reload-unmanaged annotation to recreate pods after evicting them
+	RecreateUnmanagedPod = "recreate"
+	// RecreatedFromAnnotation is an annotation used to remember the name of the first pod a recreated pod replaces
+	RecreatedFromAnnotation = "recreated-from"
+	// JobRerunOfAnnotation is an annotation used to remember the name of the Job a Job was rerun from
+	JobRerunOfAnnotation = "rerun-of"
+	// JobSupersededByAnnotation is an annotation used to mark a Job superseded by the Job rerunning it
+	JobSupersededByAnnotation = "superseded-by"
+	// RecreateJobReloadPolicy instructs Reloader to delete a Job and recreate it under the same name
+	RecreateJobReloadPolicy = "recreate"
+	// CreateNewJobReloadPolicy instructs Reloader to rerun a Job as a new Job with a generated suffix, keeping the old one
+	CreateNewJobReloadPolicy = "create-new"
+	// RerunFinishedJobReloadPolicy instructs Reloader to rerun a Job as a new Job only once it completed or failed
+	RerunFinishedJobReloadPolicy = "rerun-finished"
+	// SkipRunningJobReloadPolicy instructs Reloader to recreate a Job under the same name unless it is still running
+	SkipRunningJobReloadPolicy = "skip-running"
+	// TriggerCronJobReloadPolicy instructs Reloader to create a Job from a CronJob without updating its job template
+	TriggerCronJobReloadPolicy = "trigger"
+	// UpdateTemplateCronJobReloadPolicy instructs Reloader to update the job template of a CronJob for its next scheduled run
+	UpdateTemplateCronJobReloadPolicy = "update-template"
+	// UpdateAndTriggerCronJobReloadPolicy instructs Reloader to update the job template of a CronJob and create a Job from it
+	UpdateAndTriggerCronJobReloadPolicy = "update-and-trigger"
+	// SkipRolloutInProgressPolicy instructs Reloader to skip reloading Argo Rollouts while an update is in progress
+	SkipRolloutInProgressPolicy = "skip"
+	// QueueRolloutInProgressPolicy instructs Reloader to reload Argo Rollouts once the update in progress is promoted or aborted
+	QueueRolloutInProgressPolicy = "queue"
+	// RestartAfterPromotionRolloutInProgressPolicy instructs Reloader to restart Argo Rollouts once the update in progress is promoted or aborted
+	RestartAfterPromotionRolloutInProgressPolicy = "restart-after-promotion"
 	// SecretProviderClassController enables support for SecretProviderClassPodStatus resources
 	SecretProviderClassController = "secretproviderclasspodstatuses"
+	// ExternalSecretController enables support for External Secrets Operator ExternalSecret resources
+	ExternalSecretController = "externalsecrets"
+	// CertificateController enables support for cert-manager Certificate resources
+	CertificateController = "certificates"
+	// ReloaderPolicyController enables support for ReloaderPolicy resources
+	ReloaderPolicyController = "reloaderpolicies"
+	// ClusterReloaderPolicyController enables support for ClusterReloaderPolicy resources
+	ClusterReloaderPolicyController = "clusterreloaderpolicies"
 )
 
+// FlagEnvVarPrefix is the prefix of the environment variables setting the flags of Reloader
+const FlagEnvVarPrefix = "RELOADER_"
+
 // Leadership election related consts
 const (
 	LockName        string = "stakater-reloader-lock"
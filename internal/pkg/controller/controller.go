@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -9,9 +10,15 @@ import (
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -25,6 +32,8 @@ import (
 	"github.com/stakater/Reloader/internal/pkg/metrics"
 	"github.com/stakater/Reloader/internal/pkg/options"
 	"github.com/stakater/Reloader/internal/pkg/util"
+	"github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
+	"github.com/stakater/Reloader/pkg/common"
 	"github.com/stakater/Reloader/pkg/kube"
 )
 
@@ -33,13 +42,20 @@ type Controller struct {
 	client            kubernetes.Interface
 	queue             workqueue.TypedRateLimitingInterface[any]
 	informer          cache.Controller
+	store             cache.Store
+	listWatcher       cache.ListerWatcher
 	namespace         string
 	resource          string
 	ignoredNamespaces util.List
+	// mutex guards the ignored namespaces, the selectors and the informer replaced on changes of the config file
+	mutex             sync.RWMutex
 	collectors        metrics.Collectors
 	recorder          record.EventRecorder
 	namespaceSelector string
 	resourceSelector  string
+	// restartInformer is closed to replace the running informer by a new one listing with the changed resource
+	// selector, nil until the controller runs
+	restartInformer chan struct{}
 }
 
 // controllerInitialized flags guard against processing Add/Delete events before
@@ -49,12 +65,16 @@ var secretControllerInitialized atomic.Bool
 var configmapControllerInitialized atomic.Bool
 
 // selectedNamespacesCache holds an immutable snapshot of the set of namespace
-// names that match the namespace label selector. Written exclusively by the
-// namespace controller's informer goroutine; read concurrently by configmap/
-// secret controller informer goroutines. Using atomic.Value with an immutable
-// map[string]struct{} snapshot avoids mutexes and prevents data races.
+// names that match the namespace label selector. Written by the namespace
+// controller's informer goroutine and once the namespace selector changed;
+// read concurrently by configmap/secret controller informer goroutines. Using
+// atomic.Value with an immutable map[string]struct{} snapshot avoids mutexes
+// for readers and prevents data races.
 var selectedNamespacesCache atomic.Value // always stores map[string]struct{}
 
+// selectedNamespacesMutex serializes the writers of selectedNamespacesCache
+var selectedNamespacesMutex sync.Mutex
+
 // loadSelectedNamespaces returns the current namespace snapshot (never nil).
 func loadSelectedNamespaces() map[string]struct{} {
 	if v := selectedNamespacesCache.Load(); v != nil {
@@ -111,53 +131,195 @@ func NewController(client kubernetes.Interface, resource string, namespace strin
 
 	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[any]())
 
-	optionsModifier := func(opts *metav1.ListOptions) {
-		if resource == "namespaces" {
-			opts.LabelSelector = c.namespaceSelector
-		} else if len(c.resourceSelector) > 0 {
-			opts.LabelSelector = c.resourceSelector
-		} else {
-			opts.FieldSelector = fields.Everything().String()
+	var listWatcher cache.ListerWatcher
+	if dynamicResource, ok := dynamicResources[resource]; ok {
+		dynamicClient, err := kube.GetDynamicClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize dynamic client for %s: %w", resource, err)
+		}
+		listWatcher = getDynamicListWatcher(dynamicClient, dynamicResource, namespace, c.modifyListOptions)
+	} else {
+		getterRESTClient, err := getClientForResource(resource, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize REST client for %s: %w", resource, err)
 		}
+		listWatcher = cache.NewFilteredListWatchFromClient(getterRESTClient, resource, namespace, c.modifyListOptions)
 	}
 
-	getterRESTClient, err := getClientForResource(resource, client)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize REST client for %s: %w", resource, err)
-	}
+	c.listWatcher = listWatcher
+	c.store, c.informer = c.newInformer(false)
+	c.queue = queue
+	c.collectors = collectors
+	c.recorder = recorder
 
-	listWatcher := cache.NewFilteredListWatchFromClient(getterRESTClient, resource, namespace, optionsModifier)
+	logrus.Infof("created controller for: %s", resource)
+	return &c, nil
+}
 
-	_, informer := cache.NewInformerWithOptions(cache.InformerOptions{
-		ListerWatcher: listWatcher,
-		ObjectType:    kube.ResourceMap[resource],
-		ResyncPeriod:  0,
-		Handler: cache.ResourceEventHandlerFuncs{
-			AddFunc:    c.Add,
+// newInformer creates the informer of the controller. The objects listed initially by an informer replacing one
+// stopped on a changed resource selector are not handled as created, as they existed before.
+func (c *Controller) newInformer(restarted bool) (cache.Store, cache.Controller) {
+	return cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: c.listWatcher,
+		ObjectType:    kube.ResourceMap[c.resource],
+		ResyncPeriod:  c.resyncPeriod(),
+		Handler: cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if restarted && isInInitialList {
+					return
+				}
+				c.Add(obj)
+			},
 			UpdateFunc: c.Update,
 			DeleteFunc: c.Delete,
 		},
 		Indexers: cache.Indexers{},
 	})
-	c.informer = informer
-	c.queue = queue
-	c.collectors = collectors
-	c.recorder = recorder
+}
 
-	logrus.Infof("created controller for: %s", resource)
-	return &c, nil
+// resyncPeriod returns how often the informer passes all resources to the handlers again. Policies are resynced to
+// list the workloads they select for their status again.
+func (c *Controller) resyncPeriod() time.Duration {
+	if c.resource == constants.ReloaderPolicyController || c.resource == constants.ClusterReloaderPolicyController {
+		return handler.PolicyStatusRefreshPeriod
+	}
+	return 0
+}
+
+// modifyListOptions sets the resource selector on the options listing and watching resources. Namespaces are all
+// listed and matched by the namespace selector in the handlers, so that a changed selector applies without listing
+// them again.
+func (c *Controller) modifyListOptions(opts *metav1.ListOptions) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if len(c.resourceSelector) > 0 && c.usesResourceSelector() {
+		opts.LabelSelector = c.resourceSelector
+	} else {
+		opts.FieldSelector = fields.Everything().String()
+	}
+}
+
+// usesResourceSelector checks whether the controller lists its resources with the resource label selector
+func (c *Controller) usesResourceSelector() bool {
+	return c.resource != "namespaces" && c.resource != constants.ReloaderPolicyController && c.resource != constants.ClusterReloaderPolicyController
+}
+
+// Namespace returns the namespace watched by the controller, empty when watching all namespaces
+func (c *Controller) Namespace() string {
+	return c.namespace
+}
+
+// Resource returns the kind of resources watched by the controller
+func (c *Controller) Resource() string {
+	return c.resource
+}
+
+// HasSynced checks whether the informer of the controller listed all resources and passed them to the handlers
+func (c *Controller) HasSynced() bool {
+	c.mutex.RLock()
+	informer := c.informer
+	c.mutex.RUnlock()
+	return informer.HasSynced()
+}
+
+// PauseTimersSynced returns the HasSynced funcs of the controllers whose resources have to be known before restoring
+// the pause timers of workloads: the policies defining pause periods and the namespaces matching the namespace selector
+func PauseTimersSynced(controllers []*Controller) []cache.InformerSynced {
+	var synced []cache.InformerSynced
+	for _, c := range controllers {
+		if c.resource == constants.ReloaderPolicyController || c.resource == constants.ClusterReloaderPolicyController || c.resource == "namespaces" {
+			synced = append(synced, c.HasSynced)
+		}
+	}
+	return synced
+}
+
+// SecretLister returns a func reading secrets from the caches of the secrets controllers. Secrets are only found once
+// the informer of the controller watching their namespace synced, and only if they match the resource selector.
+func SecretLister(controllers []*Controller) func(namespace, name string) (*v1.Secret, bool) {
+	var secretControllers []*Controller
+	for _, c := range controllers {
+		if c.resource == string(v1.ResourceSecrets) {
+			secretControllers = append(secretControllers, c)
+		}
+	}
+	return func(namespace, name string) (*v1.Secret, bool) {
+		for _, c := range secretControllers {
+			if c.namespace != "" && c.namespace != namespace {
+				continue
+			}
+			if secret, found := c.getCachedSecret(namespace, name); found {
+				return secret, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// getCachedSecret returns a secret from the store of the informer, once it synced
+func (c *Controller) getCachedSecret(namespace, name string) (*v1.Secret, bool) {
+	c.mutex.RLock()
+	store, informer := c.store, c.informer
+	c.mutex.RUnlock()
+	if !informer.HasSynced() {
+		return nil, false
+	}
+	obj, found, err := store.GetByKey(namespace + "/" + name)
+	if err != nil || !found {
+		return nil, false
+	}
+	secret, ok := obj.(*v1.Secret)
+	return secret, ok
+}
+
+// InSelectedNamespaces checks whether a namespace matches the namespace selector, as cached from the namespaces
+// controller
+func InSelectedNamespaces(namespace string) bool {
+	_, selected := loadSelectedNamespaces()[namespace]
+	return selected
 }
 
 // Add function to add a new object to the queue in case of creating a resource
 func (c *Controller) Add(obj interface{}) {
+	options.RLock()
+	defer options.RUnlock()
 	c.collectors.RecordEventReceived("add", c.resource)
 
 	switch object := obj.(type) {
 	case *v1.Namespace:
-		c.addSelectedNamespaceToCache(*object)
+		c.selectNamespace(*object)
+		if options.EnableNamespaceAnnotations {
+			common.SetNamespaceAnnotations(object.Name, object.Annotations)
+		}
+		if options.EnableReloaderPolicies {
+			common.SetNamespaceLabels(object.Name, object.Labels)
+		}
 		return
 	case *csiv1.SecretProviderClassPodStatus:
 		return
+	case *v1alpha1.ReloaderPolicy:
+		c.enqueueReloaderPolicy(object)
+		return
+	case *v1alpha1.ClusterReloaderPolicy:
+		c.enqueueClusterReloaderPolicy(object)
+		return
+	case *unstructured.Unstructured:
+		// Remember the data of custom resources to only reload once a later update changed it
+		if !c.resourceInIgnoredNamespace(obj) && c.resourceInSelectedNamespaces(obj) {
+			c.enqueueCustomResource(object, nil)
+		}
+		return
+	}
+
+	if options.FollowGenerations && handler.IsResourceGeneration(obj) {
+		if !c.resourceInIgnoredNamespace(obj) && c.resourceInSelectedNamespaces(obj) && secretControllerInitialized.Load() && configmapControllerInitialized.Load() {
+			c.enqueue(handler.ResourceGenerationHandler{
+				Resource:    obj,
+				Collectors:  c.collectors,
+				Recorder:    c.recorder,
+				EnqueueTime: time.Now(),
+			})
+		}
 	}
 
 	if options.ReloadOnCreate == "true" {
@@ -174,7 +336,60 @@ func (c *Controller) Add(obj interface{}) {
 	}
 }
 
+// SetIgnoredNamespaces replaces the namespaces whose resources the controller ignores
+func (c *Controller) SetIgnoredNamespaces(namespaces []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ignoredNamespaces = namespaces
+}
+
+// SetNamespaceSelector replaces the label selector of the namespaces whose resources the controller handles. The
+// namespace controller selects the namespaces it lists again.
+func (c *Controller) SetNamespaceSelector(selector string) {
+	c.mutex.Lock()
+	changed := c.namespaceSelector != selector
+	c.namespaceSelector = selector
+	c.mutex.Unlock()
+
+	if !changed || c.resource != "namespaces" {
+		return
+	}
+	logrus.Infof("namespace-selector changed to '%s', selecting namespaces again", selector)
+	selectedNamespacesMutex.Lock()
+	defer selectedNamespacesMutex.Unlock()
+	var names []string
+	for _, obj := range c.store.List() {
+		if namespace, ok := obj.(*v1.Namespace); ok && c.isSelectedNamespace(namespace) {
+			names = append(names, namespace.Name)
+		}
+	}
+	storeSelectedNamespaces(names)
+}
+
+// SetResourceSelector replaces the label selector of the resources the controller lists. A running informer is
+// replaced by one listing the resources with the new selector.
+func (c *Controller) SetResourceSelector(selector string) {
+	if !c.usesResourceSelector() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.resourceSelector == selector {
+		return
+	}
+	c.resourceSelector = selector
+	if c.restartInformer == nil {
+		return
+	}
+	logrus.Infof("resource-label-selector changed to '%s', restarting the informer of %s", selector, c.resource)
+	c.store, c.informer = c.newInformer(true)
+	close(c.restartInformer)
+	c.restartInformer = nil
+}
+
 func (c *Controller) resourceInIgnoredNamespace(raw interface{}) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	switch obj := raw.(type) {
 	case *v1.ConfigMap:
 		return c.ignoredNamespaces.Contains(obj.Namespace)
@@ -182,12 +397,19 @@ func (c *Controller) resourceInIgnoredNamespace(raw interface{}) bool {
 		return c.ignoredNamespaces.Contains(obj.Namespace)
 	case *csiv1.SecretProviderClassPodStatus:
 		return c.ignoredNamespaces.Contains(obj.Namespace)
+	case *unstructured.Unstructured:
+		return c.ignoredNamespaces.Contains(obj.GetNamespace())
+	case *v1alpha1.ReloaderPolicy:
+		return c.ignoredNamespaces.Contains(obj.Namespace)
 	}
 	return false
 }
 
 func (c *Controller) resourceInSelectedNamespaces(raw interface{}) bool {
-	if len(c.namespaceSelector) == 0 {
+	c.mutex.RLock()
+	namespaceSelector := c.namespaceSelector
+	c.mutex.RUnlock()
+	if len(namespaceSelector) == 0 {
 		return true
 	}
 
@@ -200,6 +422,10 @@ func (c *Controller) resourceInSelectedNamespaces(raw interface{}) bool {
 		ns = object.GetNamespace()
 	case *csiv1.SecretProviderClassPodStatus:
 		ns = object.GetNamespace()
+	case *unstructured.Unstructured:
+		ns = object.GetNamespace()
+	case *v1alpha1.ReloaderPolicy:
+		ns = object.GetNamespace()
 	default:
 		return false
 	}
@@ -207,8 +433,39 @@ func (c *Controller) resourceInSelectedNamespaces(raw interface{}) bool {
 	return ok
 }
 
+// isSelectedNamespace checks whether a namespace matches the namespace selector, which is not the case without one
+func (c *Controller) isSelectedNamespace(namespace *v1.Namespace) bool {
+	c.mutex.RLock()
+	namespaceSelector := c.namespaceSelector
+	c.mutex.RUnlock()
+	if len(namespaceSelector) == 0 {
+		return false
+	}
+	selector, err := labels.Parse(namespaceSelector)
+	if err != nil {
+		logrus.Errorf("Invalid namespace selector '%s': %v", namespaceSelector, err)
+		return false
+	}
+	return selector.Matches(labels.Set(namespace.Labels))
+}
+
+// selectNamespace adds a namespace matching the namespace selector to the cache and removes it otherwise, e.g. once
+// its labels changed
+func (c *Controller) selectNamespace(namespace v1.Namespace) {
+	if c.isSelectedNamespace(&namespace) {
+		c.addSelectedNamespaceToCache(namespace)
+	} else {
+		c.removeSelectedNamespaceFromCache(namespace)
+	}
+}
+
 func (c *Controller) addSelectedNamespaceToCache(namespace v1.Namespace) {
+	selectedNamespacesMutex.Lock()
+	defer selectedNamespacesMutex.Unlock()
 	old := loadSelectedNamespaces()
+	if _, ok := old[namespace.GetName()]; ok {
+		return
+	}
 	next := make(map[string]struct{}, len(old)+1)
 	for k := range old {
 		next[k] = struct{}{}
@@ -219,6 +476,8 @@ func (c *Controller) addSelectedNamespaceToCache(namespace v1.Namespace) {
 }
 
 func (c *Controller) removeSelectedNamespaceFromCache(namespace v1.Namespace) {
+	selectedNamespacesMutex.Lock()
+	defer selectedNamespacesMutex.Unlock()
 	old := loadSelectedNamespaces()
 	if _, ok := old[namespace.GetName()]; !ok {
 		return
@@ -234,10 +493,32 @@ func (c *Controller) removeSelectedNamespaceFromCache(namespace v1.Namespace) {
 
 // Update function to add an old object and a new object to the queue in case of updating a resource
 func (c *Controller) Update(old interface{}, new interface{}) {
+	options.RLock()
+	defer options.RUnlock()
 	c.collectors.RecordEventReceived("update", c.resource)
 
-	switch new.(type) {
+	switch object := new.(type) {
 	case *v1.Namespace:
+		c.selectNamespace(*object)
+		if options.EnableNamespaceAnnotations {
+			common.SetNamespaceAnnotations(object.Name, object.Annotations)
+		}
+		if options.EnableReloaderPolicies {
+			common.SetNamespaceLabels(object.Name, object.Labels)
+		}
+		return
+	case *v1alpha1.ReloaderPolicy:
+		c.enqueueReloaderPolicy(object)
+		return
+	case *v1alpha1.ClusterReloaderPolicy:
+		c.enqueueClusterReloaderPolicy(object)
+		return
+	case *unstructured.Unstructured:
+		if oldObject, ok := old.(*unstructured.Unstructured); ok && !c.resourceInIgnoredNamespace(new) && c.resourceInSelectedNamespaces(new) {
+			c.enqueueCustomResource(object, oldObject)
+		} else {
+			c.collectors.RecordSkipped("ignored_or_not_selected")
+		}
 		return
 	}
 
@@ -256,9 +537,28 @@ func (c *Controller) Update(old interface{}, new interface{}) {
 
 // Delete function to add an object to the queue in case of deleting a resource
 func (c *Controller) Delete(old interface{}) {
+	options.RLock()
+	defer options.RUnlock()
 	c.collectors.RecordEventReceived("delete", c.resource)
 
-	if _, ok := old.(*csiv1.SecretProviderClassPodStatus); ok {
+	switch object := old.(type) {
+	case *csiv1.SecretProviderClassPodStatus:
+		return
+	case *v1alpha1.ReloaderPolicy:
+		common.DeleteReloaderPolicy(object.Namespace, object.Name)
+		handler.ForgetReloaderPolicyStatus(object.Namespace, object.Name)
+		return
+	case *v1alpha1.ClusterReloaderPolicy:
+		common.DeleteClusterReloaderPolicy(object.Name)
+		handler.ForgetClusterReloaderPolicyStatus(object.Name)
+		return
+	case *unstructured.Unstructured:
+		switch c.resource {
+		case constants.ExternalSecretController:
+			handler.ForgetExternalSecret(object)
+		case constants.CertificateController:
+			handler.ForgetCertificate(object, c.collectors)
+		}
 		return
 	}
 
@@ -278,10 +578,61 @@ func (c *Controller) Delete(old interface{}) {
 	switch object := old.(type) {
 	case *v1.Namespace:
 		c.removeSelectedNamespaceFromCache(*object)
+		common.DeleteNamespaceAnnotations(object.Name)
+		common.DeleteNamespaceLabels(object.Name)
 		return
 	}
 }
 
+// enqueueCustomResource adds the handler of a custom resource watched with the dynamic client to the queue, old is nil
+// for added resources
+func (c *Controller) enqueueCustomResource(object *unstructured.Unstructured, old *unstructured.Unstructured) {
+	switch c.resource {
+	case constants.ExternalSecretController:
+		c.enqueue(handler.ExternalSecretHandler{
+			Resource:    object,
+			OldResource: old,
+			Collectors:  c.collectors,
+			Recorder:    c.recorder,
+			EnqueueTime: time.Now(),
+		})
+	case constants.CertificateController:
+		c.enqueue(handler.CertificateHandler{
+			Resource:    object,
+			OldResource: old,
+			Collectors:  c.collectors,
+			Recorder:    c.recorder,
+			EnqueueTime: time.Now(),
+		})
+	}
+}
+
+// enqueueReloaderPolicy adds the handler of an added or updated ReloaderPolicy to the queue, unless it is in an ignored
+// or not selected namespace
+func (c *Controller) enqueueReloaderPolicy(policy *v1alpha1.ReloaderPolicy) {
+	if c.resourceInIgnoredNamespace(policy) || !c.resourceInSelectedNamespaces(policy) {
+		c.collectors.RecordSkipped("ignored_or_not_selected")
+		return
+	}
+	// Remember the policy right away so that it applies once the informer synced, the handler reports invalid policies
+	_ = common.SetReloaderPolicy(policy)
+	c.enqueue(handler.ReloaderPolicyHandler{
+		Resource:    policy,
+		Collectors:  c.collectors,
+		EnqueueTime: time.Now(),
+	})
+}
+
+// enqueueClusterReloaderPolicy remembers an added or updated ClusterReloaderPolicy and adds its handler to the queue
+func (c *Controller) enqueueClusterReloaderPolicy(policy *v1alpha1.ClusterReloaderPolicy) {
+	_ = common.SetClusterReloaderPolicy(policy)
+	c.enqueue(handler.ClusterReloaderPolicyHandler{
+		Resource:    policy,
+		Collectors:  c.collectors,
+		EnqueueTime: time.Now(),
+	})
+}
+
 // enqueue adds an item to the queue and records metrics
 func (c *Controller) enqueue(item interface{}) {
 	c.queue.Add(item)
@@ -295,20 +646,24 @@ func (c *Controller) Run(threadiness int, stopCh chan struct{}) {
 
 	var wg sync.WaitGroup
 
+	c.mutex.Lock()
+	informer := c.informer
+	c.mutex.Unlock()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		c.informer.Run(stopCh)
+		c.runInformer(stopCh)
 	}()
 
 	// Wait for all involved caches to be synced, before processing items from the queue is started
-	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
 		runtime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
 		c.queue.ShutDown()
 		wg.Wait()
 		return
 	}
 
+	handler.SetDebounceQueue(c.queue)
 	for i := 0; i < threadiness; i++ {
 		wg.Add(1)
 		go func() {
@@ -325,6 +680,35 @@ func (c *Controller) Run(threadiness int, stopCh chan struct{}) {
 	logrus.Infof("All goroutines exited for %s", c.resource)
 }
 
+// runInformer runs the informer until stopCh is closed. An informer stopped on a changed resource selector is
+// replaced by the new one.
+func (c *Controller) runInformer(stopCh chan struct{}) {
+	for {
+		c.mutex.Lock()
+		informer := c.informer
+		restart := make(chan struct{})
+		c.restartInformer = restart
+		c.mutex.Unlock()
+
+		informerStop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			informer.Run(informerStop)
+		}()
+
+		select {
+		case <-stopCh:
+			close(informerStop)
+			<-done
+			return
+		case <-restart:
+			close(informerStop)
+			<-done
+		}
+	}
+}
+
 func (c *Controller) runWorker() {
 	// At this point the controller is fully initialized and we can start processing the resources
 	if c.resource == string(v1.ResourceSecrets) {
@@ -369,7 +753,9 @@ func (c *Controller) processNextItem() bool {
 		c.collectors.RecordError("invalid_handler_type")
 		return true
 	}
+	options.RLock()
 	err := rh.Handle()
+	options.RUnlock()
 
 	duration := time.Since(startTime)
 
@@ -423,6 +809,28 @@ func (c *Controller) handleErr(err error, key interface{}) {
 	c.collectors.RecordEventProcessed("unknown", c.resource, "dropped")
 }
 
+// dynamicResources are the custom resources without typed clients, watched with the dynamic client
+var dynamicResources = map[string]schema.GroupVersionResource{
+	constants.ExternalSecretController: kube.ExternalSecretResource,
+	constants.CertificateController:    kube.CertificateResource,
+}
+
+// getDynamicListWatcher lists and watches custom resources without typed clients as unstructured objects
+func getDynamicListWatcher(client dynamic.Interface, resource schema.GroupVersionResource, namespace string, optionsModifier func(options *metav1.ListOptions)) *cache.ListWatch {
+	resourceClient := client.Resource(resource).Namespace(namespace)
+	return &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (k8sruntime.Object, error) {
+			optionsModifier(&options)
+			return resourceClient.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.Watch = true
+			optionsModifier(&options)
+			return resourceClient.Watch(ctx, options)
+		},
+	}
+}
+
 func getClientForResource(resource string, coreClient kubernetes.Interface) (cache.Getter, error) {
 	if resource == constants.SecretProviderClassController {
 		csiClient, err := kube.GetCSIClient()
@@ -431,5 +839,12 @@ func getClientForResource(resource string, coreClient kubernetes.Interface) (cac
 		}
 		return csiClient.SecretsstoreV1().RESTClient(), nil
 	}
+	if resource == constants.ReloaderPolicyController || resource == constants.ClusterReloaderPolicyController {
+		reloaderClient, err := kube.GetReloaderClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Reloader client: %w", err)
+		}
+		return reloaderClient.ReloaderV1alpha1().RESTClient(), nil
+	}
 	return coreClient.CoreV1().RESTClient(), nil
 }
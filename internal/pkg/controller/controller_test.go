@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -8,11 +9,17 @@ import (
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
+	"github.com/stakater/Reloader/internal/pkg/constants"
 	"github.com/stakater/Reloader/internal/pkg/handler"
 	"github.com/stakater/Reloader/internal/pkg/metrics"
 	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
 	"github.com/stakater/Reloader/pkg/common"
 )
 
@@ -668,15 +675,109 @@ func TestAddHandlerWithNamespaceEvent(t *testing.T) {
 
 	c := newTestController([]string{}, "env=prod")
 
-	// When a namespace is added, it should be cached
+	// When a namespace matching the selector is added, it should be cached
 	ns := &v1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{Name: "new-namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: "new-namespace", Labels: map[string]string{"env": "prod"}},
 	}
-
 	c.Add(ns)
+	c.Add(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev-namespace", Labels: map[string]string{"env": "dev"}}})
 
-	assert.Contains(t, loadSelectedNamespaces(), "new-namespace")
+	assert.ElementsMatch(t, []string{"new-namespace"}, loadSelectedNamespacesList())
 	assert.Equal(t, 0, c.queue.Len(), "Namespace add should not queue anything")
+
+	// A namespace no longer matching the selector is removed
+	updated := ns.DeepCopy()
+	updated.Labels["env"] = "dev"
+	c.Update(ns, updated)
+	assert.Empty(t, loadSelectedNamespaces())
+}
+
+func TestSetNamespaceSelector(t *testing.T) {
+	resetGlobalState()
+
+	c := newTestController([]string{}, "env=prod")
+	c.resource = "namespaces"
+	c.store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for name, env := range map[string]string{"team-a": "prod", "team-b": "dev", "team-c": "dev"} {
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"env": env}}}
+		assert.NoError(t, c.store.Add(ns))
+		c.Add(ns)
+	}
+	assert.ElementsMatch(t, []string{"team-a"}, loadSelectedNamespacesList())
+
+	c.SetNamespaceSelector("env=dev")
+	assert.ElementsMatch(t, []string{"team-b", "team-c"}, loadSelectedNamespacesList())
+
+	configMaps := newTestController([]string{}, "env=prod")
+	configMaps.SetNamespaceSelector("env=dev")
+	assert.True(t, configMaps.resourceInSelectedNamespaces(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-b"}}))
+	assert.False(t, configMaps.resourceInSelectedNamespaces(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}}))
+}
+
+func TestSetResourceSelector(t *testing.T) {
+	resetGlobalState()
+	defer func(reloadOnCreate string) { options.ReloadOnCreate = reloadOnCreate }(options.ReloadOnCreate)
+	options.ReloadOnCreate = "true"
+
+	c := newTestController([]string{}, "")
+	c.resourceSelector = "team=a"
+	listed := make(chan string, 10)
+	// The fake client doesn't support watch lists, which makes the informer list the resources
+	c.listWatcher = cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{
+		ListWithContextFunc: func(_ context.Context, opts metav1.ListOptions) (k8sruntime.Object, error) {
+			c.modifyListOptions(&opts)
+			listed <- opts.LabelSelector
+			return &v1.ConfigMapList{Items: []v1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}}}, nil
+		},
+		WatchFuncWithContext: func(_ context.Context, _ metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}, testclient.NewClientset())
+	c.store, c.informer = c.newInformer(false)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.Run(1, stop)
+	assert.Equal(t, "team=a", <-listed)
+	assert.Eventually(t, func() bool {
+		c.mutex.RLock()
+		defer c.mutex.RUnlock()
+		return c.restartInformer != nil && c.informer.HasSynced()
+	}, 5*time.Second, 10*time.Millisecond)
+	secretControllerInitialized.Store(true)
+	configmapControllerInitialized.Store(true)
+
+	c.SetResourceSelector("team=a")
+	c.SetResourceSelector("team=b")
+	assert.Equal(t, "team=b", <-listed, "Informer should list the resources with the changed selector")
+	assert.Empty(t, listed, "Informer should only be restarted once")
+
+	// The resources listed by the restarted informer existed before and are not handled as created
+	assert.Never(t, func() bool { return c.queue.Len() > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestNamespaceEventsWithNamespaceAnnotations(t *testing.T) {
+	resetGlobalState()
+	options.EnableNamespaceAnnotations = true
+	defer func() { options.EnableNamespaceAnnotations = false }()
+
+	c := newTestController([]string{}, "")
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: map[string]string{options.ReloaderAutoAnnotation: "true"}},
+	}
+
+	c.Add(ns)
+	assert.Equal(t, "true", common.GetNamespaceAnnotations("team-a")[options.ReloaderAutoAnnotation])
+	assert.Empty(t, loadSelectedNamespaces(), "Namespaces should only be selected with a namespace selector")
+
+	updated := ns.DeepCopy()
+	updated.Annotations[options.ReloaderAutoAnnotation] = "false"
+	c.Update(ns, updated)
+	assert.Equal(t, "false", common.GetNamespaceAnnotations("team-a")[options.ReloaderAutoAnnotation])
+
+	c.Delete(updated)
+	assert.Empty(t, common.GetNamespaceAnnotations("team-a"))
+	assert.Equal(t, 0, c.queue.Len(), "Namespace events should not queue anything")
 }
 
 func TestDeleteHandlerWithNamespaceEvent(t *testing.T) {
@@ -753,3 +854,52 @@ func TestProcessNextItemQueueShutdown(t *testing.T) {
 	result := c.processNextItem()
 	assert.False(t, result, "Should return false when queue is shutdown")
 }
+
+func TestAddReloaderPolicy(t *testing.T) {
+	resetGlobalState()
+	c := newTestController([]string{}, "")
+	c.resource = constants.ReloaderPolicyController
+	policy := &v1alpha1.ReloaderPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pause", Namespace: "default"},
+		Spec:       v1alpha1.ReloaderPolicySpec{PausePeriod: &metav1.Duration{Duration: 5 * time.Minute}},
+	}
+	defer common.DeleteReloaderPolicy(policy.Namespace, policy.Name)
+
+	// The policy applies as soon as the informer handled it, before its handler updated the status
+	c.Add(policy)
+	assert.Equal(t, 1, c.queue.Len())
+	assert.Equal(t, &metav1.Duration{Duration: 5 * time.Minute}, common.GetPolicyRules("default", "Deployment", nil).PausePeriod)
+}
+
+func TestSecretLister(t *testing.T) {
+	c := newTestController([]string{}, "")
+	c.resource = string(v1.ResourceSecrets)
+	c.namespace = "team-a"
+	c.listWatcher = cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{
+		ListWithContextFunc: func(_ context.Context, _ metav1.ListOptions) (k8sruntime.Object, error) {
+			return &v1.SecretList{Items: []v1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"}}}}, nil
+		},
+		WatchFuncWithContext: func(_ context.Context, _ metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}, testclient.NewClientset())
+	c.store, c.informer = c.newInformer(false)
+	configmaps := newTestController([]string{}, "")
+	lister := SecretLister([]*Controller{configmaps, c})
+
+	_, found := lister("team-a", "db")
+	assert.False(t, found, "Secrets should not be read before the informer synced")
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.informer.Run(stop)
+	assert.Eventually(t, c.HasSynced, 5*time.Second, 10*time.Millisecond)
+
+	secret, found := lister("team-a", "db")
+	assert.True(t, found)
+	assert.Equal(t, "db", secret.Name)
+	_, found = lister("team-a", "cache")
+	assert.False(t, found)
+	_, found = lister("team-b", "db")
+	assert.False(t, found, "Secrets of namespaces not watched should not be found")
+}
@@ -1,16 +1,74 @@
 package crypto
 
 import (
+	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 
 	"github.com/sirupsen/logrus"
 )
 
-// GenerateSHA generates SHA from string
+const (
+	// SHA1Algorithm hashes data with SHA-1, hashes aren't prefixed to stay compatible with earlier versions
+	SHA1Algorithm = "sha1"
+	// SHA256Algorithm hashes data with SHA-256
+	SHA256Algorithm = "sha256"
+	// HMACSHA256Algorithm hashes data with HMAC-SHA256, keyed with a secret key so hashes of low-entropy secrets can't
+	// be brute-forced by anyone able to read the workloads
+	HMACSHA256Algorithm = "hmac-sha256"
+)
+
+// HashAlgorithms are the supported hash algorithms
+var HashAlgorithms = []string{SHA1Algorithm, SHA256Algorithm, HMACSHA256Algorithm}
+
+var (
+	hashAlgorithm = SHA1Algorithm
+	hashKey       []byte
+)
+
+// SetHashAlgorithm configures the algorithm used by GenerateSHA, key is required by HMAC-SHA256 only
+func SetHashAlgorithm(algorithm string, key []byte) error {
+	switch algorithm {
+	case SHA1Algorithm, SHA256Algorithm:
+		hashKey = nil
+	case HMACSHA256Algorithm:
+		if len(key) == 0 {
+			return errors.New("hash algorithm 'hmac-sha256' requires a key")
+		}
+		hashKey = key
+	default:
+		return fmt.Errorf("invalid hash algorithm '%s'", algorithm)
+	}
+	hashAlgorithm = algorithm
+	return nil
+}
+
+// GenerateSHA generates SHA from string with the configured algorithm. Hashes of algorithms other than SHA-1 are
+// prefixed with the algorithm, e.g. "sha256:"
 func GenerateSHA(data string) string {
-	hasher := sha1.New()
+	switch hashAlgorithm {
+	case SHA256Algorithm:
+		return SHA256Algorithm + ":" + generateHash(sha256.New(), data)
+	case HMACSHA256Algorithm:
+		return HMACSHA256Algorithm + ":" + generateHash(hmac.New(sha256.New, hashKey), data)
+	}
+	return generateHash(sha1.New(), data)
+}
+
+// GenerateLegacySHA generates the SHA-1 written by earlier versions when another algorithm is configured, empty
+// otherwise. It allows to recognize unchanged resources of workloads reloaded before the algorithm was changed.
+func GenerateLegacySHA(data string) string {
+	if hashAlgorithm == SHA1Algorithm {
+		return ""
+	}
+	return generateHash(sha1.New(), data)
+}
+
+func generateHash(hasher hash.Hash, data string) string {
 	_, err := io.WriteString(hasher, data)
 	if err != nil {
 		logrus.Errorf("Unable to write data in hash writer %v", err)
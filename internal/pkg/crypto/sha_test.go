@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -26,3 +27,50 @@ func TestGenerateSHAEmptyString(t *testing.T) {
 		t.Errorf("SHA hash should be 40 characters long, got %d", len(result))
 	}
 }
+
+// TestGenerateSHAWithHashAlgorithm verifies the prefixed hashes of the configurable algorithms and the legacy SHA-1
+func TestGenerateSHAWithHashAlgorithm(t *testing.T) {
+	defer func() {
+		_ = SetHashAlgorithm(SHA1Algorithm, nil)
+	}()
+	data := "www.stakater.com"
+
+	tests := []struct {
+		algorithm      string
+		key            []byte
+		expectedPrefix string
+		expectedLegacy string
+	}{
+		{algorithm: SHA1Algorithm, expectedPrefix: "abd4ed82fb04548388a6cf3c339fd9dc84d275df"},
+		{algorithm: SHA256Algorithm, expectedPrefix: "sha256:", expectedLegacy: "abd4ed82fb04548388a6cf3c339fd9dc84d275df"},
+		{algorithm: HMACSHA256Algorithm, key: []byte("key"), expectedPrefix: "hmac-sha256:", expectedLegacy: "abd4ed82fb04548388a6cf3c339fd9dc84d275df"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			if err := SetHashAlgorithm(tt.algorithm, tt.key); err != nil {
+				t.Fatalf("Failed to set hash algorithm: %v", err)
+			}
+			if result := GenerateSHA(data); !strings.HasPrefix(result, tt.expectedPrefix) {
+				t.Errorf("Expected SHA with prefix %s, got %s", tt.expectedPrefix, result)
+			}
+			if result := GenerateLegacySHA(data); result != tt.expectedLegacy {
+				t.Errorf("Expected legacy SHA %s, got %s", tt.expectedLegacy, result)
+			}
+		})
+	}
+
+	_ = SetHashAlgorithm(HMACSHA256Algorithm, []byte("key"))
+	keyed := GenerateSHA(data)
+	_ = SetHashAlgorithm(HMACSHA256Algorithm, []byte("other-key"))
+	if GenerateSHA(data) == keyed {
+		t.Errorf("HMAC-SHA256 should depend on the key")
+	}
+
+	if err := SetHashAlgorithm(HMACSHA256Algorithm, nil); err == nil {
+		t.Errorf("HMAC-SHA256 without key should fail")
+	}
+	if err := SetHashAlgorithm("md5", nil); err == nil {
+		t.Errorf("Invalid hash algorithm should fail")
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+var (
+	// rolloutPromotionPollInterval is the interval to check whether the update of a rollout was promoted or aborted
+	rolloutPromotionPollInterval = 10 * time.Second
+	// rolloutPromotionTimeout is how long queued reloads wait for the update of a rollout to be promoted or aborted
+	rolloutPromotionTimeout = 6 * time.Hour
+	// queuedRolloutReloads tracks the reloads of rollouts waiting for their update in progress to be promoted or aborted
+	queuedRolloutReloads = &rolloutReloadQueue{pending: make(map[string]map[string]common.Config)}
+)
+
+// rolloutReloadQueue keeps the reloads of a rollout until its update in progress is promoted or aborted, so that a
+// single goroutine waits for each rollout
+type rolloutReloadQueue struct {
+	mutex sync.Mutex
+	// pending holds the latest config of each resource changed while waiting, by rollout
+	pending map[string]map[string]common.Config
+	// ctx is cancelled to stop waiting for all rollouts
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// context returns the context of waiting for rollouts, which is cancelled when the waits are stopped
+func (q *rolloutReloadQueue) context() context.Context {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.ctx == nil {
+		q.ctx, q.cancel = context.WithCancel(context.Background())
+	}
+	return q.ctx
+}
+
+// stop cancels waiting for all rollouts, their queued reloads are dropped
+func (q *rolloutReloadQueue) stop() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.cancel != nil {
+		q.cancel()
+		q.ctx, q.cancel = nil, nil
+	}
+}
+
+// add queues the reload of a rollout and returns whether waiting for the rollout must be started
+func (q *rolloutReloadQueue) add(key string, config common.Config) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	configs, waiting := q.pending[key]
+	if !waiting {
+		configs = make(map[string]common.Config)
+		q.pending[key] = configs
+	}
+	configs[config.Type+"/"+config.ResourceName] = config
+	return !waiting
+}
+
+// take removes and returns the reloads queued for a rollout
+func (q *rolloutReloadQueue) take(key string) []common.Config {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	configs := make([]common.Config, 0, len(q.pending[key]))
+	for _, resourceKey := range sortedKeys(q.pending[key]) {
+		configs = append(configs, q.pending[key][resourceKey])
+	}
+	delete(q.pending, key)
+	return configs
+}
+
+// isRolloutUpdateInProgress checks whether a rollout is updating to a new revision that is not promoted yet, e.g. in
+// the middle of its canary steps. Aborted updates are not in progress, the rollout went back to its stable revision.
+func isRolloutUpdateInProgress(rollout *argorolloutv1alpha1.Rollout) bool {
+	return rollout.Status.StableRS != "" && rollout.Status.CurrentPodHash != rollout.Status.StableRS && !rollout.Status.Abort
+}
+
+// getRolloutInProgressPolicy returns how a rollout is reloaded while an update is in progress, or an empty string if
+// it is reloaded right away
+func getRolloutInProgressPolicy(rollout *argorolloutv1alpha1.Rollout) (string, error) {
+	policy, found := rollout.Annotations[options.RolloutInProgressAnnotation]
+	if !found {
+		return "", nil
+	}
+	switch policy {
+	case constants.SkipRolloutInProgressPolicy, constants.QueueRolloutInProgressPolicy, constants.RestartAfterPromotionRolloutInProgressPolicy:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid value '%s' for annotation '%s'", policy, options.RolloutInProgressAnnotation)
+}
+
+// deferRolloutReload skips or queues the reload of a rollout whose update is in progress, as defined by its
+// rollout-in-progress policy. It returns whether the reload was deferred.
+func deferRolloutReload(clients kube.Clients, config common.Config, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, strategy invokeStrategy, rollout *argorolloutv1alpha1.Rollout) (bool, error) {
+	if !isRolloutUpdateInProgress(rollout) {
+		return false, nil
+	}
+	policy, err := getRolloutInProgressPolicy(rollout)
+	if err != nil || policy == "" {
+		return false, err
+	}
+
+	if policy == constants.SkipRolloutInProgressPolicy {
+		logrus.Infof("Skipping reload of Rollout '%s' in namespace '%s', its update is in progress", rollout.Name, config.Namespace)
+		collectors.RecordSkipped("rollout_in_progress")
+		return true, nil
+	}
+
+	key := fmt.Sprintf("%s/%s", config.Namespace, rollout.Name)
+	logrus.Infof("Update of Rollout '%s' in namespace '%s' is in progress, reloading it once promoted or aborted", rollout.Name, config.Namespace)
+	collectors.RecordSkipped("rollout_queued")
+	if queuedRolloutReloads.add(key, config) {
+		go reloadRolloutAfterPromotion(queuedRolloutReloads.context(), clients, config.Namespace, rollout.Name, upgradeFuncs, collectors, recorder, strategy)
+	}
+	return true, nil
+}
+
+// StopRolloutReloads stops waiting for the updates in progress of rollouts, e.g. when leadership is lost. The queued
+// reloads are dropped.
+func StopRolloutReloads() {
+	queuedRolloutReloads.stop()
+}
+
+// reloadRolloutAfterPromotion waits until the update in progress of a rollout is promoted or aborted, then reloads it
+// with the queued changes, or restarts it with the restart-after-promotion policy. Waiting ends after
+// rolloutPromotionTimeout or once ctx is cancelled.
+func reloadRolloutAfterPromotion(ctx context.Context, clients kube.Clients, namespace, name string, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, strategy invokeStrategy) {
+	ctx, cancel := context.WithTimeout(ctx, rolloutPromotionTimeout)
+	defer cancel()
+
+	var latest *argorolloutv1alpha1.Rollout
+	err := wait.PollUntilContextCancel(ctx, rolloutPromotionPollInterval, false, func(ctx context.Context) (bool, error) {
+		current, err := clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return false, err
+		}
+		if err != nil {
+			logrus.Debugf("Failed to get Rollout '%s' in namespace '%s': %v", name, namespace, err)
+			return false, nil
+		}
+		latest = current
+		return !isRolloutUpdateInProgress(current), nil
+	})
+	options.RLock()
+	defer options.RUnlock()
+	configs := queuedRolloutReloads.take(fmt.Sprintf("%s/%s", namespace, name))
+	if err != nil {
+		logrus.Errorf("Dropping %d queued reloads of Rollout '%s' in namespace '%s': %v", len(configs), name, namespace, err)
+		return
+	}
+
+	policy, _ := getRolloutInProgressPolicy(latest)
+	if policy == constants.RestartAfterPromotionRolloutInProgressPolicy {
+		if err := callbacks.RestartRollout(clients, namespace, name); err != nil {
+			logrus.Errorf("Failed to restart Rollout '%s' in namespace '%s' after promotion: %v", name, namespace, err)
+			return
+		}
+		logrus.Infof("Restarted Rollout '%s' in namespace '%s' after promotion", name, namespace)
+		if recorder != nil {
+			recorder.Event(latest, v1.EventTypeNormal, "Reloaded", fmt.Sprintf("Restarted '%s' of type '%s' in namespace '%s' after promotion", name, upgradeFuncs.ResourceType, namespace))
+		}
+		return
+	}
+
+	for _, config := range configs {
+		_, err := retryOnConflict(retry.DefaultRetry, func(bool) (bool, error) {
+			return upgradeResource(clients, config, upgradeFuncs, collectors, recorder, strategy, latest, true)
+		})
+		if err != nil {
+			logrus.Errorf("Failed to reload Rollout '%s' in namespace '%s' after promotion: %v", name, namespace, err)
+		}
+	}
+}
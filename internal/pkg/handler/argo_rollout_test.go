@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	fakeargoclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func createCanaryRollout(policy string, status argorolloutv1alpha1.RolloutStatus) *argorolloutv1alpha1.Rollout {
+	annotations := map[string]string{options.ConfigmapUpdateOnChangeAnnotation: "my-configmap"}
+	if policy != "" {
+		annotations[options.RolloutInProgressAnnotation] = policy
+	}
+	return &argorolloutv1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: annotations},
+		Spec: argorolloutv1alpha1.RolloutSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "app",
+							EnvFrom: []v1.EnvFromSource{
+								{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}}},
+							},
+						},
+					},
+				},
+			},
+			Strategy: argorolloutv1alpha1.RolloutStrategy{Canary: &argorolloutv1alpha1.CanaryStrategy{}},
+		},
+		Status: status,
+	}
+}
+
+func TestIsRolloutUpdateInProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   argorolloutv1alpha1.RolloutStatus
+		expected bool
+	}{
+		{name: "Not rolled out yet", status: argorolloutv1alpha1.RolloutStatus{CurrentPodHash: "abc"}},
+		{name: "Promoted", status: argorolloutv1alpha1.RolloutStatus{CurrentPodHash: "abc", StableRS: "abc"}},
+		{name: "Mid-canary", status: argorolloutv1alpha1.RolloutStatus{CurrentPodHash: "def", StableRS: "abc"}, expected: true},
+		{name: "Aborted", status: argorolloutv1alpha1.RolloutStatus{CurrentPodHash: "def", StableRS: "abc", Abort: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isRolloutUpdateInProgress(createCanaryRollout("", tt.status)))
+		})
+	}
+}
+
+func TestRolloutReloadQueue(t *testing.T) {
+	queue := &rolloutReloadQueue{pending: make(map[string]map[string]common.Config)}
+
+	assert.True(t, queue.add("default/web", common.Config{ResourceName: "a", Type: constants.ConfigmapEnvVarPostfix, SHAValue: "1"}))
+	assert.False(t, queue.add("default/web", common.Config{ResourceName: "a", Type: constants.ConfigmapEnvVarPostfix, SHAValue: "2"}))
+	assert.False(t, queue.add("default/web", common.Config{ResourceName: "b", Type: constants.SecretEnvVarPostfix, SHAValue: "3"}))
+
+	configs := queue.take("default/web")
+	assert.Equal(t, []common.Config{
+		{ResourceName: "a", Type: constants.ConfigmapEnvVarPostfix, SHAValue: "2"},
+		{ResourceName: "b", Type: constants.SecretEnvVarPostfix, SHAValue: "3"},
+	}, configs, "Only the latest change of each resource should be reloaded")
+	assert.True(t, queue.add("default/web", common.Config{ResourceName: "a", Type: constants.ConfigmapEnvVarPostfix}))
+}
+
+func TestUpgradeResourceRolloutInProgress(t *testing.T) {
+	midCanary := argorolloutv1alpha1.RolloutStatus{CurrentPodHash: "def", StableRS: "abc"}
+	tests := []struct {
+		name            string
+		policy          string
+		status          argorolloutv1alpha1.RolloutStatus
+		expectedUpdated bool
+		// expectedAfterPromotion is the change applied once the update in progress is promoted: env, restart or none
+		expectedAfterPromotion string
+	}{
+		{name: "Reloaded right away by default", status: midCanary, expectedUpdated: true},
+		{name: "Not in progress", policy: constants.SkipRolloutInProgressPolicy, status: argorolloutv1alpha1.RolloutStatus{CurrentPodHash: "abc", StableRS: "abc"}, expectedUpdated: true},
+		{name: "Skipped", policy: constants.SkipRolloutInProgressPolicy, status: midCanary, expectedAfterPromotion: "none"},
+		{name: "Queued", policy: constants.QueueRolloutInProgressPolicy, status: midCanary, expectedAfterPromotion: "env"},
+		{name: "Restarted after promotion", policy: constants.RestartAfterPromotionRolloutInProgressPolicy, status: midCanary, expectedAfterPromotion: "restart"},
+	}
+
+	originalInterval := rolloutPromotionPollInterval
+	rolloutPromotionPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { rolloutPromotionPollInterval = originalInterval })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rollout := createCanaryRollout(tt.policy, tt.status)
+			argoClient := fakeargoclientset.NewSimpleClientset(rollout.DeepCopy())
+			clients := kube.Clients{ArgoRolloutClient: argoClient}
+			config := common.Config{
+				ResourceName: "my-configmap",
+				Type:         constants.ConfigmapEnvVarPostfix,
+				SHAValue:     "sha256:abc123",
+				Namespace:    "default",
+				Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+			}
+			getRollout := func() *argorolloutv1alpha1.Rollout {
+				result, err := argoClient.ArgoprojV1alpha1().Rollouts("default").Get(context.TODO(), "web", metav1.GetOptions{})
+				assert.NoError(t, err)
+				return result
+			}
+			expectedEnv := []v1.EnvVar{{Name: getEnvVarName("my-configmap", constants.ConfigmapEnvVarPostfix), Value: "sha256:abc123"}}
+
+			updated, err := upgradeResource(clients, config, GetArgoRolloutRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, rollout, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedUpdated, updated)
+			if tt.expectedUpdated {
+				assert.Equal(t, expectedEnv, getRollout().Spec.Template.Spec.Containers[0].Env)
+				return
+			}
+			assert.Empty(t, getRollout().Spec.Template.Spec.Containers[0].Env, "Rollout should not be reloaded while its update is in progress")
+
+			// Promote the update in progress
+			promoted := getRollout()
+			promoted.Status.StableRS = promoted.Status.CurrentPodHash
+			_, err = argoClient.ArgoprojV1alpha1().Rollouts("default").Update(context.TODO(), promoted, metav1.UpdateOptions{})
+			assert.NoError(t, err)
+
+			switch tt.expectedAfterPromotion {
+			case "env":
+				assert.Eventually(t, func() bool {
+					return assert.ObjectsAreEqual(expectedEnv, getRollout().Spec.Template.Spec.Containers[0].Env)
+				}, 5*time.Second, 10*time.Millisecond, "Queued reload should be applied after promotion")
+			case "restart":
+				assert.Eventually(t, func() bool {
+					return getRollout().Spec.RestartAt != nil
+				}, 5*time.Second, 10*time.Millisecond, "Rollout should be restarted after promotion")
+				assert.Empty(t, getRollout().Spec.Template.Spec.Containers[0].Env)
+			default:
+				assert.Never(t, func() bool {
+					result := getRollout()
+					return len(result.Spec.Template.Spec.Containers[0].Env) > 0 || result.Spec.RestartAt != nil
+				}, 100*time.Millisecond, 10*time.Millisecond, "Skipped reload should never be applied")
+			}
+		})
+	}
+}
+
+func TestReloadRolloutAfterPromotion_Dropped(t *testing.T) {
+	originalInterval, originalTimeout := rolloutPromotionPollInterval, rolloutPromotionTimeout
+	rolloutPromotionPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { rolloutPromotionPollInterval, rolloutPromotionTimeout = originalInterval, originalTimeout })
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		stop    bool
+	}{
+		{name: "Timed out", timeout: 50 * time.Millisecond},
+		{name: "Stopped", timeout: time.Hour, stop: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rolloutPromotionTimeout = tt.timeout
+			rollout := createCanaryRollout(constants.QueueRolloutInProgressPolicy, argorolloutv1alpha1.RolloutStatus{CurrentPodHash: "def", StableRS: "abc"})
+			argoClient := fakeargoclientset.NewSimpleClientset(rollout.DeepCopy())
+			clients := kube.Clients{ArgoRolloutClient: argoClient}
+			config := common.Config{
+				ResourceName: "my-configmap",
+				Type:         constants.ConfigmapEnvVarPostfix,
+				SHAValue:     "sha256:abc123",
+				Namespace:    "default",
+				Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+			}
+
+			updated, err := upgradeResource(clients, config, GetArgoRolloutRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, rollout, false)
+			assert.NoError(t, err)
+			assert.False(t, updated)
+			if tt.stop {
+				StopRolloutReloads()
+			}
+
+			// The queued reload is dropped instead of waiting for the promotion
+			assert.Eventually(t, func() bool {
+				queuedRolloutReloads.mutex.Lock()
+				defer queuedRolloutReloads.mutex.Unlock()
+				_, waiting := queuedRolloutReloads.pending["default/web"]
+				return !waiting
+			}, 5*time.Second, 10*time.Millisecond)
+
+			promoted, err := argoClient.ArgoprojV1alpha1().Rollouts("default").Get(context.TODO(), "web", metav1.GetOptions{})
+			assert.NoError(t, err)
+			promoted.Status.StableRS = promoted.Status.CurrentPodHash
+			_, err = argoClient.ArgoprojV1alpha1().Rollouts("default").Update(context.TODO(), promoted, metav1.UpdateOptions{})
+			assert.NoError(t, err)
+			assert.Never(t, func() bool {
+				result, err := argoClient.ArgoprojV1alpha1().Rollouts("default").Get(context.TODO(), "web", metav1.GetOptions{})
+				return err == nil && len(result.Spec.Template.Spec.Containers[0].Env) > 0
+			}, 100*time.Millisecond, 10*time.Millisecond, "Dropped reload should never be applied")
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/internal/pkg/util"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// certificateFingerprints holds the fingerprint of the certificate last issued for each cert-manager Certificate,
+// keyed by namespace/name. The TLS secret also changes on annotation-only updates, the fingerprint tells the renewals
+// that issued a different certificate apart.
+var certificateFingerprints sync.Map
+
+// CertificateHandler contains an added or updated cert-manager Certificate
+type CertificateHandler struct {
+	Resource *unstructured.Unstructured
+	// OldResource is nil when the Certificate was added
+	OldResource *unstructured.Unstructured
+	Collectors  metrics.Collectors
+	Recorder    record.EventRecorder
+	EnqueueTime time.Time // Time when this handler was added to the queue
+}
+
+// GetEnqueueTime returns when this handler was enqueued
+func (r CertificateHandler) GetEnqueueTime() time.Time {
+	return r.EnqueueTime
+}
+
+// Handle reloads the workloads of a Certificate once it is Ready with a different certificate in its secret
+func (r CertificateHandler) Handle() error {
+	startTime := time.Now()
+	result := "error"
+
+	defer func() {
+		r.Collectors.RecordReconcile(result, time.Since(startTime))
+	}()
+
+	if r.Resource == nil {
+		logrus.Errorf("Certificate handler received nil resource")
+		return nil
+	}
+
+	client, err := kube.GetKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	config, changed, err := syncCertificate(client, r.Collectors, r.Resource, r.OldResource)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		result = "skipped"
+		r.Collectors.RecordSkipped("no_data_change")
+		return nil
+	}
+
+	// Send a webhook if update
+	if options.WebhookUrl != "" {
+		err := sendUpgradeWebhook(config, options.WebhookUrl)
+		if err == nil {
+			result = "success"
+		}
+		return err
+	}
+	err = doRollingUpgrade(config, r.Collectors, r.Recorder, invokeReloadStrategy)
+	if err == nil {
+		result = "success"
+	}
+	return err
+}
+
+// GetConfig gets configurations containing SHA, annotations, namespace and resource name
+func (r CertificateHandler) GetConfig() (common.Config, string) {
+	var shaValue string
+	if fingerprint, ok := certificateFingerprints.Load(getResourceKey(r.Resource)); ok {
+		shaValue = fingerprint.(string)
+	}
+	return common.GetCertificateConfig(r.Resource, shaValue), shaValue
+}
+
+// ForgetCertificate drops the fingerprint and expiry metric of a deleted Certificate
+func ForgetCertificate(certificate *unstructured.Unstructured, collectors metrics.Collectors) {
+	certificateFingerprints.Delete(getResourceKey(certificate))
+	collectors.DeleteCertificateNotAfter(certificate.GetNamespace(), certificate.GetName())
+}
+
+// syncCertificate remembers the fingerprint of the certificate issued for a Certificate and returns whether a new
+// issuance changed it. The fingerprint of Certificates seen for the first time, i.e. added ones and those that only
+// became Ready now, is only remembered.
+func syncCertificate(client kubernetes.Interface, collectors metrics.Collectors, certificate, old *unstructured.Unstructured) (common.Config, bool, error) {
+	fingerprint, changed, err := syncReadyResource(&certificateFingerprints, certificate, old, getCertificateIssuance, func() (string, error) {
+		secretName, _, _ := unstructured.NestedString(certificate.Object, "spec", "secretName")
+		secret, err := getSecret(client, certificate.GetNamespace(), secretName)
+		if apierrors.IsNotFound(err) {
+			logrus.Warnf("Secret '%s' of Certificate '%s' not found in namespace '%s'", secretName, certificate.GetName(), certificate.GetNamespace())
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		fingerprint, notAfter, err := util.GetCertificateFingerprint(secret.Data[v1.TLSCertKey])
+		if err != nil {
+			return "", fmt.Errorf("failed to read certificate of Certificate '%s' in namespace '%s': %w", certificate.GetName(), certificate.GetNamespace(), err)
+		}
+		collectors.SetCertificateNotAfter(certificate.GetNamespace(), certificate.GetName(), notAfter)
+		return fingerprint, nil
+	})
+	if err != nil || fingerprint == "" {
+		return common.Config{}, false, err
+	}
+	return common.GetCertificateConfig(certificate, fingerprint), changed, nil
+}
+
+// getCertificateIssuance identifies the certificate issued for a Certificate, it changes on every issuance
+func getCertificateIssuance(certificate *unstructured.Unstructured) string {
+	revision, _, _ := unstructured.NestedInt64(certificate.Object, "status", "revision")
+	notAfter, _, _ := unstructured.NestedString(certificate.Object, "status", "notAfter")
+	return fmt.Sprintf("%d/%s", revision, notAfter)
+}
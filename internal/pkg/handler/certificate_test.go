@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+)
+
+func createTestTLSCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(notAfter.Unix()),
+		Subject:      pkix.Name{CommonName: "web.example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func createTestCertificate(revision int64, ready string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec":       map[string]interface{}{"secretName": "web-tls"},
+		"status": map[string]interface{}{
+			"revision": revision,
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": ready},
+			},
+		},
+	}}
+}
+
+func TestSyncCertificate(t *testing.T) {
+	certificateFingerprints.Clear()
+	defer certificateFingerprints.Clear()
+
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	fakeClient := testclient.NewClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "default"},
+		Type:       v1.SecretTypeTLS,
+		Data:       map[string][]byte{v1.TLSCertKey: createTestTLSCertificate(t, notAfter)},
+	})
+	updateSecret := func(update func(secret *v1.Secret)) {
+		secret, err := fakeClient.CoreV1().Secrets("default").Get(context.TODO(), "web-tls", metav1.GetOptions{})
+		assert.NoError(t, err)
+		update(secret)
+		_, err = fakeClient.CoreV1().Secrets("default").Update(context.TODO(), secret, metav1.UpdateOptions{})
+		assert.NoError(t, err)
+	}
+	collectors := metrics.NewCollectors()
+
+	first := createTestCertificate(1, "True")
+	_, changed, err := syncCertificate(fakeClient, collectors, first, nil)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Added Certificate should only be remembered")
+	assert.Equal(t, float64(notAfter.Unix()), testutil.ToFloat64(collectors.CertificateNotAfter.WithLabelValues("default", "web")))
+
+	updateSecret(func(secret *v1.Secret) {
+		secret.Annotations = map[string]string{"cert-manager.io/common-name": "web.example.com"}
+	})
+	reissued := createTestCertificate(2, "True")
+	_, changed, err = syncCertificate(fakeClient, collectors, reissued, first)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Issuance without a different certificate should not reload")
+
+	renewedNotAfter := notAfter.Add(60 * 24 * time.Hour)
+	updateSecret(func(secret *v1.Secret) {
+		secret.Data[v1.TLSCertKey] = createTestTLSCertificate(t, renewedNotAfter)
+	})
+	issuing := createTestCertificate(2, "False")
+	_, changed, err = syncCertificate(fakeClient, collectors, issuing, reissued)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Certificate not Ready should not reload")
+
+	renewed := createTestCertificate(3, "True")
+	config, changed, err := syncCertificate(fakeClient, collectors, renewed, issuing)
+	assert.NoError(t, err)
+	assert.True(t, changed, "Ready Certificate with a different certificate should reload")
+	assert.Equal(t, "web", config.ResourceName)
+	assert.Equal(t, options.CertificateUpdateOnChangeAnnotation, config.Annotation)
+	assert.Equal(t, constants.CertificateEnvVarPostfix, config.Type)
+	assert.Equal(t, float64(renewedNotAfter.Unix()), testutil.ToFloat64(collectors.CertificateNotAfter.WithLabelValues("default", "web")))
+
+	_, changed, err = syncCertificate(fakeClient, collectors, renewed, renewed)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Status update without new issuance should not reload")
+
+	ForgetCertificate(renewed, collectors)
+	assert.Equal(t, 0, testutil.CollectAndCount(collectors.CertificateNotAfter))
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// getAllowedWatchNamespaces returns the namespaces other than its own whose workloads may watch the changed resource,
+// as listed by its allow-watch-from annotation. All namespaces are returned as metav1.NamespaceAll.
+func getAllowedWatchNamespaces(config common.Config) []string {
+	value := config.ResourceAnnotations[options.AllowWatchFromAnnotation]
+	if config.Namespace == "" || value == "" {
+		return nil
+	}
+
+	var namespaces []string
+	for _, namespace := range strings.Split(value, ",") {
+		namespace = strings.TrimSpace(namespace)
+		switch {
+		case namespace == "*":
+			return []string{metav1.NamespaceAll}
+		case namespace != "" && namespace != config.Namespace && isWatchedNamespace(namespace) && !slices.Contains(namespaces, namespace):
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// isWatchedNamespace checks whether Reloader watches the given namespace according to its namespace filters. It is
+// replaced by SetNamespaceFilter once the watch mode of Reloader is known.
+var isWatchedNamespace = func(namespace string) bool {
+	if slices.Contains(options.NamespacesToIgnore, namespace) {
+		return false
+	}
+	return len(options.Namespaces) == 0 || slices.Contains(options.Namespaces, namespace)
+}
+
+// SetNamespaceFilter sets the check whether Reloader watches a namespace, used for workloads listed across namespaces.
+// It must be set before the controllers start, the filter is called with the options read lock held.
+func SetNamespaceFilter(filter func(namespace string) bool) {
+	isWatchedNamespace = filter
+}
+
+// getWatchingItems returns the workloads of the namespace of the changed resource, and of the other namespaces
+// allowed to watch it
+func getWatchingItems(clients kube.Clients, config common.Config, upgradeFuncs callbacks.RollingUpgradeFuncs) []runtime.Object {
+	namespaces := getAllowedWatchNamespaces(config)
+	if slices.Contains(namespaces, metav1.NamespaceAll) {
+		items := upgradeFuncs.ItemsFunc(clients, metav1.NamespaceAll)
+		return slices.DeleteFunc(items, func(item runtime.Object) bool {
+			accessor, err := meta.Accessor(item)
+			return err == nil && accessor.GetNamespace() != config.Namespace && !isWatchedNamespace(accessor.GetNamespace())
+		})
+	}
+
+	items := upgradeFuncs.ItemsFunc(clients, config.Namespace)
+	for _, namespace := range namespaces {
+		items = append(items, upgradeFuncs.ItemsFunc(clients, namespace)...)
+	}
+	return items
+}
+
+// getWorkloadConfig returns the config to reload a workload with. Workloads in another namespace than the changed
+// resource get a config in their namespace, remembering the namespace of the resource.
+func getWorkloadConfig(config common.Config, item runtime.Object) (common.Config, error) {
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		return config, err
+	}
+	namespace := accessor.GetNamespace()
+	if namespace == "" || namespace == config.Namespace {
+		return config, nil
+	}
+
+	workloadConfig := config
+	workloadConfig.Namespace = namespace
+	workloadConfig.SourceNamespace = config.Namespace
+	return workloadConfig, nil
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	app "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func TestGetAllowedWatchNamespaces(t *testing.T) {
+	defer func(namespaces, ignored []string) {
+		options.Namespaces, options.NamespacesToIgnore = namespaces, ignored
+	}(options.Namespaces, options.NamespacesToIgnore)
+
+	tests := []struct {
+		name       string
+		allowed    string
+		namespaces []string
+		ignored    []string
+		expected   []string
+	}{
+		{name: "Not allowed"},
+		{name: "Listed namespaces", allowed: "team-a, team-b,team-a,platform", expected: []string{"team-a", "team-b"}},
+		{name: "All namespaces", allowed: "team-a,*", expected: []string{metav1.NamespaceAll}},
+		{name: "Ignored namespace", allowed: "team-a,team-b", ignored: []string{"team-b"}, expected: []string{"team-a"}},
+		{name: "Namespace not watched in scoped mode", allowed: "team-a,team-b", namespaces: []string{"platform", "team-b"}, expected: []string{"team-b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options.Namespaces, options.NamespacesToIgnore = tt.namespaces, tt.ignored
+			config := common.Config{
+				Namespace:           "platform",
+				ResourceName:        "ca-bundle",
+				ResourceAnnotations: map[string]string{options.AllowWatchFromAnnotation: tt.allowed},
+			}
+			assert.Equal(t, tt.expected, getAllowedWatchNamespaces(config))
+		})
+	}
+}
+
+func TestPerformActionCrossNamespace(t *testing.T) {
+	createDeployment := func(namespace, name string, annotations map[string]string) *app.Deployment {
+		return &app.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+			Spec: app.DeploymentSpec{
+				Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+			},
+		}
+	}
+	watching := map[string]string{options.WatchAnnotation: "platform/ca-bundle"}
+
+	tests := []struct {
+		name     string
+		allowed  string
+		expected map[string]bool
+	}{
+		{
+			name:     "Source not opted in",
+			expected: map[string]bool{"team-a/web": false, "team-b/web": false, "team-a/api": false},
+		},
+		{
+			name:     "Source allows one namespace",
+			allowed:  "team-a",
+			expected: map[string]bool{"team-a/web": true, "team-b/web": false, "team-a/api": false},
+		},
+		{
+			name:     "Source allows all namespaces",
+			allowed:  "*",
+			expected: map[string]bool{"team-a/web": true, "team-b/web": true, "team-a/api": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := testclient.NewClientset(
+				createDeployment("team-a", "web", watching),
+				createDeployment("team-b", "web", watching),
+				createDeployment("team-a", "api", map[string]string{options.ReloaderAutoAnnotation: "true"}),
+			)
+			config := common.Config{
+				Namespace:           "platform",
+				ResourceName:        "ca-bundle",
+				ResourceAnnotations: map[string]string{options.AllowWatchFromAnnotation: tt.allowed},
+				Annotation:          options.ConfigmapUpdateOnChangeAnnotation,
+				TypedAutoAnnotation: options.ConfigmapReloaderAutoAnnotation,
+				SHAValue:            "sha256:abc123",
+				Type:                constants.ConfigmapEnvVarPostfix,
+			}
+
+			err := PerformAction(kube.Clients{KubernetesClient: fakeClient}, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy)
+			assert.NoError(t, err)
+
+			for key, reloaded := range tt.expected {
+				namespace, name, _ := strings.Cut(key, "/")
+				deployment, err := fakeClient.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, reloaded, len(deployment.Spec.Template.Spec.Containers[0].Env) > 0, "Unexpected reload of %s", key)
+			}
+		})
+	}
+}
+
+func TestPerformActionCrossNamespaceSkipsFailingWorkloads(t *testing.T) {
+	createDeployment := func(namespace string) *app.Deployment {
+		return &app.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace, Annotations: map[string]string{options.WatchAnnotation: "platform/ca-bundle"}},
+			Spec: app.DeploymentSpec{
+				Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+			},
+		}
+	}
+	fakeClient := testclient.NewClientset(createDeployment("team-a"), createDeployment("team-b"))
+	forbidden := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() != "team-a" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(app.Resource("deployments"), "web", errors.New("not allowed"))
+	}
+	fakeClient.PrependReactor("patch", "deployments", forbidden)
+	fakeClient.PrependReactor("update", "deployments", forbidden)
+
+	upgradeFuncs := GetDeploymentRollingUpgradeFuncs()
+	itemsFunc := upgradeFuncs.ItemsFunc
+	upgradeFuncs.ItemsFunc = func(clients kube.Clients, namespace string) []runtime.Object {
+		// A listed object without metadata can't get a config to be reloaded with
+		return append([]runtime.Object{&metav1.Status{}}, itemsFunc(clients, namespace)...)
+	}
+	config := common.Config{
+		Namespace:           "platform",
+		ResourceName:        "ca-bundle",
+		ResourceAnnotations: map[string]string{options.AllowWatchFromAnnotation: "*"},
+		Annotation:          options.ConfigmapUpdateOnChangeAnnotation,
+		TypedAutoAnnotation: options.ConfigmapReloaderAutoAnnotation,
+		SHAValue:            "sha256:abc123",
+		Type:                constants.ConfigmapEnvVarPostfix,
+	}
+
+	err := PerformAction(kube.Clients{KubernetesClient: fakeClient}, config, upgradeFuncs, metrics.NewCollectors(), nil, invokeReloadStrategy)
+	assert.NoError(t, err)
+
+	deployment, err := fakeClient.AppsV1().Deployments("team-b").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, deployment.Spec.Template.Spec.Containers[0].Env, "Workload should reload even if other workloads fail to")
+}
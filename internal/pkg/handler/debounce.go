@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+
+	"github.com/stakater/Reloader/pkg/common"
+)
+
+// debouncer collects the changes of resources reloading workloads whose ReloaderPolicy defines a debounce period
+var debouncer = NewDebouncer(clock.RealClock{})
+
+// Debouncer postpones reloads until the changes triggering them settled. Postponed reloads are added to the queue of
+// a controller once their period passed, so that they are handled by its workers like any other change. It is safe for
+// concurrent use by the controller workers.
+type Debouncer struct {
+	clock   clock.Clock
+	mutex   sync.Mutex
+	queue   workqueue.TypedDelayingInterface[any]
+	pending map[string]*pendingReload
+}
+
+type pendingReload struct {
+	reload func() error
+	// dueAt is when the debounce period of the latest change passes
+	dueAt time.Time
+	// due is set once the debounce period passed, letting the next reload for the key through
+	due bool
+}
+
+// DebouncedReloadHandler runs a postponed reload once its debounce period passed
+type DebouncedReloadHandler struct {
+	Key string
+}
+
+// Handle runs the postponed reload, or adds the handler to the queue again if a later change restarted the period
+func (r DebouncedReloadHandler) Handle() error {
+	return debouncer.Reload(r.Key)
+}
+
+// GetConfig returns the key of the postponed reload as resource name, it has no SHA
+func (r DebouncedReloadHandler) GetConfig() (common.Config, string) {
+	return common.Config{ResourceName: r.Key}, ""
+}
+
+// NewDebouncer creates a debouncer using the given clock, which allows tests to control time
+func NewDebouncer(clock clock.Clock) *Debouncer {
+	return &Debouncer{
+		clock:   clock,
+		pending: make(map[string]*pendingReload),
+	}
+}
+
+// SetDebounceQueue sets the queue postponed reloads are added to. All controllers handle the queued handlers the same
+// way, so the queue of any running controller does.
+func SetDebounceQueue(queue workqueue.TypedDelayingInterface[any]) {
+	debouncer.SetQueue(queue)
+}
+
+// SetQueue sets the queue postponed reloads are added to
+func (d *Debouncer) SetQueue(queue workqueue.TypedDelayingInterface[any]) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.queue = queue
+}
+
+// Debounce postpones a reload until no further change arrived for key within the given period, then runs the reload
+// of the latest change. It returns whether the reload has been postponed, which is the case unless it is the reload
+// run by the debouncer itself or no queue is set to postpone it.
+func (d *Debouncer) Debounce(key string, period time.Duration, reload func() error) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	entry, exists := d.pending[key]
+	if exists && entry.due {
+		delete(d.pending, key)
+		return false
+	}
+	if exists {
+		entry.reload = reload
+		entry.dueAt = d.clock.Now().Add(period)
+		return true
+	}
+	if d.queue == nil {
+		return false
+	}
+
+	d.pending[key] = &pendingReload{reload: reload, dueAt: d.clock.Now().Add(period)}
+	d.queue.AddAfter(DebouncedReloadHandler{Key: key}, period)
+	return true
+}
+
+// Reload runs the postponed reload of key once its debounce period passed. A failed reload stays pending, so that it is
+// retried when the queue hands the handler back.
+func (d *Debouncer) Reload(key string) error {
+	d.mutex.Lock()
+	entry, exists := d.pending[key]
+	if !exists {
+		d.mutex.Unlock()
+		return nil
+	}
+	if remaining := entry.dueAt.Sub(d.clock.Now()); remaining > 0 {
+		d.queue.AddAfter(DebouncedReloadHandler{Key: key}, remaining)
+		d.mutex.Unlock()
+		return nil
+	}
+	entry.due = true
+	reload := entry.reload
+	d.mutex.Unlock()
+
+	err := reload()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	// Forget the entry if the reload returned before reaching the debouncer, e.g. as the workload changed
+	if d.pending[key] == entry {
+		delete(d.pending, key)
+	}
+	if err != nil {
+		if _, exists := d.pending[key]; !exists {
+			d.pending[key] = &pendingReload{reload: reload, dueAt: entry.dueAt}
+		}
+	}
+	return err
+}
+
+// IsPending checks whether a reload is postponed for key
+func (d *Debouncer) IsPending(key string) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	_, exists := d.pending[key]
+	return exists
+}
+
+// getDebounceKey returns the key of the reloads of a workload triggered by changes of a resource
+func getDebounceKey(resourceType, name string, config common.Config) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", resourceType, config.Namespace, name, config.Type, config.ResourceName)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// newTestDebouncer creates a debouncer adding postponed reloads to a queue using the fake clock
+func newTestDebouncer(t *testing.T) (*Debouncer, workqueue.TypedDelayingInterface[any], *clocktesting.FakeClock) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	queue := workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[any]{Clock: fakeClock})
+	t.Cleanup(queue.ShutDown)
+	debouncer := NewDebouncer(fakeClock)
+	debouncer.SetQueue(queue)
+	return debouncer, queue, fakeClock
+}
+
+// handleQueued waits for the next handler added to the queue and runs its reload
+func handleQueued(t *testing.T, debouncer *Debouncer, queue workqueue.TypedDelayingInterface[any]) error {
+	t.Helper()
+	assert.Eventually(t, func() bool { return queue.Len() > 0 }, 5*time.Second, time.Millisecond)
+	item, _ := queue.Get()
+	defer queue.Done(item)
+	return debouncer.Reload(item.(DebouncedReloadHandler).Key)
+}
+
+func TestDebouncer_RunsLatestReloadOnceSettled(t *testing.T) {
+	debouncer, queue, fakeClock := newTestDebouncer(t)
+
+	var reloads []string
+	var postponed bool
+	reload := func(name string) func() error {
+		return func() error {
+			reloads = append(reloads, name)
+			// The reload run by the debouncer passes it
+			postponed = debouncer.Debounce("key", time.Minute, func() error { return nil })
+			return nil
+		}
+	}
+
+	assert.True(t, debouncer.Debounce("key", time.Minute, reload("first")))
+	fakeClock.Step(30 * time.Second)
+	assert.True(t, debouncer.Debounce("key", time.Minute, reload("second")))
+
+	// The second change restarted the period, the queued handler waits for the rest of it
+	fakeClock.Step(30 * time.Second)
+	assert.NoError(t, handleQueued(t, debouncer, queue))
+	assert.Empty(t, reloads)
+	assert.True(t, debouncer.IsPending("key"))
+
+	fakeClock.Step(30 * time.Second)
+	assert.NoError(t, handleQueued(t, debouncer, queue))
+	assert.Equal(t, []string{"second"}, reloads)
+	assert.False(t, postponed)
+	assert.False(t, debouncer.IsPending("key"))
+}
+
+func TestDebouncer_KeysAreIndependent(t *testing.T) {
+	debouncer, queue, fakeClock := newTestDebouncer(t)
+
+	var reloads []string
+	debouncer.Debounce("a", time.Minute, func() error { reloads = append(reloads, "a"); return nil })
+	debouncer.Debounce("b", 2*time.Minute, func() error { reloads = append(reloads, "b"); return nil })
+
+	fakeClock.Step(time.Minute)
+	assert.NoError(t, handleQueued(t, debouncer, queue))
+	assert.Equal(t, []string{"a"}, reloads)
+	assert.False(t, debouncer.IsPending("a"))
+	assert.True(t, debouncer.IsPending("b"))
+
+	fakeClock.Step(time.Minute)
+	assert.NoError(t, handleQueued(t, debouncer, queue))
+	assert.Equal(t, []string{"a", "b"}, reloads)
+}
+
+func TestDebouncer_FailedReloadStaysPending(t *testing.T) {
+	debouncer, queue, fakeClock := newTestDebouncer(t)
+
+	attempts := 0
+	debouncer.Debounce("key", time.Minute, func() error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("conflict")
+		}
+		return nil
+	})
+
+	fakeClock.Step(time.Minute)
+	assert.Error(t, handleQueued(t, debouncer, queue))
+	assert.True(t, debouncer.IsPending("key"))
+
+	// The controller retries the failed handler
+	assert.NoError(t, debouncer.Reload("key"))
+	assert.Equal(t, 2, attempts)
+	assert.False(t, debouncer.IsPending("key"))
+}
+
+func TestDebouncer_WithoutQueue(t *testing.T) {
+	debouncer := NewDebouncer(clocktesting.NewFakeClock(time.Now()))
+	assert.False(t, debouncer.Debounce("key", time.Minute, func() error { return nil }), "Reloads should not be postponed without a queue")
+}
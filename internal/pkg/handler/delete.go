@@ -78,9 +78,14 @@ func (r ResourceDeleteHandler) GetConfig() (common.Config, string) {
 	return config, oldSHAData
 }
 
-func invokeDeleteStrategy(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config, autoReload bool) InvokeStrategyResult {
-	if options.ReloadStrategy == constants.AnnotationsReloadStrategy {
+func invokeDeleteStrategy(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config, autoReload bool, reloadStrategy string) InvokeStrategyResult {
+	switch reloadStrategy {
+	case constants.AnnotationsReloadStrategy:
 		return removePodAnnotations(upgradeFuncs, item, config, autoReload)
+	case constants.RestartedAtReloadStrategy:
+		// Restart to pick up the deleted resource, remembering it as empty to restart once more when it is recreated
+		config.SHAValue = testutil.GetSHAfromEmptyData()
+		return updatePodRestartedAt(upgradeFuncs, item, config, autoReload)
 	}
 
 	return removeContainerEnvVars(upgradeFuncs, item, config, autoReload)
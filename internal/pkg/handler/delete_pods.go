@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	app "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+var (
+	// deletePodsPollInterval is the interval to retry evictions blocked by a PodDisruptionBudget and to check replacement pods
+	deletePodsPollInterval = 5 * time.Second
+	// podDeletions tracks the workloads whose pods are being deleted
+	podDeletions = &podDeletionTracker{rerun: make(map[string]bool)}
+)
+
+// podDeletionTracker makes sure the pods of a workload are deleted by a single goroutine at a time
+type podDeletionTracker struct {
+	mutex sync.Mutex
+	// rerun is set for workloads changed again while their pods were being deleted
+	rerun map[string]bool
+	// ctx is cancelled to stop all deletions
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// start returns whether the deletion of the pods of a workload must be started, and the context to run it with. If it
+// is already running, it is run again once finished, so pods replaced before the latest change are deleted as well.
+func (t *podDeletionTracker) start(key string) (context.Context, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, running := t.rerun[key]; running {
+		t.rerun[key] = true
+		return nil, false
+	}
+	t.rerun[key] = false
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+	}
+	return t.ctx, true
+}
+
+// stop cancels all deletions, the workloads changed while deleting their pods aren't run again
+func (t *podDeletionTracker) stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cancel != nil {
+		t.cancel()
+		t.ctx, t.cancel = nil, nil
+	}
+	t.rerun = make(map[string]bool)
+}
+
+// finish returns whether the deletion of the pods of a workload must run again. Deletions stopped since they started
+// don't run again and leave the tracking of new deletions alone.
+func (t *podDeletionTracker) finish(ctx context.Context, key string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if ctx.Err() != nil {
+		return false
+	}
+	if t.rerun[key] {
+		t.rerun[key] = false
+		return true
+	}
+	delete(t.rerun, key)
+	return false
+}
+
+// isOnDeleteWorkload checks whether the workload only replaces pods deleted by someone else after its template changed,
+// like ReplicaSets do. Workloads paused by Reloader are not, their original update strategy is restored on resume.
+func isOnDeleteWorkload(item runtime.Object) bool {
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		return false
+	}
+	if _, paused := accessor.GetAnnotations()[getPausedUpdateStrategyAnnotationKey()]; paused {
+		return false
+	}
+
+	switch workload := item.(type) {
+	case *app.ReplicaSet:
+		return true
+	case *app.StatefulSet:
+		return workload.Spec.UpdateStrategy.Type == app.OnDeleteStatefulSetStrategyType
+	case *app.DaemonSet:
+		return workload.Spec.UpdateStrategy.Type == app.OnDeleteDaemonSetStrategyType
+	}
+	return false
+}
+
+// deleteWorkloadPods deletes the pods of a workload using the OnDelete update strategy in the background, one by one,
+// so that they are recreated from the updated template
+func deleteWorkloadPods(clients kube.Clients, namespace string, upgradeFuncs callbacks.RollingUpgradeFuncs, recorder record.EventRecorder, item runtime.Object) {
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		logrus.Errorf("Failed to delete pods of %s in namespace '%s': %v", upgradeFuncs.ResourceType, namespace, err)
+		return
+	}
+	resourceName := accessor.GetName()
+
+	key := fmt.Sprintf("%s/%s/%s", upgradeFuncs.ResourceType, namespace, resourceName)
+	ctx, started := podDeletions.start(key)
+	if !started {
+		logrus.Infof("Pods of %s '%s' in namespace '%s' are already being deleted, deleting them again afterwards", upgradeFuncs.ResourceType, resourceName, namespace)
+		return
+	}
+
+	timeout := options.DeletePodsTimeout
+	go func() {
+		for {
+			deleted, err := deletePodsOneByOne(ctx, clients, item, timeout)
+			if ctx.Err() != nil {
+				logrus.Infof("Stopped deleting pods of %s '%s' in namespace '%s' after %d pods", upgradeFuncs.ResourceType, resourceName, namespace, deleted)
+				return
+			}
+			if err != nil {
+				logrus.Errorf("Failed to delete pods of %s '%s' in namespace '%s': %v", upgradeFuncs.ResourceType, resourceName, namespace, err)
+				if recorder != nil {
+					recorder.Event(item, v1.EventTypeWarning, "DeletePodsFailed", fmt.Sprintf("Failed to delete pods of '%s' of type '%s' in namespace '%s': %v", resourceName, upgradeFuncs.ResourceType, namespace, err))
+				}
+			} else {
+				logrus.Infof("Deleted %d pods of %s '%s' in namespace '%s'", deleted, upgradeFuncs.ResourceType, resourceName, namespace)
+				if recorder != nil {
+					recorder.Event(item, v1.EventTypeNormal, "PodsDeleted", fmt.Sprintf("Deleted %d pods of '%s' of type '%s' in namespace '%s' one by one", deleted, resourceName, upgradeFuncs.ResourceType, namespace))
+				}
+			}
+
+			if !podDeletions.finish(ctx, key) {
+				return
+			}
+		}
+	}()
+}
+
+// StopPodDeletions stops deleting the pods of workloads one by one, e.g. when leadership is lost, so that they are not
+// evicted by two replicas at a time. The new leader deletes the pods once their workloads change again.
+func StopPodDeletions() {
+	podDeletions.stop()
+}
+
+// deletePodsOneByOne evicts the pods of a workload in reverse ordinal order, waiting for each replacement to become
+// ready before evicting the next one. It returns the number of evicted pods.
+func deletePodsOneByOne(ctx context.Context, clients kube.Clients, item runtime.Object, timeout time.Duration) (int, error) {
+	pods, err := getWorkloadPods(ctx, clients, item)
+	if err != nil {
+		return 0, err
+	}
+	// Pods existing before and replacements found so far, a replacement is never matched twice
+	seen := make(map[types.UID]bool)
+	for _, pod := range pods {
+		seen[pod.UID] = true
+	}
+	pods = slices.DeleteFunc(pods, func(pod v1.Pod) bool { return pod.DeletionTimestamp != nil })
+	sortPodsForDeletion(item, pods)
+
+	for i := range pods {
+		pod := &pods[i]
+		if err := evictPod(ctx, clients, pod, timeout); err != nil {
+			return i, fmt.Errorf("failed to evict pod '%s': %w", pod.Name, err)
+		}
+		logrus.Infof("Evicted pod '%s' in namespace '%s', waiting for its replacement to become ready", pod.Name, pod.Namespace)
+
+		if err := waitForReplacementPod(ctx, clients, item, pod, seen, timeout); err != nil {
+			return i + 1, fmt.Errorf("replacement of pod '%s' did not become ready within %s: %w", pod.Name, timeout, err)
+		}
+	}
+	return len(pods), nil
+}
+
+// getWorkloadPods returns the pods controlled by a workload
+func getWorkloadPods(ctx context.Context, clients kube.Clients, item runtime.Object) ([]v1.Pod, error) {
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := getPodSelector(item)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := clients.KubernetesClient.CoreV1().Pods(accessor.GetNamespace()).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(pods.Items, func(pod v1.Pod) bool { return !metav1.IsControlledBy(&pod, accessor) }), nil
+}
+
+// sortPodsForDeletion sorts the pods of a StatefulSet by descending ordinal, like a rolling update would replace them.
+// Pods of other workloads have no ordinal and are sorted by descending name.
+func sortPodsForDeletion(item runtime.Object, pods []v1.Pod) {
+	accessor, _ := meta.Accessor(item)
+	ordinal := func(pod v1.Pod) int {
+		if _, ok := item.(*app.StatefulSet); !ok || accessor == nil {
+			return -1
+		}
+		suffix, found := strings.CutPrefix(pod.Name, accessor.GetName()+"-")
+		if !found {
+			return -1
+		}
+		n, err := strconv.Atoi(suffix)
+		if err != nil {
+			return -1
+		}
+		return n
+	}
+
+	slices.SortStableFunc(pods, func(a, b v1.Pod) int {
+		if ordinalA, ordinalB := ordinal(a), ordinal(b); ordinalA != ordinalB {
+			return ordinalB - ordinalA
+		}
+		return strings.Compare(b.Name, a.Name)
+	})
+}
+
+// evictPod evicts a pod through the eviction API, retrying while a PodDisruptionBudget doesn't allow the disruption
+func evictPod(ctx context.Context, clients kube.Clients, pod *v1.Pod, timeout time.Duration) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		// Never evict a pod already replaced under the same name
+		DeleteOptions: &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &pod.UID}},
+	}
+
+	return wait.PollUntilContextTimeout(ctx, deletePodsPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		err := clients.KubernetesClient.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case err == nil, apierrors.IsNotFound(err), apierrors.IsConflict(err):
+			return true, nil
+		case apierrors.IsTooManyRequests(err):
+			logrus.Infof("Eviction of pod '%s' in namespace '%s' is blocked by a PodDisruptionBudget, retrying", pod.Name, pod.Namespace)
+			return false, nil
+		default:
+			return false, err
+		}
+	})
+}
+
+// waitForReplacementPod waits until the workload replaced the evicted pod by a ready one. StatefulSets recreate pods
+// under the same name, DaemonSets on the same node and ReplicaSets anywhere.
+func waitForReplacementPod(ctx context.Context, clients kube.Clients, item runtime.Object, evicted *v1.Pod, seen map[types.UID]bool, timeout time.Duration) error {
+	isReplacement := func(pod *v1.Pod) bool {
+		switch item.(type) {
+		case *app.StatefulSet:
+			return pod.Name == evicted.Name
+		case *app.DaemonSet:
+			return pod.Spec.NodeName == evicted.Spec.NodeName
+		}
+		return true
+	}
+
+	return wait.PollUntilContextTimeout(ctx, deletePodsPollInterval, timeout, false, func(ctx context.Context) (bool, error) {
+		pods, err := getWorkloadPods(ctx, clients, item)
+		if err != nil {
+			logrus.Debugf("Failed to list pods replacing pod '%s' in namespace '%s': %v", evicted.Name, evicted.Namespace, err)
+			return false, nil
+		}
+		for i := range pods {
+			pod := &pods[i]
+			if seen[pod.UID] || pod.DeletionTimestamp != nil || !isPodReady(pod) || !isReplacement(pod) {
+				continue
+			}
+			seen[pod.UID] = true
+			return true, nil
+		}
+		return false, nil
+	})
+}
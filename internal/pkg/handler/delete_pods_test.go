@@ -0,0 +1,407 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// fakeEvictions replaces evicted pods by ready pods, as the workload controller would, and records the evicted pods
+type fakeEvictions struct {
+	mutex   sync.Mutex
+	evicted []string
+	// blocked is the number of evictions rejected by a PodDisruptionBudget before one is allowed
+	blocked int
+	// replace returns the pod replacing an evicted pod, or nil if it is never replaced
+	replace func(evicted *v1.Pod) *v1.Pod
+}
+
+func useFakeEvictions(t *testing.T, fakeClient *testclient.Clientset, replace func(evicted *v1.Pod) *v1.Pod) *fakeEvictions {
+	original := deletePodsPollInterval
+	deletePodsPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() {
+		// Let the deletions running in the background finish before restoring the interval they poll with
+		assert.Eventually(t, func() bool {
+			podDeletions.mutex.Lock()
+			defer podDeletions.mutex.Unlock()
+			return len(podDeletions.rerun) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+		deletePodsPollInterval = original
+	})
+
+	fake := &fakeEvictions{replace: replace}
+	podsResource := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+
+		if fake.blocked > 0 {
+			fake.blocked--
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		obj, err := fakeClient.Tracker().Get(podsResource, eviction.Namespace, eviction.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*v1.Pod)
+		if err := fakeClient.Tracker().Delete(podsResource, pod.Namespace, pod.Name); err != nil {
+			return true, nil, err
+		}
+		fake.evicted = append(fake.evicted, pod.Name)
+		if replacement := fake.replace(pod); replacement != nil {
+			if err := fakeClient.Tracker().Create(podsResource, replacement, replacement.Namespace); err != nil {
+				return true, nil, err
+			}
+		}
+		return true, nil, nil
+	})
+	return fake
+}
+
+func (f *fakeEvictions) evictedPods() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.evicted...)
+}
+
+func createOnDeleteStatefulSet() *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "web-uid"},
+		Spec: appsv1.StatefulSetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType},
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "app",
+							EnvFrom: []v1.EnvFromSource{
+								{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func createOwnedPod(name, nodeName string, owner metav1.Object, kind string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       owner.GetNamespace(),
+			UID:             types.UID(name + "-uid"),
+			Labels:          map[string]string{"app": "web"},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind(kind))},
+		},
+		Spec: v1.PodSpec{NodeName: nodeName},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+		},
+	}
+}
+
+// recreatePod replaces a pod under the same name, like a StatefulSet does
+func recreatePod(evicted *v1.Pod) *v1.Pod {
+	replacement := evicted.DeepCopy()
+	replacement.UID = types.UID(fmt.Sprintf("%s-replacement", evicted.UID))
+	replacement.ResourceVersion = ""
+	return replacement
+}
+
+func TestIsOnDeleteWorkload(t *testing.T) {
+	statefulSet := createOnDeleteStatefulSet()
+	assert.True(t, isOnDeleteWorkload(statefulSet))
+
+	statefulSet.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	assert.False(t, isOnDeleteWorkload(statefulSet))
+
+	daemonSet := &appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}}}
+	assert.True(t, isOnDeleteWorkload(daemonSet))
+
+	daemonSet.Annotations = map[string]string{getPausedUpdateStrategyAnnotationKey(): `{"type":"RollingUpdate"}`}
+	assert.False(t, isOnDeleteWorkload(daemonSet), "DaemonSet paused by Reloader should not be treated as OnDelete")
+
+	assert.False(t, isOnDeleteWorkload(&appsv1.Deployment{}))
+}
+
+func TestSortPodsForDeletion(t *testing.T) {
+	statefulSet := createOnDeleteStatefulSet()
+	pods := []v1.Pod{
+		*createOwnedPod("web-0", "", statefulSet, "StatefulSet"),
+		*createOwnedPod("web-10", "", statefulSet, "StatefulSet"),
+		*createOwnedPod("web-2", "", statefulSet, "StatefulSet"),
+	}
+	sortPodsForDeletion(statefulSet, pods)
+	assert.Equal(t, []string{"web-10", "web-2", "web-0"}, []string{pods[0].Name, pods[1].Name, pods[2].Name})
+
+	daemonSet := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"}}
+	pods = []v1.Pod{
+		*createOwnedPod("agent-abc", "node-1", daemonSet, "DaemonSet"),
+		*createOwnedPod("agent-xyz", "node-2", daemonSet, "DaemonSet"),
+	}
+	sortPodsForDeletion(daemonSet, pods)
+	assert.Equal(t, []string{"agent-xyz", "agent-abc"}, []string{pods[0].Name, pods[1].Name})
+}
+
+func TestDeletePodsOneByOne(t *testing.T) {
+	statefulSet := createOnDeleteStatefulSet()
+	other := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", UID: "other-uid"}}
+	terminating := createOwnedPod("web-3", "", statefulSet, "StatefulSet")
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	terminating.Finalizers = []string{"test"}
+
+	fakeClient := testclient.NewClientset(
+		statefulSet,
+		createOwnedPod("web-0", "", statefulSet, "StatefulSet"),
+		createOwnedPod("web-1", "", statefulSet, "StatefulSet"),
+		createOwnedPod("web-2", "", statefulSet, "StatefulSet"),
+		terminating,
+		createOwnedPod("other-0", "", other, "StatefulSet"),
+	)
+	evictions := useFakeEvictions(t, fakeClient, recreatePod)
+	evictions.blocked = 2
+
+	deleted, err := deletePodsOneByOne(context.Background(), kube.Clients{KubernetesClient: fakeClient}, statefulSet, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	assert.Equal(t, []string{"web-2", "web-1", "web-0"}, evictions.evictedPods(), "Pods should be evicted in reverse ordinal order, skipping terminating pods and pods of other workloads")
+}
+
+func TestDeletePodsOneByOneDaemonSet(t *testing.T) {
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default", UID: "agent-uid"},
+		Spec: appsv1.DaemonSetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType},
+		},
+	}
+	fakeClient := testclient.NewClientset(
+		daemonSet,
+		createOwnedPod("agent-a", "node-1", daemonSet, "DaemonSet"),
+		createOwnedPod("agent-b", "node-2", daemonSet, "DaemonSet"),
+	)
+	evictions := useFakeEvictions(t, fakeClient, func(evicted *v1.Pod) *v1.Pod {
+		replacement := recreatePod(evicted)
+		replacement.Name = evicted.Name + "-new"
+		return replacement
+	})
+
+	deleted, err := deletePodsOneByOne(context.Background(), kube.Clients{KubernetesClient: fakeClient}, daemonSet, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, []string{"agent-b", "agent-a"}, evictions.evictedPods())
+}
+
+func TestDeletePodsOneByOneReplacementNotReady(t *testing.T) {
+	statefulSet := createOnDeleteStatefulSet()
+	fakeClient := testclient.NewClientset(
+		statefulSet,
+		createOwnedPod("web-0", "", statefulSet, "StatefulSet"),
+		createOwnedPod("web-1", "", statefulSet, "StatefulSet"),
+	)
+	evictions := useFakeEvictions(t, fakeClient, func(evicted *v1.Pod) *v1.Pod {
+		replacement := recreatePod(evicted)
+		replacement.Status.Conditions = nil
+		return replacement
+	})
+
+	deleted, err := deletePodsOneByOne(context.Background(), kube.Clients{KubernetesClient: fakeClient}, statefulSet, 100*time.Millisecond)
+	assert.Error(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, []string{"web-1"}, evictions.evictedPods(), "Next pod should not be evicted before the replacement is ready")
+}
+
+func TestPodDeletionTracker(t *testing.T) {
+	tracker := &podDeletionTracker{rerun: make(map[string]bool)}
+
+	ctx, started := tracker.start("web")
+	assert.True(t, started)
+	_, started = tracker.start("web")
+	assert.False(t, started, "Deletion should not start while already running")
+	_, started = tracker.start("web")
+	assert.False(t, started)
+	_, started = tracker.start("other")
+	assert.True(t, started)
+
+	assert.True(t, tracker.finish(ctx, "web"), "Deletion should run again after changes while running")
+	assert.False(t, tracker.finish(ctx, "web"))
+	_, started = tracker.start("web")
+	assert.True(t, started)
+
+	tracker.stop()
+	assert.Error(t, ctx.Err(), "Deletions should be cancelled once stopped")
+	newCtx, started := tracker.start("web")
+	assert.True(t, started, "Deletions should start again after being stopped")
+	assert.NoError(t, newCtx.Err())
+	assert.False(t, tracker.finish(ctx, "web"), "Stopped deletions should not run again")
+	assert.Contains(t, tracker.rerun, "web", "Stopped deletions should not finish new ones")
+	assert.False(t, tracker.finish(newCtx, "web"))
+	assert.Empty(t, tracker.rerun)
+}
+
+func TestStopPodDeletions(t *testing.T) {
+	statefulSet := createOnDeleteStatefulSet()
+	fakeClient := testclient.NewClientset(
+		statefulSet,
+		createOwnedPod("web-0", "", statefulSet, "StatefulSet"),
+		createOwnedPod("web-1", "", statefulSet, "StatefulSet"),
+	)
+	evictions := useFakeEvictions(t, fakeClient, func(evicted *v1.Pod) *v1.Pod {
+		replacement := recreatePod(evicted)
+		replacement.Status.Conditions = nil
+		return replacement
+	})
+	defer func(timeout time.Duration) { options.DeletePodsTimeout = timeout }(options.DeletePodsTimeout)
+	options.DeletePodsTimeout = time.Hour
+
+	deleteWorkloadPods(kube.Clients{KubernetesClient: fakeClient}, "default", GetStatefulSetRollingUpgradeFuncs(), nil, statefulSet)
+	assert.Eventually(t, func() bool {
+		return len(evictions.evictedPods()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	StopPodDeletions()
+	assert.Eventually(t, func() bool {
+		podDeletions.mutex.Lock()
+		defer podDeletions.mutex.Unlock()
+		return len(podDeletions.rerun) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool {
+		return len(evictions.evictedPods()) > 1
+	}, 100*time.Millisecond, 10*time.Millisecond, "Pods should not be evicted once deletions are stopped")
+}
+
+func TestUpgradeResourceDeletePodsStrategy(t *testing.T) {
+	tests := []struct {
+		name            string
+		updateStrategy  appsv1.StatefulSetUpdateStrategyType
+		expectedEvicted []string
+	}{
+		{
+			name:            "OnDelete pods are deleted",
+			updateStrategy:  appsv1.OnDeleteStatefulSetStrategyType,
+			expectedEvicted: []string{"web-1", "web-0"},
+		},
+		{
+			name:           "RollingUpdate pods are left to the controller",
+			updateStrategy: appsv1.RollingUpdateStatefulSetStrategyType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statefulSet := createOnDeleteStatefulSet()
+			statefulSet.Spec.UpdateStrategy.Type = tt.updateStrategy
+			statefulSet.Annotations = map[string]string{
+				options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+				options.ReloadStrategyAnnotation:          constants.DeletePodsReloadStrategy,
+			}
+			fakeClient := testclient.NewClientset(
+				statefulSet,
+				createOwnedPod("web-0", "", statefulSet, "StatefulSet"),
+				createOwnedPod("web-1", "", statefulSet, "StatefulSet"),
+			)
+			evictions := useFakeEvictions(t, fakeClient, recreatePod)
+			config := common.Config{
+				ResourceName: "my-configmap",
+				Type:         constants.ConfigmapEnvVarPostfix,
+				SHAValue:     "sha256:abc123",
+				Namespace:    "default",
+				Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+			}
+
+			updated, err := upgradeResource(kube.Clients{KubernetesClient: fakeClient}, config, GetStatefulSetRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, statefulSet, false)
+			assert.NoError(t, err)
+			assert.True(t, updated)
+
+			result, err := fakeClient.AppsV1().StatefulSets("default").Get(context.TODO(), statefulSet.Name, metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, []v1.EnvVar{{Name: getEnvVarName("my-configmap", constants.ConfigmapEnvVarPostfix), Value: "sha256:abc123"}},
+				result.Spec.Template.Spec.Containers[0].Env, "Pod template should be updated with env-vars strategy")
+
+			if tt.expectedEvicted == nil {
+				assert.Never(t, func() bool {
+					return len(evictions.evictedPods()) > 0
+				}, 100*time.Millisecond, 10*time.Millisecond)
+				return
+			}
+			assert.Eventually(t, func() bool {
+				return assert.ObjectsAreEqual(tt.expectedEvicted, evictions.evictedPods())
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestUpgradeResourceUnmanagedReplicaSet(t *testing.T) {
+	statefulSet := createOnDeleteStatefulSet()
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			UID:       "web-uid",
+			Annotations: map[string]string{
+				options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+				options.ReloadUnmanagedAnnotation:         "true",
+			},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Selector: statefulSet.Spec.Selector,
+			Template: statefulSet.Spec.Template,
+		},
+	}
+	fakeClient := testclient.NewClientset(
+		replicaSet,
+		createOwnedPod("web-a", "", replicaSet, "ReplicaSet"),
+		createOwnedPod("web-b", "", replicaSet, "ReplicaSet"),
+	)
+	evictions := useFakeEvictions(t, fakeClient, func(evicted *v1.Pod) *v1.Pod {
+		return createOwnedPod(evicted.Name+"-new", "", replicaSet, "ReplicaSet")
+	})
+	config := common.Config{
+		ResourceName: "my-configmap",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    "default",
+		Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+	}
+
+	updated, err := upgradeResource(kube.Clients{KubernetesClient: fakeClient}, config, GetReplicaSetRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, replicaSet, false)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	result, err := fakeClient.AppsV1().ReplicaSets("default").Get(context.TODO(), replicaSet.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.EnvVar{{Name: getEnvVarName("my-configmap", constants.ConfigmapEnvVarPostfix), Value: "sha256:abc123"}},
+		result.Spec.Template.Spec.Containers[0].Env, "Pod template should be updated with env-vars strategy")
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"web-b", "web-a"}, evictions.evictedPods())
+	}, 5*time.Second, 10*time.Millisecond, "Pods of the ReplicaSet should be evicted one by one")
+}
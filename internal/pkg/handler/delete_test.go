@@ -11,7 +11,6 @@ import (
 
 	"github.com/stakater/Reloader/internal/pkg/callbacks"
 	"github.com/stakater/Reloader/internal/pkg/constants"
-	"github.com/stakater/Reloader/internal/pkg/options"
 	"github.com/stakater/Reloader/pkg/common"
 )
 
@@ -61,6 +60,17 @@ func mockVolumesFunc(item runtime.Object) []v1.Volume {
 	return deployment.Spec.Template.Spec.Volumes
 }
 
+func mockAnnotationsFunc(item runtime.Object) map[string]string {
+	deployment, ok := item.(*appsv1.Deployment)
+	if !ok {
+		return nil
+	}
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	return deployment.Annotations
+}
+
 func mockPodAnnotationsFunc(item runtime.Object) map[string]string {
 	deployment, ok := item.(*appsv1.Deployment)
 	if !ok {
@@ -191,6 +201,7 @@ func TestRemoveContainerEnvVars(t *testing.T) {
 				ContainersFunc:     mockContainersFunc,
 				InitContainersFunc: mockInitContainersFunc,
 				VolumesFunc:        mockVolumesFunc,
+				AnnotationsFunc:    mockAnnotationsFunc,
 				PodAnnotationsFunc: mockPodAnnotationsFunc,
 				PatchTemplatesFunc: mockPatchTemplatesFunc,
 				SupportsPatch:      true,
@@ -214,11 +225,6 @@ func TestRemoveContainerEnvVars(t *testing.T) {
 }
 
 func TestInvokeDeleteStrategy(t *testing.T) {
-	originalStrategy := options.ReloadStrategy
-	defer func() {
-		options.ReloadStrategy = originalStrategy
-	}()
-
 	tests := []struct {
 		name           string
 		reloadStrategy string
@@ -250,6 +256,29 @@ func TestInvokeDeleteStrategy(t *testing.T) {
 				SHAValue:     "sha-value",
 			},
 		},
+		{
+			name:           "RestartedAt strategy",
+			reloadStrategy: constants.RestartedAtReloadStrategy,
+			containers: []v1.Container{
+				{
+					Name: "app",
+					EnvFrom: []v1.EnvFromSource{
+						{
+							ConfigMapRef: &v1.ConfigMapEnvSource{
+								LocalObjectReference: v1.LocalObjectReference{
+									Name: "my-configmap",
+								},
+							},
+						},
+					},
+				},
+			},
+			volumes: []v1.Volume{},
+			config: common.Config{
+				ResourceName: "my-configmap",
+				Type:         constants.ConfigmapEnvVarPostfix,
+			},
+		},
 		{
 			name:           "EnvVars strategy",
 			reloadStrategy: constants.EnvVarsReloadStrategy,
@@ -280,20 +309,19 @@ func TestInvokeDeleteStrategy(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			options.ReloadStrategy = tt.reloadStrategy
-
 			deployment := mockDeploymentForDelete("test-deploy", "default", tt.containers, tt.volumes)
 
 			funcs := callbacks.RollingUpgradeFuncs{
 				ContainersFunc:     mockContainersFunc,
 				InitContainersFunc: mockInitContainersFunc,
 				VolumesFunc:        mockVolumesFunc,
+				AnnotationsFunc:    mockAnnotationsFunc,
 				PodAnnotationsFunc: mockPodAnnotationsFunc,
 				PatchTemplatesFunc: mockPatchTemplatesFunc,
 				SupportsPatch:      true,
 			}
 
-			result := invokeDeleteStrategy(funcs, deployment, tt.config, true)
+			result := invokeDeleteStrategy(funcs, deployment, tt.config, true, tt.reloadStrategy)
 
 			assert.NotNil(t, result)
 		})
@@ -340,6 +368,7 @@ func TestRemovePodAnnotations(t *testing.T) {
 				ContainersFunc:     mockContainersFunc,
 				InitContainersFunc: mockInitContainersFunc,
 				VolumesFunc:        mockVolumesFunc,
+				AnnotationsFunc:    mockAnnotationsFunc,
 				PodAnnotationsFunc: mockPodAnnotationsFunc,
 				PatchTemplatesFunc: mockPatchTemplatesFunc,
 				SupportsPatch:      false,
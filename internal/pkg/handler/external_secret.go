@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/internal/pkg/util"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// externalSecretSHAs holds the SHA of the data last synced by each ExternalSecret into its target secret, keyed by
+// namespace/name. External Secrets Operator rewrites the target secret on every refresh, the SHA tells the refreshes
+// that changed its data apart.
+var externalSecretSHAs sync.Map
+
+// ExternalSecretHandler contains an added or updated ExternalSecret
+type ExternalSecretHandler struct {
+	Resource *unstructured.Unstructured
+	// OldResource is nil when the ExternalSecret was added
+	OldResource *unstructured.Unstructured
+	Collectors  metrics.Collectors
+	Recorder    record.EventRecorder
+	EnqueueTime time.Time // Time when this handler was added to the queue
+}
+
+// GetEnqueueTime returns when this handler was enqueued
+func (r ExternalSecretHandler) GetEnqueueTime() time.Time {
+	return r.EnqueueTime
+}
+
+// Handle reloads the workloads of an ExternalSecret once it successfully synced changed data into its target secret
+func (r ExternalSecretHandler) Handle() error {
+	startTime := time.Now()
+	result := "error"
+
+	defer func() {
+		r.Collectors.RecordReconcile(result, time.Since(startTime))
+	}()
+
+	if r.Resource == nil {
+		logrus.Errorf("ExternalSecret handler received nil resource")
+		return nil
+	}
+
+	client, err := kube.GetKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	config, changed, err := syncExternalSecret(client, r.Resource, r.OldResource)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		result = "skipped"
+		r.Collectors.RecordSkipped("no_data_change")
+		return nil
+	}
+
+	// Send a webhook if update
+	if options.WebhookUrl != "" {
+		err := sendUpgradeWebhook(config, options.WebhookUrl)
+		if err == nil {
+			result = "success"
+		}
+		return err
+	}
+	err = doRollingUpgrade(config, r.Collectors, r.Recorder, invokeReloadStrategy)
+	if err == nil {
+		result = "success"
+	}
+	return err
+}
+
+// GetConfig gets configurations containing SHA, annotations, namespace and resource name
+func (r ExternalSecretHandler) GetConfig() (common.Config, string) {
+	var shaValue string
+	if sha, ok := externalSecretSHAs.Load(getResourceKey(r.Resource)); ok {
+		shaValue = sha.(string)
+	}
+	return common.GetExternalSecretConfig(r.Resource, shaValue), shaValue
+}
+
+// ForgetExternalSecret drops the data remembered for a deleted ExternalSecret
+func ForgetExternalSecret(externalSecret *unstructured.Unstructured) {
+	externalSecretSHAs.Delete(getResourceKey(externalSecret))
+}
+
+// syncExternalSecret remembers the data synced by an ExternalSecret and returns whether a new successful sync changed
+// it. The data of ExternalSecrets seen for the first time, i.e. added ones and those that only became Ready now, is
+// only remembered.
+func syncExternalSecret(client kubernetes.Interface, externalSecret, old *unstructured.Unstructured) (common.Config, bool, error) {
+	sha, changed, err := syncReadyResource(&externalSecretSHAs, externalSecret, old, getExternalSecretSyncVersion, func() (string, error) {
+		targetName := getExternalSecretTargetName(externalSecret)
+		secret, err := getSecret(client, externalSecret.GetNamespace(), targetName)
+		if apierrors.IsNotFound(err) {
+			logrus.Warnf("Target secret '%s' of ExternalSecret '%s' not found in namespace '%s'", targetName, externalSecret.GetName(), externalSecret.GetNamespace())
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return util.GetSHAfromSecret(secret.Data), nil
+	})
+	if err != nil || sha == "" {
+		return common.Config{}, false, err
+	}
+	return common.GetExternalSecretConfig(externalSecret, sha), changed, nil
+}
+
+// getExternalSecretSyncVersion identifies a sync of an ExternalSecret, it changes on every refresh and on changes of
+// the ExternalSecret itself
+func getExternalSecretSyncVersion(externalSecret *unstructured.Unstructured) string {
+	refreshTime, _, _ := unstructured.NestedString(externalSecret.Object, "status", "refreshTime")
+	syncedResourceVersion, _, _ := unstructured.NestedString(externalSecret.Object, "status", "syncedResourceVersion")
+	return refreshTime + "/" + syncedResourceVersion
+}
+
+// getExternalSecretTargetName returns the name of the secret an ExternalSecret syncs into, which defaults to the name
+// of the ExternalSecret
+func getExternalSecretTargetName(externalSecret *unstructured.Unstructured) string {
+	if name, _, _ := unstructured.NestedString(externalSecret.Object, "spec", "target", "name"); name != "" {
+		return name
+	}
+	if name, _, _ := unstructured.NestedString(externalSecret.Object, "status", "binding", "name"); name != "" {
+		return name
+	}
+	return externalSecret.GetName()
+}
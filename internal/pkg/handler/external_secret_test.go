@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/options"
+)
+
+func createTestExternalSecret(refreshTime string, ready string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "external-secrets.io/v1",
+		"kind":       "ExternalSecret",
+		"metadata":   map[string]interface{}{"name": "db-credentials", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"target": map[string]interface{}{"name": "db"},
+		},
+		"status": map[string]interface{}{
+			"refreshTime":           refreshTime,
+			"syncedResourceVersion": "1-abc",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": ready, "reason": "SecretSynced"},
+			},
+		},
+	}}
+}
+
+func TestSyncExternalSecret(t *testing.T) {
+	externalSecretSHAs.Clear()
+	defer externalSecretSHAs.Clear()
+
+	fakeClient := testclient.NewClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("a")},
+	})
+	updateSecret := func(password string) {
+		secret, err := fakeClient.CoreV1().Secrets("default").Get(context.TODO(), "db", metav1.GetOptions{})
+		assert.NoError(t, err)
+		secret.Data["password"] = []byte(password)
+		_, err = fakeClient.CoreV1().Secrets("default").Update(context.TODO(), secret, metav1.UpdateOptions{})
+		assert.NoError(t, err)
+	}
+
+	first := createTestExternalSecret("2026-10-18T10:00:00Z", "True")
+	_, changed, err := syncExternalSecret(fakeClient, first, nil)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Added ExternalSecret should only be remembered")
+
+	refreshed := createTestExternalSecret("2026-10-18T11:00:00Z", "True")
+	_, changed, err = syncExternalSecret(fakeClient, refreshed, first)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Refresh without changed data should not reload")
+
+	updateSecret("b")
+	failed := createTestExternalSecret("2026-10-18T12:00:00Z", "False")
+	_, changed, err = syncExternalSecret(fakeClient, failed, refreshed)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Failed sync should not reload")
+
+	synced := createTestExternalSecret("2026-10-18T12:00:00Z", "True")
+	config, changed, err := syncExternalSecret(fakeClient, synced, failed)
+	assert.NoError(t, err)
+	assert.True(t, changed, "Successful sync with changed data should reload")
+	assert.Equal(t, "db-credentials", config.ResourceName)
+	assert.Equal(t, options.ExternalSecretUpdateOnChangeAnnotation, config.Annotation)
+	assert.Equal(t, constants.ExternalSecretEnvVarPostfix, config.Type)
+
+	_, changed, err = syncExternalSecret(fakeClient, synced, synced)
+	assert.NoError(t, err)
+	assert.False(t, changed, "Status update without new sync should not reload")
+}
+
+func TestSyncExternalSecretFirstReady(t *testing.T) {
+	externalSecretSHAs.Clear()
+	defer externalSecretSHAs.Clear()
+
+	fakeClient := testclient.NewClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("a")},
+	})
+
+	pending := createTestExternalSecret("", "False")
+	_, changed, err := syncExternalSecret(fakeClient, pending, nil)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	ready := createTestExternalSecret("2026-10-18T10:00:00Z", "True")
+	_, changed, err = syncExternalSecret(fakeClient, ready, pending)
+	assert.NoError(t, err)
+	assert.False(t, changed, "First successful sync should only be remembered")
+	sha, found := externalSecretSHAs.Load("default/db-credentials")
+	assert.True(t, found)
+	assert.NotEmpty(t, sha)
+}
+
+func TestSyncExternalSecretFromCache(t *testing.T) {
+	externalSecretSHAs.Clear()
+	defer externalSecretSHAs.Clear()
+	defer SetSecretLister(secretLister)
+
+	cached := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("a")},
+	}
+	SetSecretLister(func(namespace, name string) (*v1.Secret, bool) {
+		if namespace == cached.Namespace && name == cached.Name {
+			return cached, true
+		}
+		return nil, false
+	})
+	// The API server has no secrets, so they can only be read from the cache
+	fakeClient := testclient.NewClientset()
+
+	first := createTestExternalSecret("2026-10-18T10:00:00Z", "True")
+	_, changed, err := syncExternalSecret(fakeClient, first, nil)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	cached = cached.DeepCopy()
+	cached.Data["password"] = []byte("b")
+	refreshed := createTestExternalSecret("2026-10-18T11:00:00Z", "True")
+	_, changed, err = syncExternalSecret(fakeClient, refreshed, first)
+	assert.NoError(t, err)
+	assert.True(t, changed, "Changed data of the cached target secret should reload")
+}
+
+func TestGetExternalSecretTargetName(t *testing.T) {
+	externalSecret := createTestExternalSecret("2026-10-18T10:00:00Z", "True")
+	assert.Equal(t, "db", getExternalSecretTargetName(externalSecret))
+
+	unstructured.RemoveNestedField(externalSecret.Object, "spec", "target")
+	assert.Equal(t, "db-credentials", getExternalSecretTargetName(externalSecret))
+}
@@ -0,0 +1,388 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// ResourceGenerationHandler contains a newly created generation of a versioned configmap or secret
+type ResourceGenerationHandler struct {
+	Resource    interface{}
+	Collectors  metrics.Collectors
+	Recorder    record.EventRecorder
+	EnqueueTime time.Time // Time when this handler was added to the queue
+}
+
+// GetEnqueueTime returns when this handler was enqueued
+func (r ResourceGenerationHandler) GetEnqueueTime() time.Time {
+	return r.EnqueueTime
+}
+
+// Handle updates the workloads referencing older generations of the resource to reference the new generation
+func (r ResourceGenerationHandler) Handle() error {
+	startTime := time.Now()
+	result := "error"
+
+	defer func() {
+		r.Collectors.RecordReconcile(result, time.Since(startTime))
+	}()
+
+	generation, ok := getResourceGeneration(r.Resource)
+	if !ok {
+		logrus.Errorf("Resource generation handler received a resource without '%s' label", options.GenerationOfLabel)
+		return nil
+	}
+
+	err := followGeneration(kube.GetClients(), generation, r.Collectors, r.Recorder)
+	if err == nil {
+		result = "success"
+	}
+	return err
+}
+
+// GetConfig gets configurations containing SHA, annotations, namespace and resource name
+func (r ResourceGenerationHandler) GetConfig() (common.Config, string) {
+	return ResourceCreatedHandler{Resource: r.Resource}.GetConfig()
+}
+
+// IsResourceGeneration checks whether a configmap or secret is a generation of a versioned resource
+func IsResourceGeneration(resource interface{}) bool {
+	_, ok := getResourceGeneration(resource)
+	return ok
+}
+
+// resourceGeneration is a generation of a versioned configmap or secret, e.g. "app-config-7fh8k2" of "app-config"
+type resourceGeneration struct {
+	Type              string
+	Namespace         string
+	Name              string
+	BaseName          string
+	CreationTimestamp metav1.Time
+}
+
+// getResourceGeneration returns the generation of a configmap or secret labeled with the generation-of label
+func getResourceGeneration(resource interface{}) (resourceGeneration, bool) {
+	var (
+		object       metav1.Object
+		resourceType string
+	)
+	switch res := resource.(type) {
+	case *v1.ConfigMap:
+		object, resourceType = res, constants.ConfigmapEnvVarPostfix
+	case *v1.Secret:
+		object, resourceType = res, constants.SecretEnvVarPostfix
+	default:
+		return resourceGeneration{}, false
+	}
+
+	baseName := object.GetLabels()[options.GenerationOfLabel]
+	if baseName == "" {
+		return resourceGeneration{}, false
+	}
+	return resourceGeneration{
+		Type:              resourceType,
+		Namespace:         object.GetNamespace(),
+		Name:              object.GetName(),
+		BaseName:          baseName,
+		CreationTimestamp: object.GetCreationTimestamp(),
+	}, true
+}
+
+// getGenerationUpgradeFuncs returns the callback funcs of the reloaded workloads whose references follow new
+// generations. Jobs and pods can't be updated and are left to complete with the generation they started with.
+func getGenerationUpgradeFuncs() []callbacks.RollingUpgradeFuncs {
+	var upgradeFuncs []callbacks.RollingUpgradeFuncs
+	for _, funcs := range getReloadedWorkloadFuncs() {
+		switch funcs.ResourceType {
+		case "Job", "Pod":
+			continue
+		case "CronJob":
+			// The next scheduled job uses the new generation, no job is triggered
+			funcs.UpdateFunc = callbacks.UpdateCronJobTemplate
+		case "Rollout":
+			// The new generation is rolled out even by rollouts restarted on reloads
+			funcs.UpdateFunc = callbacks.UpdateRolloutTemplate
+		}
+		upgradeFuncs = append(upgradeFuncs, funcs)
+	}
+	return upgradeFuncs
+}
+
+// followGeneration updates the workloads referencing older generations of a versioned resource to reference the given
+// generation, which rolls them out, then deletes the older generations no longer referenced if enabled
+func followGeneration(clients kube.Clients, generation resourceGeneration, collectors metrics.Collectors, recorder record.EventRecorder) error {
+	olderGenerations, err := getOlderGenerations(clients, generation)
+	if err != nil {
+		return err
+	}
+	if len(olderGenerations) == 0 {
+		return nil
+	}
+
+	var updateErr error
+	for _, upgradeFuncs := range getGenerationUpgradeFuncs() {
+		for _, item := range upgradeFuncs.ItemsFunc(clients, generation.Namespace) {
+			renamed := renameResourceReferences(upgradeFuncs.ContainersFunc(item), upgradeFuncs.VolumesFunc(item), generation.Type, olderGenerations, generation.Name)
+			renamedInit := renameResourceReferences(upgradeFuncs.InitContainersFunc(item), nil, generation.Type, olderGenerations, generation.Name)
+			if !renamed && !renamedInit {
+				continue
+			}
+			if err := updateGenerationReferences(clients, generation, upgradeFuncs, collectors, recorder, item); err != nil {
+				updateErr = err
+			}
+		}
+	}
+	if updateErr != nil {
+		return updateErr
+	}
+
+	if options.PruneGenerations {
+		return pruneGenerations(clients, generation, olderGenerations)
+	}
+	return nil
+}
+
+// updateGenerationReferences updates a workload whose references were renamed to a new generation
+func updateGenerationReferences(clients kube.Clients, generation resourceGeneration, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, item runtime.Object) error {
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		return err
+	}
+	resourceName := accessor.GetName()
+
+	err = upgradeFuncs.UpdateFunc(clients, generation.Namespace, item)
+	if err != nil {
+		message := fmt.Sprintf("Update of references to '%s' of type '%s' in '%s' of type '%s' in namespace '%s' failed with error %v",
+			generation.Name, generation.Type, resourceName, upgradeFuncs.ResourceType, generation.Namespace, err)
+		logrus.Error(message)
+		collectors.Reloaded.With(prometheus.Labels{"success": "false"}).Inc()
+		collectors.ReloadedByNamespace.With(prometheus.Labels{"success": "false", "namespace": generation.Namespace}).Inc()
+		if recorder != nil {
+			recorder.Event(item, v1.EventTypeWarning, "ReloadFail", message)
+		}
+		return err
+	}
+
+	message := fmt.Sprintf("New generation '%s' of '%s' of type '%s' in namespace '%s', updated references of '%s' of type '%s'",
+		generation.Name, generation.BaseName, generation.Type, generation.Namespace, resourceName, upgradeFuncs.ResourceType)
+	logrus.Info(message)
+	collectors.Reloaded.With(prometheus.Labels{"success": "true"}).Inc()
+	collectors.ReloadedByNamespace.With(prometheus.Labels{"success": "true", "namespace": generation.Namespace}).Inc()
+	if recorder != nil {
+		recorder.Event(item, v1.EventTypeNormal, "Reloaded", message)
+	}
+	return nil
+}
+
+// getOlderGenerations returns the names of the generations of a versioned resource created before the given one.
+// Generations newer than the given one are never returned, so they are neither renamed nor pruned.
+func getOlderGenerations(clients kube.Clients, generation resourceGeneration) ([]string, error) {
+	selector := labels.SelectorFromSet(labels.Set{options.GenerationOfLabel: generation.BaseName}).String()
+	var objects []metav1.Object
+
+	if generation.Type == constants.ConfigmapEnvVarPostfix {
+		configMaps, err := clients.KubernetesClient.CoreV1().ConfigMaps(generation.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for i := range configMaps.Items {
+			objects = append(objects, &configMaps.Items[i])
+		}
+	} else {
+		secrets, err := clients.KubernetesClient.CoreV1().Secrets(generation.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for i := range secrets.Items {
+			objects = append(objects, &secrets.Items[i])
+		}
+	}
+
+	var names []string
+	for _, object := range objects {
+		if isOlderGeneration(object, generation) {
+			names = append(names, object.GetName())
+		}
+	}
+	return names, nil
+}
+
+// isOlderGeneration checks whether an object was created before the given generation. Creation timestamps only have
+// a precision of seconds, generations created in the same second are ordered by name so that only one of them is
+// the newest.
+func isOlderGeneration(object metav1.Object, generation resourceGeneration) bool {
+	created := object.GetCreationTimestamp()
+	if !created.Equal(&generation.CreationTimestamp) {
+		return created.Before(&generation.CreationTimestamp)
+	}
+	return object.GetName() < generation.Name
+}
+
+// renameResourceReferences replaces the references of containers and volumes to the configmaps or secrets with the
+// given old names by the new name. It returns whether any reference was renamed.
+func renameResourceReferences(containers []v1.Container, volumes []v1.Volume, resourceType string, oldNames []string, newName string) bool {
+	renamed := false
+	rename := func(name *string) {
+		if slices.Contains(oldNames, *name) {
+			*name = newName
+			renamed = true
+		}
+	}
+
+	for i := range containers {
+		for j := range containers[i].Env {
+			valueFrom := containers[i].Env[j].ValueFrom
+			switch {
+			case valueFrom == nil:
+			case resourceType == constants.ConfigmapEnvVarPostfix && valueFrom.ConfigMapKeyRef != nil:
+				rename(&valueFrom.ConfigMapKeyRef.Name)
+			case resourceType == constants.SecretEnvVarPostfix && valueFrom.SecretKeyRef != nil:
+				rename(&valueFrom.SecretKeyRef.Name)
+			}
+		}
+		for j := range containers[i].EnvFrom {
+			envFrom := &containers[i].EnvFrom[j]
+			switch {
+			case resourceType == constants.ConfigmapEnvVarPostfix && envFrom.ConfigMapRef != nil:
+				rename(&envFrom.ConfigMapRef.Name)
+			case resourceType == constants.SecretEnvVarPostfix && envFrom.SecretRef != nil:
+				rename(&envFrom.SecretRef.Name)
+			}
+		}
+	}
+
+	for i := range volumes {
+		switch {
+		case resourceType == constants.ConfigmapEnvVarPostfix && volumes[i].ConfigMap != nil:
+			rename(&volumes[i].ConfigMap.Name)
+		case resourceType == constants.SecretEnvVarPostfix && volumes[i].Secret != nil:
+			rename(&volumes[i].Secret.SecretName)
+		case volumes[i].Projected != nil:
+			for j := range volumes[i].Projected.Sources {
+				source := &volumes[i].Projected.Sources[j]
+				if resourceType == constants.ConfigmapEnvVarPostfix && source.ConfigMap != nil {
+					rename(&source.ConfigMap.Name)
+				} else if resourceType == constants.SecretEnvVarPostfix && source.Secret != nil {
+					rename(&source.Secret.Name)
+				}
+			}
+		}
+	}
+	return renamed
+}
+
+// pruneGenerations deletes the given older generations of a versioned resource that no pod template, pod or
+// ServiceAccount references anymore. Generations still used by pods being replaced, or by the ReplicaSets kept for
+// rollbacks, are deleted with a later generation.
+func pruneGenerations(clients kube.Clients, generation resourceGeneration, olderGenerations []string) error {
+	podSpecs, err := listPodSpecs(clients, generation.Namespace)
+	if err != nil {
+		return err
+	}
+	var serviceAccounts []v1.ServiceAccount
+	if generation.Type == constants.SecretEnvVarPostfix {
+		list, err := clients.KubernetesClient.CoreV1().ServiceAccounts(generation.Namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		serviceAccounts = list.Items
+	}
+
+	for _, name := range olderGenerations {
+		if slices.ContainsFunc(podSpecs, func(spec *v1.PodSpec) bool { return isReferencedByPodSpec(spec, generation.Type, name) }) ||
+			slices.ContainsFunc(serviceAccounts, func(serviceAccount v1.ServiceAccount) bool {
+				return isReferencedByServiceAccount(&serviceAccount, name)
+			}) {
+			continue
+		}
+
+		if generation.Type == constants.ConfigmapEnvVarPostfix {
+			err = clients.KubernetesClient.CoreV1().ConfigMaps(generation.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		} else {
+			err = clients.KubernetesClient.CoreV1().Secrets(generation.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			logrus.Errorf("Failed to delete generation '%s' of '%s' of type '%s' in namespace '%s': %v", name, generation.BaseName, generation.Type, generation.Namespace, err)
+			continue
+		}
+		logrus.Infof("Deleted unreferenced generation '%s' of '%s' of type '%s' in namespace '%s'", name, generation.BaseName, generation.Type, generation.Namespace)
+	}
+	return nil
+}
+
+// listPodSpecs returns the pod templates of the reloaded workloads, and the pod specs of all replicaSets, jobs and pods
+// in a namespace
+func listPodSpecs(clients kube.Clients, namespace string) ([]*v1.PodSpec, error) {
+	var podSpecs []*v1.PodSpec
+	ctx := context.TODO()
+
+	for _, funcs := range getReloadedWorkloadFuncs() {
+		switch funcs.ResourceType {
+		case "ReplicaSet", "Job", "Pod":
+			// Listed below, including the ones managed by controllers
+			continue
+		}
+		for _, item := range funcs.ItemsFunc(clients, namespace) {
+			if spec := funcs.PodSpecFunc(item); spec != nil {
+				podSpecs = append(podSpecs, spec)
+			}
+		}
+	}
+
+	replicaSets, err := clients.KubernetesClient.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range replicaSets.Items {
+		podSpecs = append(podSpecs, &replicaSets.Items[i].Spec.Template.Spec)
+	}
+	jobs, err := clients.KubernetesClient.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		podSpecs = append(podSpecs, &jobs.Items[i].Spec.Template.Spec)
+	}
+	pods, err := clients.KubernetesClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		podSpecs = append(podSpecs, &pods.Items[i].Spec)
+	}
+	return podSpecs, nil
+}
+
+// isReferencedByPodSpec checks whether a pod spec references the configmap or secret with the given name
+func isReferencedByPodSpec(spec *v1.PodSpec, resourceType string, name string) bool {
+	return getVolumeMountName(spec.Volumes, resourceType, name) != "" ||
+		getContainerWithEnvReference(spec.Containers, name, resourceType) != nil ||
+		getContainerWithEnvReference(spec.InitContainers, name, resourceType) != nil ||
+		resourceType == constants.SecretEnvVarPostfix && containsLocalObjectReference(spec.ImagePullSecrets, name)
+}
+
+// isReferencedByServiceAccount checks whether a ServiceAccount lists the secret with the given name as image pull
+// secret or secret
+func isReferencedByServiceAccount(serviceAccount *v1.ServiceAccount, name string) bool {
+	return containsLocalObjectReference(serviceAccount.ImagePullSecrets, name) ||
+		slices.ContainsFunc(serviceAccount.Secrets, func(secret v1.ObjectReference) bool { return secret.Name == name })
+}
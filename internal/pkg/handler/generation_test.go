@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	fakeargoclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	app "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func createTestGeneration(name string, created time.Time) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            map[string]string{options.GenerationOfLabel: "app-config"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+	}
+}
+
+func createTestPodSpec(configMapName string) v1.PodSpec {
+	return v1.PodSpec{
+		Containers: []v1.Container{
+			{
+				Name: "app",
+				EnvFrom: []v1.EnvFromSource{
+					{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: configMapName}}},
+				},
+			},
+		},
+		Volumes: []v1.Volume{
+			{
+				Name: "config",
+				VolumeSource: v1.VolumeSource{
+					ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: configMapName}},
+				},
+			},
+		},
+	}
+}
+
+func TestRenameResourceReferences(t *testing.T) {
+	spec := createTestPodSpec("app-config-old")
+	spec.Containers[0].Env = []v1.EnvVar{
+		{Name: "A", ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "app-config-old"}, Key: "a"}}},
+		{Name: "B", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "app-config-old"}, Key: "b"}}},
+		{Name: "C", Value: "c"},
+	}
+	spec.Volumes = append(spec.Volumes, v1.Volume{
+		Name: "projected",
+		VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{
+			{ConfigMap: &v1.ConfigMapProjection{LocalObjectReference: v1.LocalObjectReference{Name: "app-config-old"}}},
+			{ConfigMap: &v1.ConfigMapProjection{LocalObjectReference: v1.LocalObjectReference{Name: "other"}}},
+		}}},
+	})
+
+	renamed := renameResourceReferences(spec.Containers, spec.Volumes, constants.ConfigmapEnvVarPostfix, []string{"app-config-old"}, "app-config-new")
+
+	assert.True(t, renamed)
+	assert.Equal(t, "app-config-new", spec.Containers[0].EnvFrom[0].ConfigMapRef.Name)
+	assert.Equal(t, "app-config-new", spec.Containers[0].Env[0].ValueFrom.ConfigMapKeyRef.Name)
+	assert.Equal(t, "app-config-old", spec.Containers[0].Env[1].ValueFrom.SecretKeyRef.Name, "Secret with the same name should not be renamed")
+	assert.Equal(t, "app-config-new", spec.Volumes[0].ConfigMap.Name)
+	assert.Equal(t, "app-config-new", spec.Volumes[1].Projected.Sources[0].ConfigMap.Name)
+	assert.Equal(t, "other", spec.Volumes[1].Projected.Sources[1].ConfigMap.Name)
+
+	assert.False(t, renameResourceReferences(spec.Containers, spec.Volumes, constants.ConfigmapEnvVarPostfix, []string{"app-config-old"}, "app-config-new"))
+}
+
+func TestIsOlderGeneration(t *testing.T) {
+	now := metav1.Now().Rfc3339Copy()
+	generation, _ := getResourceGeneration(createTestGeneration("app-config-b", now.Time))
+
+	tests := []struct {
+		name     string
+		object   *v1.ConfigMap
+		expected bool
+	}{
+		{name: "Created before", object: createTestGeneration("app-config-c", now.Add(-time.Second)), expected: true},
+		{name: "Created after", object: createTestGeneration("app-config-a", now.Add(time.Second))},
+		{name: "Same generation", object: createTestGeneration("app-config-b", now.Time)},
+		{name: "Same second with a lower name", object: createTestGeneration("app-config-a", now.Time), expected: true},
+		{name: "Same second with a higher name", object: createTestGeneration("app-config-c", now.Time)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isOlderGeneration(tt.object, generation))
+		})
+	}
+
+	// Of two generations created in the same second, exactly one is older than the other
+	other, _ := getResourceGeneration(createTestGeneration("app-config-a", now.Time))
+	assert.NotEqual(t, isOlderGeneration(createTestGeneration("app-config-a", now.Time), generation),
+		isOlderGeneration(createTestGeneration("app-config-b", now.Time), other))
+}
+
+func TestFollowGeneration(t *testing.T) {
+	defer func(prune bool) { options.PruneGenerations = prune }(options.PruneGenerations)
+	now := time.Now()
+
+	tests := []struct {
+		name            string
+		prune           bool
+		runningPod      bool
+		expectedDeleted bool
+	}{
+		{name: "Older generation kept"},
+		{name: "Unreferenced older generation pruned", prune: true, expectedDeleted: true},
+		{name: "Older generation used by a pod kept", prune: true, runningPod: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options.PruneGenerations = tt.prune
+			newGeneration := createTestGeneration("app-config-b", now)
+			objects := []runtime.Object{
+				createTestGeneration("app-config-a", now.Add(-time.Hour)),
+				newGeneration,
+				createTestGeneration("app-config-c", now.Add(time.Hour)),
+				&app.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+					Spec:       app.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: createTestPodSpec("app-config-a")}},
+				},
+				&app.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+					Spec:       app.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: createTestPodSpec("api-config")}},
+				},
+			}
+			if tt.runningPod {
+				objects = append(objects, &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+					Spec:       createTestPodSpec("app-config-a"),
+				})
+			}
+			fakeClient := testclient.NewClientset(objects...)
+			generation, ok := getResourceGeneration(newGeneration)
+			assert.True(t, ok)
+
+			err := followGeneration(kube.Clients{KubernetesClient: fakeClient}, generation, metrics.NewCollectors(), nil)
+			assert.NoError(t, err)
+
+			web, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "app-config-b", web.Spec.Template.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Name)
+			assert.Equal(t, "app-config-b", web.Spec.Template.Spec.Volumes[0].ConfigMap.Name)
+			api, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), "api", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "api-config", api.Spec.Template.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Name)
+
+			_, err = fakeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "app-config-a", metav1.GetOptions{})
+			assert.Equal(t, tt.expectedDeleted, err != nil)
+			_, err = fakeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "app-config-c", metav1.GetOptions{})
+			assert.NoError(t, err, "Newer generation should never be pruned")
+		})
+	}
+}
+
+func TestFollowGeneration_Rollout(t *testing.T) {
+	defer func(isArgoRollouts string) { options.IsArgoRollouts = isArgoRollouts }(options.IsArgoRollouts)
+	options.IsArgoRollouts = "true"
+	now := time.Now()
+
+	newGeneration := createTestGeneration("app-config-b", now)
+	fakeClient := testclient.NewClientset(createTestGeneration("app-config-a", now.Add(-time.Hour)), newGeneration)
+	fakeArgoClient := fakeargoclientset.NewSimpleClientset(&argorolloutv1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{options.RolloutStrategyAnnotation: "restart"},
+		},
+		Spec: argorolloutv1alpha1.RolloutSpec{Template: v1.PodTemplateSpec{Spec: createTestPodSpec("app-config-a")}},
+	})
+	generation, _ := getResourceGeneration(newGeneration)
+
+	err := followGeneration(kube.Clients{KubernetesClient: fakeClient, ArgoRolloutClient: fakeArgoClient}, generation, metrics.NewCollectors(), nil)
+	assert.NoError(t, err)
+
+	rollout, err := fakeArgoClient.ArgoprojV1alpha1().Rollouts("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "app-config-b", rollout.Spec.Template.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Name)
+	assert.Nil(t, rollout.Spec.RestartAt, "The template of a restarted rollout should be updated instead")
+}
+
+func TestPruneGenerations_SecretCredentials(t *testing.T) {
+	createSecretGeneration := func(name string) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{options.GenerationOfLabel: "registry"}}}
+	}
+	fakeClient := testclient.NewClientset(
+		createSecretGeneration("registry-a"),
+		createSecretGeneration("registry-b"),
+		createSecretGeneration("registry-c"),
+		&app.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: app.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+				ImagePullSecrets: []v1.LocalObjectReference{{Name: "registry-a"}},
+			}}},
+		},
+		&v1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: "default"},
+			Secrets:    []v1.ObjectReference{{Name: "registry-b"}},
+		},
+	)
+	generation := resourceGeneration{Type: constants.SecretEnvVarPostfix, Namespace: "default", Name: "registry-d", BaseName: "registry"}
+
+	err := pruneGenerations(kube.Clients{KubernetesClient: fakeClient}, generation, []string{"registry-a", "registry-b", "registry-c"})
+	assert.NoError(t, err)
+
+	for name, expectedDeleted := range map[string]bool{"registry-a": false, "registry-b": false, "registry-c": true} {
+		_, err := fakeClient.CoreV1().Secrets("default").Get(context.TODO(), name, metav1.GetOptions{})
+		assert.Equal(t, expectedDeleted, err != nil, name)
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	alert "github.com/stakater/Reloader/internal/pkg/alerts"
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+const (
+	// fluxHelmReleaseNameLabel and fluxHelmReleaseNamespaceLabel are set by Flux helm-controller on the resources of a release
+	fluxHelmReleaseNameLabel      = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNamespaceLabel = "helm.toolkit.fluxcd.io/namespace"
+	// argoCDTrackingIDAnnotation is set by Argo CD on the resources of an application with annotation based tracking,
+	// e.g. "my-app:apps/Deployment:default/web"
+	argoCDTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+	// argoCDInstanceLabel is the label commonly configured for label based tracking in Argo CD
+	argoCDInstanceLabel = "argocd.argoproj.io/instance"
+)
+
+// gitOpsOwner is the Flux HelmRelease or Argo CD Application a workload is deployed by
+type gitOpsOwner struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// getGitOpsOwner returns the HelmRelease or Argo CD Application a workload is deployed by, detected from the labels
+// and annotations set by Flux and Argo CD
+func getGitOpsOwner(labels, annotations map[string]string) (gitOpsOwner, bool) {
+	if name, namespace := labels[fluxHelmReleaseNameLabel], labels[fluxHelmReleaseNamespaceLabel]; name != "" && namespace != "" {
+		return gitOpsOwner{Kind: "HelmRelease", Namespace: namespace, Name: name}, true
+	}
+
+	application := labels[argoCDInstanceLabel]
+	if trackingID := annotations[argoCDTrackingIDAnnotation]; trackingID != "" {
+		application, _, _ = strings.Cut(trackingID, ":")
+	}
+	if application == "" {
+		return gitOpsOwner{}, false
+	}
+	// Applications outside of the Argo CD namespace are tracked as "<namespace>_<name>"
+	namespace, name, found := strings.Cut(application, "_")
+	if !found {
+		namespace, name = options.ArgoCDNamespace, application
+	}
+	return gitOpsOwner{Kind: "Application", Namespace: namespace, Name: name}, true
+}
+
+// isGitOpsSyncEnabled checks whether a workload is reloaded through its GitOps owner, which the gitops-sync annotation
+// on the workload or its pod template overrides
+func isGitOpsSyncEnabled(upgradeFuncs callbacks.RollingUpgradeFuncs, resourceName, namespace string, annotations, podAnnotations map[string]string) bool {
+	value, found := getWorkloadAnnotation(annotations, podAnnotations, options.GitOpsSyncAnnotation)
+	if !found {
+		return options.GitOpsSync
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		logrus.Warnf("Invalid value '%s' of annotation '%s' on %s '%s' in namespace '%s', using %t", value, options.GitOpsSyncAnnotation, upgradeFuncs.ResourceType, resourceName, namespace, options.GitOpsSync)
+		return options.GitOpsSync
+	}
+	return enabled
+}
+
+// syncGitOpsOwner passes the hash of the changed resource to the HelmRelease or Argo CD Application a workload is
+// deployed by and triggers its reconciliation, instead of patching the workload which the GitOps tool would revert
+func syncGitOpsOwner(clients kube.Clients, config common.Config, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, resource runtime.Object, owner gitOpsOwner, actionStartTime time.Time) (bool, error) {
+	accessor, err := meta.Accessor(resource)
+	if err != nil {
+		return false, err
+	}
+	resourceName := accessor.GetName()
+	valueName := getEnvVarName(config.ResourceName, config.Type)
+
+	if options.GitOpsValuesKey == "" {
+		// A reconcile without the new hash renders the same manifests, which would be reported as a reload without one
+		err = errors.New("no gitops-values-key to pass the hash under")
+	} else if owner.Kind == "HelmRelease" {
+		err = callbacks.RequestHelmReleaseReconcile(clients, owner.Namespace, owner.Name, options.GitOpsValuesKey, valueName, config.SHAValue)
+	} else {
+		err = callbacks.SyncArgoCDApplication(clients, owner.Namespace, owner.Name, options.GitOpsValuesKey, valueName, config.SHAValue)
+	}
+
+	actionLatency := time.Since(actionStartTime)
+
+	if err != nil {
+		message := fmt.Sprintf("Sync of %s '%s' in namespace '%s' deploying '%s' of type '%s' failed with error %v", owner.Kind, owner.Name, owner.Namespace, resourceName, upgradeFuncs.ResourceType, err)
+		logrus.Error(message)
+
+		collectors.Reloaded.With(prometheus.Labels{"success": "false"}).Inc()
+		collectors.ReloadedByNamespace.With(prometheus.Labels{"success": "false", "namespace": config.Namespace}).Inc()
+		collectors.RecordAction(upgradeFuncs.ResourceType, "error", actionLatency)
+		if recorder != nil {
+			recorder.Event(resource, v1.EventTypeWarning, "ReloadFail", message)
+		}
+		return true, err
+	}
+
+	message := fmt.Sprintf("Changes detected in '%s' of type '%s' in namespace '%s', synced %s '%s' in namespace '%s' deploying '%s' of type '%s'",
+		config.ResourceName, config.Type, config.Namespace, owner.Kind, owner.Name, owner.Namespace, resourceName, upgradeFuncs.ResourceType)
+	logrus.Info(message)
+
+	collectors.Reloaded.With(prometheus.Labels{"success": "true"}).Inc()
+	collectors.ReloadedByNamespace.With(prometheus.Labels{"success": "true", "namespace": config.Namespace}).Inc()
+	collectors.RecordAction(upgradeFuncs.ResourceType, "success", actionLatency)
+	if recorder != nil {
+		recorder.Event(resource, v1.EventTypeNormal, "Reloaded", message)
+	}
+	if options.AlertOnReload {
+		alert.SendWebhookAlert(fmt.Sprintf(
+			"Reloader detected changes in *%s* of type *%s* in namespace *%s*. Hence synced *%s* *%s* in namespace *%s*",
+			config.ResourceName, config.Type, config.Namespace, owner.Kind, owner.Name, owner.Namespace))
+	}
+	return true, nil
+}
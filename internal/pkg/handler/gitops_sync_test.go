@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	app "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func TestGetGitOpsOwner(t *testing.T) {
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		annotations   map[string]string
+		expectedOwner gitOpsOwner
+		expectedFound bool
+	}{
+		{
+			name:          "Flux HelmRelease",
+			labels:        map[string]string{fluxHelmReleaseNameLabel: "web", fluxHelmReleaseNamespaceLabel: "flux-apps"},
+			expectedOwner: gitOpsOwner{Kind: "HelmRelease", Namespace: "flux-apps", Name: "web"},
+			expectedFound: true,
+		},
+		{
+			name:          "Argo CD tracking annotation",
+			annotations:   map[string]string{argoCDTrackingIDAnnotation: "web:apps/Deployment:default/web"},
+			expectedOwner: gitOpsOwner{Kind: "Application", Namespace: "argocd", Name: "web"},
+			expectedFound: true,
+		},
+		{
+			name:          "Argo CD application in any namespace",
+			annotations:   map[string]string{argoCDTrackingIDAnnotation: "team-a_web:apps/Deployment:default/web"},
+			expectedOwner: gitOpsOwner{Kind: "Application", Namespace: "team-a", Name: "web"},
+			expectedFound: true,
+		},
+		{
+			name:          "Argo CD instance label",
+			labels:        map[string]string{argoCDInstanceLabel: "web"},
+			expectedOwner: gitOpsOwner{Kind: "Application", Namespace: "argocd", Name: "web"},
+			expectedFound: true,
+		},
+		{
+			name:   "Flux label without namespace",
+			labels: map[string]string{fluxHelmReleaseNameLabel: "web"},
+		},
+		{
+			name:   "Not deployed by GitOps",
+			labels: map[string]string{"app.kubernetes.io/instance": "web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, found := getGitOpsOwner(tt.labels, tt.annotations)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expectedOwner, owner)
+		})
+	}
+}
+
+func TestIsGitOpsSyncEnabled(t *testing.T) {
+	defer func(gitOpsSync bool) { options.GitOpsSync = gitOpsSync }(options.GitOpsSync)
+
+	tests := []struct {
+		name        string
+		flag        bool
+		annotations map[string]string
+		expected    bool
+	}{
+		{name: "Disabled by default"},
+		{name: "Enabled by flag", flag: true, expected: true},
+		{name: "Enabled by annotation", annotations: map[string]string{options.GitOpsSyncAnnotation: "true"}, expected: true},
+		{name: "Disabled by annotation", flag: true, annotations: map[string]string{options.GitOpsSyncAnnotation: "false"}},
+		{name: "Invalid annotation uses flag", flag: true, annotations: map[string]string{options.GitOpsSyncAnnotation: "yes please"}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options.GitOpsSync = tt.flag
+			assert.Equal(t, tt.expected, isGitOpsSyncEnabled(GetDeploymentRollingUpgradeFuncs(), "web", "default", tt.annotations, nil))
+		})
+	}
+}
+
+func TestUpgradeResourceGitOpsSync(t *testing.T) {
+	defer func(gitOpsSync bool) { options.GitOpsSync = gitOpsSync }(options.GitOpsSync)
+	options.GitOpsSync = true
+
+	deployment := &app.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Labels:      map[string]string{fluxHelmReleaseNameLabel: "web", fluxHelmReleaseNamespaceLabel: "default"},
+			Annotations: map[string]string{options.ConfigmapUpdateOnChangeAnnotation: "web-config"},
+		},
+		Spec: app.DeploymentSpec{
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+		},
+	}
+	helmRelease := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "helm.toolkit.fluxcd.io/v2",
+		"kind":       "HelmRelease",
+		"metadata":   map[string]any{"name": "web", "namespace": "default"},
+		"spec":       map[string]any{"values": map[string]any{"replicas": int64(2)}},
+	}}
+	fakeClient := testclient.NewClientset(deployment)
+	clients := kube.Clients{
+		KubernetesClient: fakeClient,
+		DynamicClient:    fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), helmRelease),
+	}
+	config := common.Config{
+		ResourceName: "web-config",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    "default",
+		Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+	}
+
+	updated, err := upgradeResource(clients, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment, false)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	result, err := clients.DynamicClient.Resource(callbacks.HelmReleaseResource).Namespace("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	values, _, _ := unstructured.NestedMap(result.Object, "spec", "values")
+	assert.Equal(t, int64(2), values["replicas"], "Existing values should be kept")
+	hash, _, _ := unstructured.NestedString(values, options.GitOpsValuesKey, getEnvVarName("web-config", constants.ConfigmapEnvVarPostfix))
+	assert.Equal(t, "sha256:abc123", hash)
+	assert.NotEmpty(t, result.GetAnnotations()[callbacks.FluxReconcileRequestAnnotation])
+
+	unchanged, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, unchanged.Spec.Template.Spec.Containers[0].Env, "Deployment managed by GitOps should not be patched")
+}
+
+func TestUpgradeResourceGitOpsSyncWithoutValuesKey(t *testing.T) {
+	defer func(gitOpsSync bool, valuesKey string) {
+		options.GitOpsSync, options.GitOpsValuesKey = gitOpsSync, valuesKey
+	}(options.GitOpsSync, options.GitOpsValuesKey)
+	options.GitOpsSync, options.GitOpsValuesKey = true, ""
+
+	deployment := &app.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Labels:      map[string]string{fluxHelmReleaseNameLabel: "web", fluxHelmReleaseNamespaceLabel: "default"},
+			Annotations: map[string]string{options.ConfigmapUpdateOnChangeAnnotation: "web-config"},
+		},
+		Spec: app.DeploymentSpec{
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+		},
+	}
+	helmRelease := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "helm.toolkit.fluxcd.io/v2",
+		"kind":       "HelmRelease",
+		"metadata":   map[string]any{"name": "web", "namespace": "default"},
+	}}
+	clients := kube.Clients{
+		KubernetesClient: testclient.NewClientset(deployment),
+		DynamicClient:    fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), helmRelease),
+	}
+	config := common.Config{
+		ResourceName: "web-config",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    "default",
+		Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+	}
+	collectors := metrics.NewCollectors()
+
+	updated, err := upgradeResource(clients, config, GetDeploymentRollingUpgradeFuncs(), collectors, nil, invokeReloadStrategy, deployment, false)
+	assert.Error(t, err, "A reconcile without the hash should not be reported as a reload")
+	assert.True(t, updated)
+	assert.Equal(t, float64(1), testutil.ToFloat64(collectors.Reloaded.With(prometheus.Labels{"success": "false"})))
+
+	result, err := clients.DynamicClient.Resource(callbacks.HelmReleaseResource).Namespace("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, result.GetAnnotations()[callbacks.FluxReconcileRequestAnnotation], "HelmRelease should not be reconciled")
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// httpReloadClient calls the reload endpoints of pods
+var httpReloadClient = &http.Client{Timeout: 10 * time.Second}
+
+// httpReloadEndpoint is the reload endpoint of the pods of a workload, defined by annotation as "[METHOD] :PORT[/PATH]"
+type httpReloadEndpoint struct {
+	method string
+	port   string
+	path   string
+}
+
+func (e httpReloadEndpoint) String() string {
+	return fmt.Sprintf("%s :%s%s", e.method, e.port, e.path)
+}
+
+// url returns the URL of the endpoint on a pod
+func (e httpReloadEndpoint) url(podIP string) string {
+	return "http://" + net.JoinHostPort(podIP, e.port) + e.path
+}
+
+// parseHTTPReloadEndpoint parses an endpoint like "POST :9090/-/reload". The method defaults to POST and the path to "/".
+func parseHTTPReloadEndpoint(value string) (httpReloadEndpoint, error) {
+	endpoint := httpReloadEndpoint{method: http.MethodPost, path: "/"}
+
+	fields := strings.Fields(value)
+	switch len(fields) {
+	case 1:
+	case 2:
+		endpoint.method = strings.ToUpper(fields[0])
+		if !slices.Contains([]string{http.MethodGet, http.MethodPost, http.MethodPut}, endpoint.method) {
+			return httpReloadEndpoint{}, fmt.Errorf("unsupported method '%s'", fields[0])
+		}
+		fields = fields[1:]
+	default:
+		return httpReloadEndpoint{}, fmt.Errorf("expected '[METHOD] :PORT[/PATH]' but got '%s'", value)
+	}
+
+	address, found := strings.CutPrefix(fields[0], ":")
+	if !found {
+		return httpReloadEndpoint{}, fmt.Errorf("expected '[METHOD] :PORT[/PATH]' but got '%s'", value)
+	}
+	port, path, found := strings.Cut(address, "/")
+	if found {
+		endpoint.path = "/" + path
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 1 || number > 65535 {
+		return httpReloadEndpoint{}, fmt.Errorf("invalid port '%s'", port)
+	}
+	endpoint.port = port
+	return endpoint, nil
+}
+
+// getHTTPReload returns the in-place reload calling the reload endpoint of the pods, defined by annotation on the
+// workload or its pod template. Only ready pods serve the endpoint, and a workload whose pods failed to reload is rolled.
+// Pods are called once their mounted files were updated, or after the delay set by annotation for images without cat.
+func getHTTPReload(annotations, podAnnotations map[string]string) (inPlaceReload, error) {
+	value, found := getWorkloadAnnotation(annotations, podAnnotations, options.HTTPReloadAnnotation)
+	if !found {
+		return inPlaceReload{}, fmt.Errorf("annotation '%s' is missing", options.HTTPReloadAnnotation)
+	}
+	endpoint, err := parseHTTPReloadEndpoint(value)
+	if err != nil {
+		return inPlaceReload{}, fmt.Errorf("invalid value of annotation '%s': %w", options.HTTPReloadAnnotation, err)
+	}
+
+	var delay time.Duration
+	if value, found := getWorkloadAnnotation(annotations, podAnnotations, options.HTTPReloadDelayAnnotation); found {
+		delay, err = time.ParseDuration(value)
+		if err != nil || delay <= 0 {
+			return inPlaceReload{}, fmt.Errorf("invalid value of annotation '%s': expected a positive duration but got '%s'", options.HTTPReloadDelayAnnotation, value)
+		}
+	}
+
+	return inPlaceReload{
+		description:       fmt.Sprintf("call '%s'", endpoint),
+		successReason:     "HTTPReloaded",
+		failureReason:     "HTTPReloadFailed",
+		readyPodsOnly:     true,
+		rollOnFailure:     true,
+		filesUpdatedDelay: delay,
+		reloadFunc: func(ctx context.Context, _ kube.Clients, target reloadTarget) error {
+			return callHTTPReloadEndpoint(ctx, endpoint, target.pod.Status.PodIP)
+		},
+	}, nil
+}
+
+// callHTTPReloadEndpoint calls the reload endpoint on a pod and expects a successful status code
+func callHTTPReloadEndpoint(ctx context.Context, endpoint httpReloadEndpoint, podIP string) error {
+	if podIP == "" {
+		return errors.New("pod has no IP")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, endpoint.method, endpoint.url(podIP), nil)
+	if err != nil {
+		return err
+	}
+	response, err := httpReloadClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s returned status %s", endpoint.url(podIP), response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/crypto"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// reloadServer is a reload endpoint recording the requests it received
+type reloadServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []string
+}
+
+func newReloadServer(t *testing.T, status int) *reloadServer {
+	server := &reloadServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		server.requests = append(server.requests, r.Method+" "+r.URL.Path)
+		server.mutex.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *reloadServer) port() string {
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	return port
+}
+
+func (s *reloadServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func TestParseHTTPReloadEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected httpReloadEndpoint
+		wantErr  bool
+	}{
+		{
+			name:     "Method, port and path",
+			value:    "POST :9090/-/reload",
+			expected: httpReloadEndpoint{method: "POST", port: "9090", path: "/-/reload"},
+		},
+		{
+			name:     "Default method",
+			value:    ":9093/-/reload",
+			expected: httpReloadEndpoint{method: "POST", port: "9093", path: "/-/reload"},
+		},
+		{
+			name:     "Default path",
+			value:    "get :8686",
+			expected: httpReloadEndpoint{method: "GET", port: "8686", path: "/"},
+		},
+		{
+			name:    "Unsupported method",
+			value:   "DELETE :9090/-/reload",
+			wantErr: true,
+		},
+		{
+			name:    "Missing port",
+			value:   "POST /-/reload",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid port",
+			value:   "POST :http/-/reload",
+			wantErr: true,
+		},
+		{
+			name:    "Port out of range",
+			value:   ":70000",
+			wantErr: true,
+		},
+		{
+			name:    "Too many fields",
+			value:   "POST :9090 /-/reload",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := parseHTTPReloadEndpoint(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, endpoint)
+		})
+	}
+}
+
+func TestGetReloadStrategyHTTPReloadAnnotation(t *testing.T) {
+	annotations := map[string]string{options.HTTPReloadAnnotation: "POST :9090/-/reload"}
+	assert.Equal(t, constants.HTTPReloadStrategy, getReloadStrategy(GetDeploymentRollingUpgradeFuncs(), "app", "default", annotations, nil, ""))
+	assert.Equal(t, constants.HTTPReloadStrategy, getReloadStrategy(GetDeploymentRollingUpgradeFuncs(), "app", "default", nil, annotations, ""))
+
+	annotations[options.ReloadStrategyAnnotation] = constants.AnnotationsReloadStrategy
+	assert.Equal(t, constants.AnnotationsReloadStrategy, getReloadStrategy(GetDeploymentRollingUpgradeFuncs(), "app", "default", annotations, nil, ""),
+		"Reload strategy annotation should take precedence over the HTTP reload endpoint")
+}
+
+func TestHTTPReloadPods(t *testing.T) {
+	fake := useFakeExec(t)
+	server := newReloadServer(t, http.StatusOK)
+
+	reload, err := getHTTPReload(map[string]string{options.HTTPReloadAnnotation: "POST :" + server.port() + "/-/reload"}, nil)
+	assert.NoError(t, err)
+
+	pod := createReloadTestPod("pod", v1.Volume{}, v1.VolumeMount{})
+	pod.Status.PodIP = "127.0.0.1"
+	noIP := createReloadTestPod("no-ip", v1.Volume{}, v1.VolumeMount{})
+	targets := []reloadTarget{
+		{pod: &pod, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")},
+		{pod: &noIP, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")},
+	}
+	fake.setContent("pod", "new")
+	fake.setContent("no-ip", "new")
+	recorder := record.NewFakeRecorder(10)
+
+	succeeded := reloadPods(context.Background(), kube.Clients{}, recorder, targets, reload, time.Minute)
+
+	assert.False(t, succeeded, "Reload should fail for pods without IP")
+	assert.Equal(t, []string{"POST /-/reload"}, server.received())
+	events := []string{<-recorder.Events, <-recorder.Events}
+	assert.Contains(t, strings.Join(events, "\n"), "Normal HTTPReloaded")
+	assert.Contains(t, strings.Join(events, "\n"), "Warning HTTPReloadFailed")
+}
+
+func TestUpgradeResourceHTTPStrategy(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		expectedRolling bool
+	}{
+		{
+			name:   "Reloaded in place",
+			status: http.StatusOK,
+		},
+		{
+			name:            "Rolled after failed reload",
+			status:          http.StatusInternalServerError,
+			expectedRolling: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeExec(t)
+			server := newReloadServer(t, tt.status)
+
+			configmap := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-configmap", Namespace: "default"},
+				Data:       map[string]string{"app.conf": "new"},
+			}
+			volume := v1.Volume{
+				Name: "config",
+				VolumeSource: v1.VolumeSource{
+					ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}},
+				},
+			}
+			mount := v1.VolumeMount{Name: "config", MountPath: "/etc/app"}
+			pod := createReloadTestPod("test-deployment-pod", volume, mount)
+			pod.Status.PodIP = "127.0.0.1"
+			pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+
+			deployment := createTestDeployment([]v1.Container{{Name: "app", VolumeMounts: []v1.VolumeMount{mount}}}, []v1.Container{}, []v1.Volume{volume})
+			deployment.Annotations = map[string]string{
+				options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+				options.HTTPReloadAnnotation:              ":" + server.port() + "/-/reload",
+			}
+			deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+			fakeClient := testclient.NewClientset(configmap, &pod, deployment)
+			clients := kube.Clients{KubernetesClient: fakeClient}
+			config := common.Config{
+				ResourceName: "my-configmap",
+				Type:         constants.ConfigmapEnvVarPostfix,
+				SHAValue:     "sha256:abc123",
+				Namespace:    "default",
+				Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+			}
+			fake.setContent(pod.Name, "new")
+
+			updated, err := upgradeResource(clients, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment, false)
+			assert.NoError(t, err)
+			assert.True(t, updated)
+
+			assert.Eventually(t, func() bool {
+				return len(server.received()) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+
+			expectedEnv := []v1.EnvVar{{Name: getEnvVarName("my-configmap", constants.ConfigmapEnvVarPostfix), Value: "sha256:abc123"}}
+			getEnv := func() []v1.EnvVar {
+				result, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+				assert.NoError(t, err)
+				return result.Spec.Template.Spec.Containers[0].Env
+			}
+			if tt.expectedRolling {
+				assert.Eventually(t, func() bool {
+					return assert.ObjectsAreEqual(expectedEnv, getEnv())
+				}, 5*time.Second, 10*time.Millisecond, "Workload should be rolled after failed reload")
+				return
+			}
+			assert.Never(t, func() bool {
+				return len(getEnv()) > 0
+			}, 100*time.Millisecond, 10*time.Millisecond, "Reloaded workload should not be rolled")
+		})
+	}
+}
+
+func TestHTTPReloadPodsWithDelay(t *testing.T) {
+	original := execInPod
+	execInPod = func(context.Context, kube.Clients, string, string, string, []string) (string, error) {
+		return "", errors.New(`exec: "cat": executable file not found in $PATH`)
+	}
+	t.Cleanup(func() { execInPod = original })
+	server := newReloadServer(t, http.StatusOK)
+
+	reload, err := getHTTPReload(map[string]string{
+		options.HTTPReloadAnnotation:      ":" + server.port() + "/-/reload",
+		options.HTTPReloadDelayAnnotation: "10ms",
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Millisecond, reload.filesUpdatedDelay)
+
+	pod := createReloadTestPod("pod", v1.Volume{}, v1.VolumeMount{})
+	pod.Status.PodIP = "127.0.0.1"
+	targets := []reloadTarget{{pod: &pod, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")}}
+
+	succeeded := reloadPods(context.Background(), kube.Clients{}, nil, targets, reload, time.Second)
+
+	assert.True(t, succeeded, "Pods should be reloaded after the delay without reading the mounted files")
+	assert.Equal(t, []string{"POST /-/reload"}, server.received())
+
+	for _, delay := range []string{"soon", "0s", "-1m"} {
+		_, err := getHTTPReload(map[string]string{
+			options.HTTPReloadAnnotation:      ":9090",
+			options.HTTPReloadDelayAnnotation: delay,
+		}, nil)
+		assert.Error(t, err, "Delay '%s' should be invalid", delay)
+	}
+}
+
+func TestUpgradeResourceHTTPStrategyStopped(t *testing.T) {
+	server := newReloadServer(t, http.StatusInternalServerError)
+
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-configmap", Namespace: "default"},
+		Data:       map[string]string{"app.conf": "new"},
+	}
+	volume := v1.Volume{
+		Name: "config",
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}},
+		},
+	}
+	mount := v1.VolumeMount{Name: "config", MountPath: "/etc/app"}
+	pod := createReloadTestPod("test-deployment-pod", volume, mount)
+	pod.Status.PodIP = "127.0.0.1"
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+
+	deployment := createTestDeployment([]v1.Container{{Name: "app", VolumeMounts: []v1.VolumeMount{mount}}}, []v1.Container{}, []v1.Volume{volume})
+	deployment.Annotations = map[string]string{
+		options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+		options.HTTPReloadAnnotation:              ":" + server.port() + "/-/reload",
+		options.HTTPReloadDelayAnnotation:         "100ms",
+	}
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	fakeClient := testclient.NewClientset(configmap, &pod, deployment)
+	config := common.Config{
+		ResourceName: "my-configmap",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    "default",
+		Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+	}
+
+	updated, err := upgradeResource(kube.Clients{KubernetesClient: fakeClient}, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment, false)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	StopInPlaceReloads()
+
+	assert.Never(t, func() bool {
+		result, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return len(result.Spec.Template.Spec.Containers[0].Env) > 0
+	}, 300*time.Millisecond, 10*time.Millisecond, "Stopped reload should not roll the workload")
+	assert.Empty(t, server.received(), "Endpoint should not be called once reloads are stopped")
+}
@@ -0,0 +1,497 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/sirupsen/logrus"
+	app "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/crypto"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+var (
+	// execInPod runs a command in a container of a pod, replaced in tests
+	execInPod = kube.ExecInPod
+	// mountedFilesPollInterval is the interval to check whether mounted files have been updated in a pod
+	mountedFilesPollInterval = 5 * time.Second
+	// inPlaceReloads cancels the in-place reloads running in the background
+	inPlaceReloads = &inPlaceReloadTracker{}
+)
+
+// inPlaceReloadTracker holds the context of the in-place reloads running in the background, which is cancelled to
+// stop all of them
+type inPlaceReloadTracker struct {
+	mutex  sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// context returns the context of in-place reloads, which is cancelled when the reloads are stopped
+func (t *inPlaceReloadTracker) context() context.Context {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+	}
+	return t.ctx
+}
+
+// stop cancels all in-place reloads running in the background
+func (t *inPlaceReloadTracker) stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cancel != nil {
+		t.cancel()
+		t.ctx, t.cancel = nil, nil
+	}
+}
+
+// StopInPlaceReloads stops the in-place reloads waiting for the kubelet or reloading pods in the background, e.g. when
+// leadership is lost, so that a former leader neither runs commands in pods nor rolls workloads whose reload failed
+func StopInPlaceReloads() {
+	inPlaceReloads.stop()
+}
+
+// inPlaceReload reloads the application running in a pod without restarting it
+type inPlaceReload struct {
+	// description of the reload used in logs and events, e.g. "run 'kill -HUP 1'"
+	description   string
+	successReason string
+	failureReason string
+	// readyPodsOnly restricts the reload to pods that are ready
+	readyPodsOnly bool
+	// rollOnFailure rolls the workload if the reload failed in any of its pods
+	rollOnFailure bool
+	// filesUpdatedDelay is the time to wait for the kubelet to update the mounted files, instead of reading them in the
+	// pods, which requires cat in the image
+	filesUpdatedDelay time.Duration
+	reloadFunc        func(ctx context.Context, clients kube.Clients, target reloadTarget) error
+}
+
+// reloadTarget is a container of a pod mounting the changed resource
+type reloadTarget struct {
+	pod       *v1.Pod
+	container string
+	files     []string
+	// contentHash is the hash of the expected content of files, concatenated in order
+	contentHash string
+}
+
+// isInPlaceReloadStrategy checks whether the reload strategy reloads applications without rolling the workload
+func isInPlaceReloadStrategy(reloadStrategy string) bool {
+	return reloadStrategy == constants.SignalReloadStrategy || reloadStrategy == constants.HTTPReloadStrategy
+}
+
+// getInPlaceReload returns how pods are reloaded by an in-place reload strategy, configured by workload annotations
+func getInPlaceReload(reloadStrategy string, annotations, podAnnotations map[string]string) (inPlaceReload, error) {
+	if reloadStrategy == constants.HTTPReloadStrategy {
+		return getHTTPReload(annotations, podAnnotations)
+	}
+	return getSignalReload(annotations, podAnnotations), nil
+}
+
+// canReloadInPlace checks whether the changed resource is mounted as a volume by the workload. Only then the
+// kubelet updates its files in the running pods, so the application can reload them.
+func canReloadInPlace(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config) bool {
+	if config.Type != constants.ConfigmapEnvVarPostfix && config.Type != constants.SecretEnvVarPostfix {
+		return false
+	}
+	return getVolumeMountName(upgradeFuncs.VolumesFunc(item), config.Type, config.ResourceName) != ""
+}
+
+// getWorkloadAnnotation returns the value of an annotation on the workload or else on its pod template
+func getWorkloadAnnotation(annotations, podAnnotations map[string]string, key string) (string, bool) {
+	value, found := annotations[key]
+	if !found {
+		value, found = podAnnotations[key]
+	}
+	return value, found
+}
+
+// getPodSelector returns the label selector matching the pods of a workload
+func getPodSelector(item runtime.Object) (labels.Selector, error) {
+	var selector *metav1.LabelSelector
+	switch workload := item.(type) {
+	case *app.Deployment:
+		selector = workload.Spec.Selector
+	case *app.DaemonSet:
+		selector = workload.Spec.Selector
+	case *app.StatefulSet:
+		selector = workload.Spec.Selector
+	case *app.ReplicaSet:
+		selector = workload.Spec.Selector
+	case *argorolloutv1alpha1.Rollout:
+		selector = workload.Spec.Selector
+	case *callbacks.KnativeService:
+		// Knative labels the pods of all revisions of a service with its name
+		selector = &metav1.LabelSelector{MatchLabels: map[string]string{"serving.knative.dev/service": workload.Name}}
+	default:
+		return nil, fmt.Errorf("unsupported workload type %T", item)
+	}
+	if selector == nil {
+		return nil, errors.New("workload has no pod selector")
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// reloadWorkloadInPlace reloads the application in the pods of a workload instead of rolling them
+func reloadWorkloadInPlace(clients kube.Clients, config common.Config, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, item runtime.Object, reload inPlaceReload, rollOnFailure func(ctx context.Context), actionStartTime time.Time) (bool, error) {
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		return false, err
+	}
+	resourceName := accessor.GetName()
+
+	err = reloadPodsInPlace(clients, config, recorder, item, reload, rollOnFailure)
+	if apierrors.IsNotFound(err) {
+		// The kubelet keeps the last content of deleted resources, so there is nothing to reload
+		logrus.Infof("'%s' of type '%s' no longer exists in namespace '%s', not reloading %s '%s'", config.ResourceName, config.Type, config.Namespace, upgradeFuncs.ResourceType, resourceName)
+		return false, nil
+	}
+
+	actionLatency := time.Since(actionStartTime)
+	if err != nil {
+		logrus.Errorf("In-place reload for '%s' of type '%s' in namespace '%s' failed with error %v", resourceName, upgradeFuncs.ResourceType, config.Namespace, err)
+		collectors.RecordReload(false, config.Namespace)
+		collectors.RecordAction(upgradeFuncs.ResourceType, "error", actionLatency)
+		if recorder != nil {
+			recorder.Event(item, v1.EventTypeWarning, "ReloadFail", fmt.Sprintf("In-place reload for '%s' of type '%s' in namespace '%s' failed with error %v", resourceName, upgradeFuncs.ResourceType, config.Namespace, err))
+		}
+		return true, err
+	}
+
+	logrus.Infof("Changes detected in '%s' of type '%s' in namespace '%s'; reloading pods of '%s' of type '%s' in namespace '%s' in place", config.ResourceName, config.Type, config.Namespace, resourceName, upgradeFuncs.ResourceType, config.Namespace)
+	collectors.RecordReload(true, config.Namespace)
+	collectors.RecordAction(upgradeFuncs.ResourceType, "success", actionLatency)
+	if recorder != nil {
+		recorder.Event(item, v1.EventTypeNormal, "Reloaded", fmt.Sprintf("Changes detected in '%s' of type '%s' in namespace '%s', Reloading pods of '%s' of type '%s' in namespace '%s' in place", config.ResourceName, config.Type, config.Namespace, resourceName, upgradeFuncs.ResourceType, config.Namespace))
+	}
+	return true, nil
+}
+
+// reloadPodsInPlace reloads the application in every running pod of the workload once the kubelet updated the files of
+// the changed resource. Waiting for the kubelet can take a minute or more, so pods are reloaded in the background until
+// the in-place reloads are stopped.
+func reloadPodsInPlace(clients kube.Clients, config common.Config, recorder record.EventRecorder, item runtime.Object, reload inPlaceReload, rollOnFailure func(ctx context.Context)) error {
+	pods, err := getPodsToReload(clients, config.Namespace, item)
+	if err != nil {
+		return err
+	}
+
+	data, err := getResourceData(clients, config)
+	if err != nil {
+		return err
+	}
+
+	targets := getReloadTargets(pods, config, data, reload.readyPodsOnly)
+	if len(targets) == 0 {
+		logrus.Infof("No running pods mounting '%s' of type '%s' found in namespace '%s' to reload", config.ResourceName, config.Type, config.Namespace)
+		return nil
+	}
+
+	timeout := options.SignalTimeout
+	ctx := inPlaceReloads.context()
+	go func() {
+		succeeded := reloadPods(ctx, clients, recorder, targets, reload, timeout)
+		if ctx.Err() != nil {
+			logrus.Infof("Stopped in-place reload of pods mounting '%s' of type '%s' in namespace '%s'", config.ResourceName, config.Type, config.Namespace)
+			return
+		}
+		if !succeeded && reload.rollOnFailure {
+			rollOnFailure(ctx)
+		}
+	}()
+	return nil
+}
+
+// getPodsToReload returns the pods of a workload, or the pod itself for pods without managing controller
+func getPodsToReload(clients kube.Clients, namespace string, item runtime.Object) ([]v1.Pod, error) {
+	if pod, ok := item.(*v1.Pod); ok {
+		return []v1.Pod{*pod}, nil
+	}
+
+	selector, err := getPodSelector(item)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clients.KubernetesClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// rollResourceAfterFailedReload rolls a workload whose pods could not be reloaded in place, unless ctx is cancelled
+// because the in-place reloads were stopped. The options are only locked for each attempt, so that reloading the
+// config file doesn't wait for the retries.
+func rollResourceAfterFailedReload(ctx context.Context, clients kube.Clients, config common.Config, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, strategy invokeStrategy, resourceName string, autoReload bool) {
+	_, err := retryOnConflict(retry.DefaultRetry, func(bool) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		options.RLock()
+		defer options.RUnlock()
+
+		reloadStrategy := getFallbackReloadStrategy()
+		logrus.Infof("Rolling %s '%s' in namespace '%s' with reload strategy '%s' after in-place reload failed", upgradeFuncs.ResourceType, resourceName, config.Namespace, reloadStrategy)
+		resource, err := upgradeFuncs.ItemFunc(clients, resourceName, config.Namespace)
+		if err != nil {
+			return false, err
+		}
+		return rollResource(clients, config, upgradeFuncs, collectors, recorder, strategy, resource, autoReload, reloadStrategy, time.Now())
+	})
+	if err != nil {
+		logrus.Errorf("Failed to roll %s '%s' in namespace '%s' after in-place reload failed: %v", upgradeFuncs.ResourceType, resourceName, config.Namespace, err)
+	}
+}
+
+// getResourceData returns the current content of the changed ConfigMap or Secret, which is in another namespace than
+// the workload when watched across namespaces
+func getResourceData(clients kube.Clients, config common.Config) (map[string][]byte, error) {
+	namespace := config.Namespace
+	if config.SourceNamespace != "" {
+		namespace = config.SourceNamespace
+	}
+
+	data := make(map[string][]byte)
+	if config.Type == constants.SecretEnvVarPostfix {
+		secret, err := clients.KubernetesClient.CoreV1().Secrets(namespace).Get(context.TODO(), config.ResourceName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+		return data, nil
+	}
+
+	configmap, err := clients.KubernetesClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), config.ResourceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range configmap.Data {
+		data[k] = []byte(v)
+	}
+	for k, v := range configmap.BinaryData {
+		data[k] = v
+	}
+	return data, nil
+}
+
+// isPodReady checks whether the Ready condition of a pod is true
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getReloadTargets returns the containers of running pods mounting the changed resource, with the files and
+// content the kubelet will project into them
+func getReloadTargets(pods []v1.Pod, config common.Config, data map[string][]byte, readyPodsOnly bool) []reloadTarget {
+	var targets []reloadTarget
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != v1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if readyPodsOnly && !isPodReady(pod) {
+			continue
+		}
+
+		volumeName := getVolumeMountName(pod.Spec.Volumes, config.Type, config.ResourceName)
+		if volumeName == "" {
+			continue
+		}
+		volumeIndex := slices.IndexFunc(pod.Spec.Volumes, func(volume v1.Volume) bool { return volume.Name == volumeName })
+		keyToPath := getVolumeKeyToPath(pod.Spec.Volumes[volumeIndex], config, data)
+
+		for _, container := range pod.Spec.Containers {
+			target, ok := getContainerReloadTarget(pod, container, volumeName, keyToPath, data)
+			if ok {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+func getContainerReloadTarget(pod *v1.Pod, container v1.Container, volumeName string, keyToPath map[string]string, data map[string][]byte) (reloadTarget, bool) {
+	for _, mount := range container.VolumeMounts {
+		// Files mounted with subPath are never updated by the kubelet
+		if mount.Name != volumeName || mount.SubPath != "" {
+			continue
+		}
+
+		var files []string
+		var content []byte
+		for _, key := range sortedKeys(keyToPath) {
+			files = append(files, path.Join(mount.MountPath, keyToPath[key]))
+			content = append(content, data[key]...)
+		}
+		if len(files) == 0 {
+			return reloadTarget{}, false
+		}
+
+		return reloadTarget{
+			pod:         pod,
+			container:   container.Name,
+			files:       files,
+			contentHash: crypto.GenerateSHA(string(content)),
+		}, true
+	}
+	return reloadTarget{}, false
+}
+
+// getVolumeKeyToPath returns the path relative to the mount of each key of the changed resource projected into the volume
+func getVolumeKeyToPath(volume v1.Volume, config common.Config, data map[string][]byte) map[string]string {
+	var items []v1.KeyToPath
+	switch {
+	case volume.ConfigMap != nil:
+		items = volume.ConfigMap.Items
+	case volume.Secret != nil:
+		items = volume.Secret.Items
+	case volume.Projected != nil:
+		for _, source := range volume.Projected.Sources {
+			if config.Type == constants.ConfigmapEnvVarPostfix && source.ConfigMap != nil && source.ConfigMap.Name == config.ResourceName {
+				items = source.ConfigMap.Items
+			}
+			if config.Type == constants.SecretEnvVarPostfix && source.Secret != nil && source.Secret.Name == config.ResourceName {
+				items = source.Secret.Items
+			}
+		}
+	}
+
+	keyToPath := make(map[string]string)
+	if len(items) == 0 {
+		for key := range data {
+			keyToPath[key] = key
+		}
+		return keyToPath
+	}
+	for _, item := range items {
+		if _, ok := data[item.Key]; ok {
+			keyToPath[item.Key] = item.Path
+		}
+	}
+	return keyToPath
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// reloadPods reloads all targets in parallel and records the result of each pod as an Event.
+// It returns whether all targets have been reloaded.
+func reloadPods(ctx context.Context, clients kube.Clients, recorder record.EventRecorder, targets []reloadTarget, reload inPlaceReload, timeout time.Duration) bool {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	succeeded := true
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target reloadTarget) {
+			defer wg.Done()
+
+			err := reloadPod(ctx, clients, target, reload, timeout)
+			if err != nil {
+				mutex.Lock()
+				succeeded = false
+				mutex.Unlock()
+				if ctx.Err() != nil {
+					// Stopped reloads are no failure of the pod, nothing is recorded
+					return
+				}
+
+				logrus.Errorf("Failed to %s in container '%s' of pod '%s' in namespace '%s': %v", reload.description, target.container, target.pod.Name, target.pod.Namespace, err)
+				if recorder != nil {
+					recorder.Event(target.pod, v1.EventTypeWarning, reload.failureReason, fmt.Sprintf("Failed to %s in container '%s': %v", reload.description, target.container, err))
+				}
+				return
+			}
+
+			logrus.Infof("Reloaded container '%s' of pod '%s' in namespace '%s'", target.container, target.pod.Name, target.pod.Namespace)
+			if recorder != nil {
+				recorder.Event(target.pod, v1.EventTypeNormal, reload.successReason, fmt.Sprintf("Mounted files were updated, %s in container '%s'", reload.description, target.container))
+			}
+		}(target)
+	}
+	wg.Wait()
+	return succeeded
+}
+
+// reloadPod waits until the kubelet updated the mounted files of the target and then reloads it
+func reloadPod(ctx context.Context, clients kube.Clients, target reloadTarget, reload inPlaceReload, timeout time.Duration) error {
+	if reload.filesUpdatedDelay > 0 {
+		timer := time.NewTimer(reload.filesUpdatedDelay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		return reload.reloadFunc(ctx, clients, target)
+	}
+
+	readCommand := append([]string{"cat"}, target.files...)
+	err := wait.PollUntilContextTimeout(ctx, mountedFilesPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		content, err := execInPod(ctx, clients, target.pod.Namespace, target.pod.Name, target.container, readCommand)
+		if isExecutableNotFound(err) {
+			return false, fmt.Errorf("checking mounted files requires cat in the container: %w", err)
+		}
+		if err != nil {
+			logrus.Debugf("Failed to read mounted files of pod '%s' in namespace '%s': %v", target.pod.Name, target.pod.Namespace, err)
+			return false, nil
+		}
+		return crypto.GenerateSHA(content) == target.contentHash, nil
+	})
+	if isExecutableNotFound(err) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("mounted files were not updated within %s: %w", timeout, err)
+	}
+
+	err = reload.reloadFunc(ctx, clients, target)
+	if isExecutableNotFound(err) {
+		return fmt.Errorf("the executable of the command is missing in the container: %w", err)
+	}
+	return err
+}
+
+// isExecutableNotFound checks whether a command run in a pod failed because its executable is missing in the
+// container image, e.g. cat or kill in distroless images
+func isExecutableNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "executable file not found")
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/crypto"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// fakeExec replaces execInPod, returning the file content of the pod for cat commands and recording all other commands
+type fakeExec struct {
+	mutex    sync.Mutex
+	content  map[string]string
+	commands map[string][][]string
+	err      error
+	// catErr is returned by cat commands, e.g. for images without cat
+	catErr error
+}
+
+func useFakeExec(t *testing.T) *fakeExec {
+	fake := &fakeExec{
+		content:  make(map[string]string),
+		commands: make(map[string][][]string),
+	}
+	originalExec, originalInterval := execInPod, mountedFilesPollInterval
+	execInPod = fake.exec
+	mountedFilesPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() {
+		execInPod = originalExec
+		mountedFilesPollInterval = originalInterval
+	})
+	return fake
+}
+
+func (f *fakeExec) exec(_ context.Context, _ kube.Clients, _, podName, _ string, command []string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if command[0] == "cat" {
+		return f.content[podName], f.catErr
+	}
+	f.commands[podName] = append(f.commands[podName], command)
+	return "", f.err
+}
+
+func (f *fakeExec) setContent(podName, content string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.content[podName] = content
+}
+
+func createReloadTestPod(name string, volume v1.Volume, mount v1.VolumeMount) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app": "test"},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "sidecar"},
+				{Name: "app", VolumeMounts: []v1.VolumeMount{mount}},
+			},
+			Volumes: []v1.Volume{volume},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func TestGetReloadTargets(t *testing.T) {
+	config := common.Config{
+		ResourceName: "my-configmap",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		Namespace:    "default",
+	}
+	data := map[string][]byte{
+		"b.conf": []byte("b"),
+		"a.conf": []byte("a"),
+	}
+	configMapVolume := func(items ...v1.KeyToPath) v1.Volume {
+		return v1.Volume{
+			Name: "config",
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"},
+					Items:                items,
+				},
+			},
+		}
+	}
+	mount := v1.VolumeMount{Name: "config", MountPath: "/etc/app"}
+
+	t.Run("All keys of the resource", func(t *testing.T) {
+		targets := getReloadTargets([]v1.Pod{createReloadTestPod("pod", configMapVolume(), mount)}, config, data, false)
+		assert.Len(t, targets, 1)
+		assert.Equal(t, "app", targets[0].container)
+		assert.Equal(t, []string{"/etc/app/a.conf", "/etc/app/b.conf"}, targets[0].files)
+		assert.Equal(t, crypto.GenerateSHA("ab"), targets[0].contentHash)
+	})
+
+	t.Run("Selected items of the resource", func(t *testing.T) {
+		volume := configMapVolume(v1.KeyToPath{Key: "b.conf", Path: "conf/app.conf"}, v1.KeyToPath{Key: "missing", Path: "missing"})
+		targets := getReloadTargets([]v1.Pod{createReloadTestPod("pod", volume, mount)}, config, data, false)
+		assert.Len(t, targets, 1)
+		assert.Equal(t, []string{"/etc/app/conf/app.conf"}, targets[0].files)
+		assert.Equal(t, crypto.GenerateSHA("b"), targets[0].contentHash)
+	})
+
+	t.Run("Projected volume", func(t *testing.T) {
+		volume := v1.Volume{
+			Name: "config",
+			VolumeSource: v1.VolumeSource{
+				Projected: &v1.ProjectedVolumeSource{
+					Sources: []v1.VolumeProjection{
+						{Secret: &v1.SecretProjection{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}}},
+						{ConfigMap: &v1.ConfigMapProjection{
+							LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"},
+							Items:                []v1.KeyToPath{{Key: "a.conf", Path: "first.conf"}},
+						}},
+					},
+				},
+			},
+		}
+		targets := getReloadTargets([]v1.Pod{createReloadTestPod("pod", volume, mount)}, config, data, false)
+		assert.Len(t, targets, 1)
+		assert.Equal(t, []string{"/etc/app/first.conf"}, targets[0].files)
+	})
+
+	t.Run("SubPath mounts are never updated", func(t *testing.T) {
+		subPathMount := v1.VolumeMount{Name: "config", MountPath: "/etc/app/a.conf", SubPath: "a.conf"}
+		targets := getReloadTargets([]v1.Pod{createReloadTestPod("pod", configMapVolume(), subPathMount)}, config, data, false)
+		assert.Empty(t, targets)
+	})
+
+	t.Run("Pods not running are skipped", func(t *testing.T) {
+		pending := createReloadTestPod("pending", configMapVolume(), mount)
+		pending.Status.Phase = v1.PodPending
+		terminating := createReloadTestPod("terminating", configMapVolume(), mount)
+		terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		targets := getReloadTargets([]v1.Pod{pending, terminating}, config, data, false)
+		assert.Empty(t, targets)
+	})
+
+	t.Run("Pods not ready are skipped if only ready pods are reloaded", func(t *testing.T) {
+		ready := createReloadTestPod("ready", configMapVolume(), mount)
+		ready.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+		notReady := createReloadTestPod("not-ready", configMapVolume(), mount)
+		notReady.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}}
+
+		targets := getReloadTargets([]v1.Pod{ready, notReady}, config, data, true)
+		assert.Len(t, targets, 1)
+		assert.Equal(t, "ready", targets[0].pod.Name)
+
+		targets = getReloadTargets([]v1.Pod{ready, notReady}, config, data, false)
+		assert.Len(t, targets, 2)
+	})
+}
+
+func TestGetPodSelector(t *testing.T) {
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}}}
+	selector, err := getPodSelector(deployment)
+	assert.NoError(t, err)
+	assert.Equal(t, "app=test", selector.String())
+
+	_, err = getPodSelector(&appsv1.Deployment{})
+	assert.Error(t, err)
+
+	_, err = getPodSelector(&v1.Pod{})
+	assert.Error(t, err)
+}
+
+func TestGetResourceData(t *testing.T) {
+	clients := kube.Clients{KubernetesClient: testclient.NewClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-config", Namespace: "shared"},
+		Data:       map[string]string{"app.yaml": "debug: true"},
+	})}
+
+	// Workloads watching the ConfigMap from another namespace read it from its own namespace
+	config := common.Config{Namespace: "team-a", SourceNamespace: "shared", ResourceName: "shared-config", Type: constants.ConfigmapEnvVarPostfix}
+	data, err := getResourceData(clients, config)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"app.yaml": []byte("debug: true")}, data)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
+)
+
+// getJobRerunSkipReason returns why a Job must not be rerun according to its reload policy, or an empty string if it
+// must be rerun
+func getJobRerunSkipReason(job *batchv1.Job) (string, error) {
+	// Superseded Jobs still reference the resource, only the Job that superseded them is rerun
+	if callbacks.IsSupersededJob(job) {
+		return "job_superseded", nil
+	}
+
+	policy, err := callbacks.GetJobReloadPolicy(job)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case policy == constants.RerunFinishedJobReloadPolicy && !isJobFinished(job):
+		return "job_not_finished", nil
+	case policy == constants.SkipRunningJobReloadPolicy && job.Status.Active > 0:
+		return "job_running", nil
+	}
+	return "", nil
+}
+
+// isJobFinished returns whether a Job completed or failed
+func isJobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
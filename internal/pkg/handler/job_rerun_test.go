@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func createTestJob(policy string, status batchv1.JobStatus) *batchv1.Job {
+	annotations := map[string]string{options.ConfigmapUpdateOnChangeAnnotation: "my-configmap"}
+	if policy != "" {
+		annotations[options.JobReloadPolicyAnnotation] = policy
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default", Annotations: annotations},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "app",
+							EnvFrom: []v1.EnvFromSource{
+								{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}}},
+							},
+						},
+					},
+				},
+			},
+		},
+		Status: status,
+	}
+}
+
+func TestGetJobRerunSkipReason(t *testing.T) {
+	running := batchv1.JobStatus{Active: 1}
+	completed := batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}}
+	failed := batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue}}}
+	superseded := createTestJob(constants.CreateNewJobReloadPolicy, completed)
+	superseded.Annotations["reloader.stakater.com/superseded-by"] = "migrate-x7k2p"
+
+	tests := []struct {
+		name           string
+		job            *batchv1.Job
+		expectedReason string
+		wantErr        bool
+	}{
+		{name: "Running job is recreated by default", job: createTestJob("", running)},
+		{name: "Running job is rerun as new job", job: createTestJob(constants.CreateNewJobReloadPolicy, running)},
+		{name: "Completed job is rerun", job: createTestJob(constants.RerunFinishedJobReloadPolicy, completed)},
+		{name: "Failed job is rerun", job: createTestJob(constants.RerunFinishedJobReloadPolicy, failed)},
+		{name: "Running job is not rerun until finished", job: createTestJob(constants.RerunFinishedJobReloadPolicy, running), expectedReason: "job_not_finished"},
+		{name: "Suspended job is not rerun until finished", job: createTestJob(constants.RerunFinishedJobReloadPolicy, batchv1.JobStatus{}), expectedReason: "job_not_finished"},
+		{name: "Running job is skipped", job: createTestJob(constants.SkipRunningJobReloadPolicy, running), expectedReason: "job_running"},
+		{name: "Suspended job is not skipped", job: createTestJob(constants.SkipRunningJobReloadPolicy, batchv1.JobStatus{})},
+		{name: "Superseded job is skipped", job: superseded, expectedReason: "job_superseded"},
+		{name: "Invalid policy", job: createTestJob("never", completed), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := getJobRerunSkipReason(tt.job)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedReason, reason)
+		})
+	}
+}
+
+func TestUpgradeResourceJobReloadPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		status       batchv1.JobStatus
+		expectedJobs int
+		expectedRun  bool
+	}{
+		{
+			name:         "Recreated under the same name",
+			policy:       constants.RecreateJobReloadPolicy,
+			status:       batchv1.JobStatus{Active: 1},
+			expectedJobs: 1,
+			expectedRun:  true,
+		},
+		{
+			name:         "Rerun as new job",
+			policy:       constants.CreateNewJobReloadPolicy,
+			status:       batchv1.JobStatus{Active: 1},
+			expectedJobs: 2,
+			expectedRun:  true,
+		},
+		{
+			name:         "Running job skipped",
+			policy:       constants.SkipRunningJobReloadPolicy,
+			status:       batchv1.JobStatus{Active: 1},
+			expectedJobs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := createTestJob(tt.policy, tt.status)
+			fakeClient := testclient.NewClientset(job)
+			config := common.Config{
+				ResourceName: "my-configmap",
+				Type:         constants.ConfigmapEnvVarPostfix,
+				SHAValue:     "sha256:abc123",
+				Namespace:    "default",
+				Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+			}
+
+			updated, err := upgradeResource(kube.Clients{KubernetesClient: fakeClient}, config, GetJobCreateJobFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, job, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRun, updated)
+
+			jobs, err := fakeClient.BatchV1().Jobs("default").List(context.TODO(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, jobs.Items, tt.expectedJobs)
+			envVarName := getEnvVarName("my-configmap", constants.ConfigmapEnvVarPostfix)
+			rerun := 0
+			for _, item := range jobs.Items {
+				if len(item.Spec.Template.Spec.Containers[0].Env) > 0 && item.Spec.Template.Spec.Containers[0].Env[0].Name == envVarName {
+					rerun++
+				}
+			}
+			if tt.expectedRun {
+				assert.Equal(t, 1, rerun, "Exactly one job should run with the new configuration")
+			} else {
+				assert.Zero(t, rerun, "Skipped job should not be rerun")
+			}
+		})
+	}
+}
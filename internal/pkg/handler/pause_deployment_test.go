@@ -237,12 +237,7 @@ func TestHandleMissingTimerSimple(t *testing.T) {
 
 	for _, test := range tests {
 		// Clean up any timers at the end of the test
-		defer func() {
-			for key, timer := range activeTimers {
-				timer.Stop()
-				delete(activeTimers, key)
-			}
-		}()
+		defer pauseScheduler.Stop()
 
 		t.Run(test.name, func(t *testing.T) {
 			fakeClient := testclient.NewClientset()
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/internal/pkg/util"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// pauseScheduler keeps track of the timers resuming workloads paused by reloader
+var pauseScheduler = NewPauseScheduler(clock.RealClock{})
+
+// PauseScheduler schedules the resume of paused workloads. It is safe for concurrent use by
+// the controller workers and the timer callbacks it runs.
+type PauseScheduler struct {
+	clock  clock.WithDelayedExecution
+	mutex  sync.Mutex
+	timers map[string]*scheduledResume
+}
+
+type scheduledResume struct {
+	timer clock.Timer
+}
+
+// NewPauseScheduler creates a scheduler using the given clock, which allows tests to control time
+func NewPauseScheduler(clock clock.WithDelayedExecution) *PauseScheduler {
+	return &PauseScheduler{
+		clock:  clock,
+		timers: make(map[string]*scheduledResume),
+	}
+}
+
+// Now returns the current time of the scheduler's clock
+func (s *PauseScheduler) Now() time.Time {
+	return s.clock.Now()
+}
+
+// Schedule runs resume after the given duration, unless a resume is already scheduled for key.
+// It returns whether a new resume has been scheduled.
+func (s *PauseScheduler) Schedule(key string, duration time.Duration, resume func()) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.timers[key]; exists {
+		return false
+	}
+
+	entry := &scheduledResume{}
+	entry.timer = s.clock.AfterFunc(duration, func() {
+		s.mutex.Lock()
+		// The entry may have been cancelled and replaced while this callback was waiting for the lock
+		if s.timers[key] == entry {
+			delete(s.timers, key)
+		}
+		s.mutex.Unlock()
+
+		options.RLock()
+		defer options.RUnlock()
+		resume()
+	})
+	s.timers[key] = entry
+	return true
+}
+
+// IsScheduled checks whether a resume is pending for key
+func (s *PauseScheduler) IsScheduled(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, exists := s.timers[key]
+	return exists
+}
+
+// Cancel stops the resume pending for key, if any
+func (s *PauseScheduler) Cancel(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, exists := s.timers[key]; exists {
+		entry.timer.Stop()
+		delete(s.timers, key)
+	}
+}
+
+// Stop cancels all pending resumes
+func (s *PauseScheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, entry := range s.timers {
+		entry.timer.Stop()
+		delete(s.timers, key)
+	}
+}
+
+// getAllPauseFuncs returns the pause funcs of every workload kind reloader is allowed to pause
+func getAllPauseFuncs() []PauseFuncs {
+	pauseFuncs := []PauseFuncs{
+		GetDeploymentPauseFuncs(),
+		GetStatefulSetPauseFuncs(),
+		GetDaemonSetPauseFuncs(),
+	}
+	if options.IsArgoRollouts == "true" {
+		pauseFuncs = append(pauseFuncs, GetArgoRolloutPauseFuncs())
+	}
+	return pauseFuncs
+}
+
+// RestorePauseTimers schedules the resume of every workload paused by reloader in the given namespaces that are
+// watched according to the namespace filter, once the given informers synced so that the pause periods of the ReloaderPolicies and the namespaces
+// matching the namespace selector are known.
+// Timers only live in memory, so without this workloads paused before a restart or a leader change
+// would stay paused until the next change of one of their ConfigMaps or Secrets.
+func RestorePauseTimers(namespaces []string, stopCh <-chan struct{}, synced ...cache.InformerSynced) {
+	if len(namespaces) == 0 {
+		return
+	}
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		logrus.Warn("Not restoring pause timers, the ReloaderPolicies did not sync")
+		return
+	}
+
+	options.RLock()
+	defer options.RUnlock()
+
+	clients := kube.GetClients()
+	for _, pauseFuncs := range getAllPauseFuncs() {
+		for _, namespace := range uniqueNamespaces(namespaces) {
+			restorePauseTimers(pauseFuncs, clients, namespace)
+		}
+	}
+}
+
+func restorePauseTimers(pauseFuncs PauseFuncs, clients kube.Clients, namespace string) {
+	for _, item := range pauseFuncs.ItemsFunc(clients, namespace) {
+		if !isPausedByReloader(pauseFuncs, item) {
+			continue
+		}
+
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			logrus.Errorf("Failed to read metadata of paused %s: %v", pauseFuncs.ResourceType, err)
+			continue
+		}
+		// Workloads of all namespaces are listed when watching globally, including ignored and not selected ones
+		if !isWatchedNamespace(accessor.GetNamespace()) {
+			continue
+		}
+
+		// Without a valid pause period there is nothing left to wait for
+		pausePeriod, _ := getPausePeriod(pauseFuncs.ResourceType, accessor.GetNamespace(), accessor.GetAnnotations(), accessor.GetLabels())
+		pauseDuration, err := ParsePauseDuration(pausePeriod)
+		if err != nil {
+			pauseDuration = 0
+		}
+
+		logrus.Infof("Restoring pause timer for %s '%s' in namespace '%s'", pauseFuncs.ResourceType, accessor.GetName(), accessor.GetNamespace())
+		handleMissingTimer(pauseFuncs, item, pauseDuration, clients, accessor.GetNamespace())
+	}
+}
+
+// StopPauseTimers cancels all pending resumes, e.g. when leadership is lost. The new leader
+// restores them from the workloads' annotations.
+func StopPauseTimers() {
+	pauseScheduler.Stop()
+}
+
+// uniqueNamespaces returns the given namespaces without duplicates, keeping their order
+func uniqueNamespaces(namespaces []string) []string {
+	unique := util.List{}
+	for _, namespace := range namespaces {
+		if !unique.Contains(namespace) {
+			unique = append(unique, namespace)
+		}
+	}
+	return unique
+}
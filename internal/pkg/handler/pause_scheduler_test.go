@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// useFakePauseClock replaces the pause scheduler with one driven by a fake clock for the duration of a test
+func useFakePauseClock(t *testing.T) *testingclock.FakeClock {
+	fakeClock := testingclock.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	original := pauseScheduler
+	pauseScheduler = NewPauseScheduler(fakeClock)
+	t.Cleanup(func() {
+		pauseScheduler.Stop()
+		pauseScheduler = original
+	})
+	return fakeClock
+}
+
+func TestPauseSchedulerSchedule(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	scheduler := NewPauseScheduler(fakeClock)
+
+	resumed := 0
+	assert.True(t, scheduler.Schedule("key", 5*time.Minute, func() { resumed++ }))
+	assert.False(t, scheduler.Schedule("key", time.Minute, func() { resumed++ }), "Resume already scheduled for key")
+	assert.True(t, scheduler.IsScheduled("key"))
+
+	fakeClock.Step(4 * time.Minute)
+	assert.Equal(t, 0, resumed)
+	assert.True(t, scheduler.IsScheduled("key"))
+
+	fakeClock.Step(time.Minute)
+	assert.Equal(t, 1, resumed)
+	assert.False(t, scheduler.IsScheduled("key"))
+
+	assert.True(t, scheduler.Schedule("key", time.Minute, func() { resumed++ }), "Key can be scheduled again once resumed")
+}
+
+func TestPauseSchedulerCancelAndStop(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	scheduler := NewPauseScheduler(fakeClock)
+
+	resumed := 0
+	scheduler.Schedule("first", time.Minute, func() { resumed++ })
+	scheduler.Schedule("second", time.Minute, func() { resumed++ })
+	scheduler.Schedule("third", time.Minute, func() { resumed++ })
+
+	scheduler.Cancel("first")
+	assert.False(t, scheduler.IsScheduled("first"))
+	assert.True(t, scheduler.IsScheduled("second"))
+
+	scheduler.Stop()
+	assert.False(t, scheduler.IsScheduled("second"))
+	assert.False(t, scheduler.IsScheduled("third"))
+
+	fakeClock.Step(time.Hour)
+	assert.Equal(t, 0, resumed)
+	assert.False(t, fakeClock.HasWaiters())
+}
+
+func TestPauseAndResumeDeploymentWithFakeClock(t *testing.T) {
+	fakeClock := useFakePauseClock(t)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-deployment",
+			Namespace: "default",
+		},
+	}
+
+	fakeClient := testclient.NewClientset(deployment)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+
+	_, err := PauseWorkload(deployment, clients, "default", "5m")
+	assert.NoError(t, err)
+
+	paused, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, paused.Spec.Paused)
+	assert.Equal(t, fakeClock.Now().Format(time.RFC3339), paused.Annotations[options.PauseDeploymentTimeAnnotation])
+
+	fakeClock.Step(5 * time.Minute)
+
+	resumed, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, resumed.Spec.Paused)
+	assert.NotContains(t, resumed.Annotations, options.PauseDeploymentTimeAnnotation)
+	assert.False(t, pauseScheduler.IsScheduled(getTimerKey("Deployment", "default", deployment.Name)))
+}
+
+func TestRestorePauseTimers(t *testing.T) {
+	fakeClock := useFakePauseClock(t)
+
+	pausedDeployment := func(namespace, name string, pausedAt time.Time) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Annotations: map[string]string{
+					options.PauseDeploymentAnnotation:     "5m",
+					options.PauseDeploymentTimeAnnotation: pausedAt.Format(time.RFC3339),
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Paused: true,
+			},
+		}
+	}
+
+	expired := pausedDeployment("default", "expired", fakeClock.Now().Add(-6*time.Minute))
+	pending := pausedDeployment("default", "pending", fakeClock.Now().Add(-2*time.Minute))
+	ignored := pausedDeployment("kube-system", "expired", fakeClock.Now().Add(-6*time.Minute))
+	notByReloader := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "not-by-reloader",
+			Namespace: "default",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Paused: true,
+		},
+	}
+
+	fakeClient := testclient.NewClientset(expired, pending, notByReloader, ignored)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+
+	// Watching globally lists the workloads of all namespaces
+	defer func(filter func(string) bool) { isWatchedNamespace = filter }(isWatchedNamespace)
+	SetNamespaceFilter(func(namespace string) bool { return namespace != "kube-system" })
+	restorePauseTimers(GetDeploymentPauseFuncs(), clients, metav1.NamespaceAll)
+
+	getDeployment := func(name string) *appsv1.Deployment {
+		deployment, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return deployment
+	}
+
+	assert.False(t, getDeployment("expired").Spec.Paused, "Expired pause should be resumed right away")
+	assert.True(t, getDeployment("pending").Spec.Paused)
+	assert.True(t, pauseScheduler.IsScheduled(getTimerKey("Deployment", "default", "pending")))
+	assert.True(t, getDeployment("not-by-reloader").Spec.Paused)
+	assert.False(t, pauseScheduler.IsScheduled(getTimerKey("Deployment", "default", "not-by-reloader")))
+	notWatched, err := fakeClient.AppsV1().Deployments("kube-system").Get(context.TODO(), "expired", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, notWatched.Spec.Paused, "Workloads of namespaces that aren't watched should be left alone")
+
+	fakeClock.Step(3 * time.Minute)
+	assert.False(t, getDeployment("pending").Spec.Paused, "Pending pause should be resumed once the remaining period passed")
+}
+
+func TestUniqueNamespaces(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, uniqueNamespaces([]string{"a", "b", "a"}))
+	assert.Empty(t, uniqueNamespaces(nil))
+}
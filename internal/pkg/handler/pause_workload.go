@@ -0,0 +1,477 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/sirupsen/logrus"
+	app "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	patchtypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// PausePatchFunc is a generic func to return the patch that pauses or resumes rollouts of a workload
+type PausePatchFunc func(runtime.Object) ([]byte, error)
+
+// IsPausedFunc is a generic func to check whether rollouts of a workload are currently paused
+type IsPausedFunc func(runtime.Object) bool
+
+// PauseFuncs contains generic functions to pause and resume rollouts of a workload
+type PauseFuncs struct {
+	ItemFunc        callbacks.ItemFunc
+	ItemsFunc       callbacks.ItemsFunc
+	IsPausedFunc    IsPausedFunc
+	PausePatchFunc  PausePatchFunc
+	ResumePatchFunc PausePatchFunc
+	PatchFunc       callbacks.PatchFunc
+	PatchType       patchtypes.PatchType
+	ResourceType    string
+}
+
+// GetDeploymentPauseFuncs returns all pause funcs for a deployment
+func GetDeploymentPauseFuncs() PauseFuncs {
+	return PauseFuncs{
+		ItemFunc:  callbacks.GetDeploymentItem,
+		ItemsFunc: callbacks.GetDeploymentItems,
+		IsPausedFunc: func(item runtime.Object) bool {
+			deployment, ok := item.(*app.Deployment)
+			return ok && IsPaused(deployment)
+		},
+		PausePatchFunc:  func(runtime.Object) ([]byte, error) { return CreatePausePatch() },
+		ResumePatchFunc: func(runtime.Object) ([]byte, error) { return CreateResumePatch() },
+		PatchFunc:       callbacks.PatchDeployment,
+		PatchType:       patchtypes.StrategicMergePatchType,
+		ResourceType:    "Deployment",
+	}
+}
+
+// GetStatefulSetPauseFuncs returns all pause funcs for a statefulSet
+func GetStatefulSetPauseFuncs() PauseFuncs {
+	return PauseFuncs{
+		ItemFunc:        callbacks.GetStatefulSetItem,
+		ItemsFunc:       callbacks.GetStatefulSetItems,
+		IsPausedFunc:    isStatefulSetPaused,
+		PausePatchFunc:  createStatefulSetPausePatch,
+		ResumePatchFunc: createStatefulSetResumePatch,
+		PatchFunc:       callbacks.PatchStatefulSet,
+		PatchType:       patchtypes.StrategicMergePatchType,
+		ResourceType:    "StatefulSet",
+	}
+}
+
+// GetDaemonSetPauseFuncs returns all pause funcs for a daemonSet
+func GetDaemonSetPauseFuncs() PauseFuncs {
+	return PauseFuncs{
+		ItemFunc:        callbacks.GetDaemonSetItem,
+		ItemsFunc:       callbacks.GetDaemonSetItems,
+		IsPausedFunc:    isDaemonSetPaused,
+		PausePatchFunc:  createDaemonSetPausePatch,
+		ResumePatchFunc: createDaemonSetResumePatch,
+		PatchFunc:       callbacks.PatchDaemonSet,
+		PatchType:       patchtypes.StrategicMergePatchType,
+		ResourceType:    "DaemonSet",
+	}
+}
+
+// GetArgoRolloutPauseFuncs returns all pause funcs for a rollout
+func GetArgoRolloutPauseFuncs() PauseFuncs {
+	return PauseFuncs{
+		ItemFunc:        callbacks.GetRolloutItem,
+		ItemsFunc:       callbacks.GetRolloutItems,
+		IsPausedFunc:    isRolloutPaused,
+		PausePatchFunc:  func(runtime.Object) ([]byte, error) { return CreatePausePatch() },
+		ResumePatchFunc: func(runtime.Object) ([]byte, error) { return CreateResumePatch() },
+		PatchFunc:       patchRolloutPauseState,
+		PatchType:       patchtypes.MergePatchType,
+		ResourceType:    "Rollout",
+	}
+}
+
+// getPauseFuncs returns the pause funcs matching the kind of the given workload
+func getPauseFuncs(resource runtime.Object) (PauseFuncs, bool) {
+	switch resource.(type) {
+	case *app.Deployment:
+		return GetDeploymentPauseFuncs(), true
+	case *app.StatefulSet:
+		return GetStatefulSetPauseFuncs(), true
+	case *app.DaemonSet:
+		return GetDaemonSetPauseFuncs(), true
+	case *argorolloutv1alpha1.Rollout:
+		return GetArgoRolloutPauseFuncs(), true
+	}
+	return PauseFuncs{}, false
+}
+
+// PauseWorkload pauses rollouts of a Deployment, StatefulSet, DaemonSet or Argo Rollout for the
+// specified duration and creates a timer to resume it afterwards. The returned object is the
+// latest version of the workload, which differs from the given one only if it has been paused.
+func PauseWorkload(resource runtime.Object, clients kube.Clients, namespace, pauseIntervalValue string) (runtime.Object, error) {
+	pauseFuncs, ok := getPauseFuncs(resource)
+	if !ok {
+		logrus.Warnf("Annotation '%s' only applicable for deployments, statefulsets, daemonsets and rollouts", options.PauseDeploymentAnnotation)
+		return resource, nil
+	}
+	return pauseWorkload(pauseFuncs, resource, clients, namespace, pauseIntervalValue)
+}
+
+func pauseWorkload(pauseFuncs PauseFuncs, resource runtime.Object, clients kube.Clients, namespace, pauseIntervalValue string) (runtime.Object, error) {
+	accessor, err := meta.Accessor(resource)
+	if err != nil {
+		return nil, err
+	}
+	name := accessor.GetName()
+
+	pauseDuration, err := ParsePauseDuration(pauseIntervalValue)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pauseFuncs.IsPausedFunc(resource) {
+		logrus.Infof("Pausing %s '%s' in namespace '%s' for %s", pauseFuncs.ResourceType, name, namespace, pauseDuration)
+
+		pausePatch, err := pauseFuncs.PausePatchFunc(resource)
+		if err != nil {
+			logrus.Errorf("Failed to create pause patch for %s '%s': %v", pauseFuncs.ResourceType, name, err)
+			return resource, err
+		}
+
+		err = pauseFuncs.PatchFunc(clients, namespace, resource, pauseFuncs.PatchType, pausePatch)
+		if err != nil {
+			logrus.Errorf("Failed to patch %s '%s' in namespace '%s': %v", pauseFuncs.ResourceType, name, namespace, err)
+			return resource, err
+		}
+
+		updatedResource, err := pauseFuncs.ItemFunc(clients, name, namespace)
+
+		createResumeTimer(pauseFuncs, name, clients, namespace, pauseDuration)
+		return updatedResource, err
+	}
+
+	if !isPausedByReloader(pauseFuncs, resource) {
+		logrus.Infof("%s '%s' in namespace '%s' already paused", pauseFuncs.ResourceType, name, namespace)
+		return resource, nil
+	}
+
+	// Workload has already been paused by reloader, check for timer
+	logrus.Debugf("%s '%s' in namespace '%s' is already paused by reloader", pauseFuncs.ResourceType, name, namespace)
+
+	if !pauseScheduler.IsScheduled(getTimerKey(pauseFuncs.ResourceType, namespace, name)) {
+		logrus.Warnf("Timer does not exist for already paused %s '%s' in namespace '%s', creating new one",
+			pauseFuncs.ResourceType, name, namespace)
+		handleMissingTimer(pauseFuncs, resource, pauseDuration, clients, namespace)
+	}
+	return resource, nil
+}
+
+// getPausePeriod returns the pause period of a workload from its pause annotation, or else from the ReloaderPolicies
+// selecting it
+func getPausePeriod(resourceType, namespace string, annotations, workloadLabels map[string]string) (string, bool) {
+	if pausePeriod, found := annotations[options.PauseDeploymentAnnotation]; found {
+		return pausePeriod, true
+	}
+	if pausePeriod := common.GetPolicyRules(namespace, resourceType, workloadLabels).PausePeriod; pausePeriod != nil {
+		return pausePeriod.Duration.String(), true
+	}
+	return "", false
+}
+
+// isPausedByReloader checks whether the workload is paused and carries the paused-at annotation set by reloader
+func isPausedByReloader(pauseFuncs PauseFuncs, resource runtime.Object) bool {
+	if !pauseFuncs.IsPausedFunc(resource) {
+		return false
+	}
+	accessor, err := meta.Accessor(resource)
+	if err != nil {
+		return false
+	}
+	return accessor.GetAnnotations()[options.PauseDeploymentTimeAnnotation] != ""
+}
+
+// getPauseStartTime returns the time the workload was paused by reloader, nil otherwise
+func getPauseStartTime(pauseFuncs PauseFuncs, resource runtime.Object) (*time.Time, error) {
+	if !isPausedByReloader(pauseFuncs, resource) {
+		return nil, nil
+	}
+
+	accessor, err := meta.Accessor(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTime, err := time.Parse(time.RFC3339, accessor.GetAnnotations()[options.PauseDeploymentTimeAnnotation])
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsedTime, nil
+}
+
+func handleMissingTimer(pauseFuncs PauseFuncs, resource runtime.Object, pauseDuration time.Duration, clients kube.Clients, namespace string) {
+	accessor, err := meta.Accessor(resource)
+	if err != nil {
+		logrus.Errorf("Failed to read metadata of paused %s: %v", pauseFuncs.ResourceType, err)
+		return
+	}
+	name := accessor.GetName()
+
+	pauseStartTime, err := getPauseStartTime(pauseFuncs, resource)
+	if err != nil {
+		logrus.Errorf("Error parsing pause start time for %s '%s' in namespace '%s': %v. Resuming immediately",
+			pauseFuncs.ResourceType, name, namespace, err)
+		resumeWorkload(pauseFuncs, name, namespace, clients)
+		return
+	}
+
+	if pauseStartTime == nil {
+		return
+	}
+
+	elapsedPauseTime := pauseScheduler.Now().Sub(*pauseStartTime)
+	remainingPauseTime := pauseDuration - elapsedPauseTime
+
+	if remainingPauseTime <= 0 {
+		logrus.Infof("Pause period for %s '%s' in namespace '%s' has expired. Resuming immediately",
+			pauseFuncs.ResourceType, name, namespace)
+		resumeWorkload(pauseFuncs, name, namespace, clients)
+		return
+	}
+
+	logrus.Infof("Creating missing timer for already paused %s '%s' in namespace '%s' with remaining time %s",
+		pauseFuncs.ResourceType, name, namespace, remainingPauseTime)
+	createResumeTimer(pauseFuncs, name, clients, namespace, remainingPauseTime)
+}
+
+func createResumeTimer(pauseFuncs PauseFuncs, name string, clients kube.Clients, namespace string, pauseDuration time.Duration) {
+	scheduled := pauseScheduler.Schedule(getTimerKey(pauseFuncs.ResourceType, namespace, name), pauseDuration, func() {
+		resumeWorkload(pauseFuncs, name, namespace, clients)
+	})
+
+	if !scheduled {
+		logrus.Debugf("Timer already exists for %s '%s' in namespace '%s', Skipping creation",
+			pauseFuncs.ResourceType, name, namespace)
+		return
+	}
+
+	logrus.Debugf("Created pause timer for %s '%s' in namespace '%s' with duration %s",
+		pauseFuncs.ResourceType, name, namespace, pauseDuration)
+}
+
+func resumeWorkload(pauseFuncs PauseFuncs, name, namespace string, clients kube.Clients) {
+	current, err := pauseFuncs.ItemFunc(clients, name, namespace)
+	if err != nil {
+		logrus.Errorf("Failed to get %s '%s' in namespace '%s': %v", pauseFuncs.ResourceType, name, namespace, err)
+		return
+	}
+
+	if !isPausedByReloader(pauseFuncs, current) {
+		logrus.Infof("%s '%s' in namespace '%s' not paused by Reloader. Skipping resume", pauseFuncs.ResourceType, name, namespace)
+		return
+	}
+
+	resumePatch, err := pauseFuncs.ResumePatchFunc(current)
+	if err != nil {
+		logrus.Errorf("Failed to create resume patch for %s '%s': %v", pauseFuncs.ResourceType, name, err)
+		return
+	}
+
+	// Remove the timer, in case the workload is resumed before it fired
+	pauseScheduler.Cancel(getTimerKey(pauseFuncs.ResourceType, namespace, name))
+
+	err = pauseFuncs.PatchFunc(clients, namespace, current, pauseFuncs.PatchType, resumePatch)
+	if err != nil {
+		logrus.Errorf("Failed to resume %s '%s' in namespace '%s': %v", pauseFuncs.ResourceType, name, namespace, err)
+		return
+	}
+
+	logrus.Infof("Successfully resumed %s '%s' in namespace '%s'", pauseFuncs.ResourceType, name, namespace)
+}
+
+func getPausedUpdateStrategyAnnotationKey() string {
+	return fmt.Sprintf("%s/%s",
+		constants.ReloaderAnnotationPrefix,
+		constants.PausedUpdateStrategyAnnotation,
+	)
+}
+
+// A statefulSet is paused when no pod would be updated by a template change, i.e. its
+// rolling update partition covers all replicas or it is updated on delete only
+func isStatefulSetPaused(item runtime.Object) bool {
+	statefulSet, ok := item.(*app.StatefulSet)
+	if !ok {
+		return false
+	}
+	if statefulSet.Spec.UpdateStrategy.Type == app.OnDeleteStatefulSetStrategyType {
+		return true
+	}
+	rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate
+	return rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition >= getStatefulSetReplicas(statefulSet)
+}
+
+func getStatefulSetReplicas(statefulSet *app.StatefulSet) int32 {
+	if statefulSet.Spec.Replicas == nil {
+		return 1
+	}
+	return *statefulSet.Spec.Replicas
+}
+
+// Raises the rolling update partition to the number of replicas, keeping the original
+// partition in an annotation so it can be restored on resume
+func createStatefulSetPausePatch(item runtime.Object) ([]byte, error) {
+	statefulSet, ok := item.(*app.StatefulSet)
+	if !ok {
+		return nil, fmt.Errorf("resource is not a StatefulSet")
+	}
+
+	originalPartition := ""
+	if rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+		originalPartition = strconv.Itoa(int(*rollingUpdate.Partition))
+	}
+
+	patchData := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"updateStrategy": map[string]interface{}{
+				"rollingUpdate": map[string]interface{}{
+					"partition": getStatefulSetReplicas(statefulSet),
+				},
+			},
+		},
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				options.PauseDeploymentTimeAnnotation:  pauseScheduler.Now().Format(time.RFC3339),
+				getPausedUpdateStrategyAnnotationKey(): originalPartition,
+			},
+		},
+	}
+
+	return json.Marshal(patchData)
+}
+
+func createStatefulSetResumePatch(item runtime.Object) ([]byte, error) {
+	statefulSet, ok := item.(*app.StatefulSet)
+	if !ok {
+		return nil, fmt.Errorf("resource is not a StatefulSet")
+	}
+
+	var partition interface{}
+	if originalPartition := statefulSet.Annotations[getPausedUpdateStrategyAnnotationKey()]; originalPartition != "" {
+		value, err := strconv.Atoi(originalPartition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition '%s' in annotation '%s': %w", originalPartition, getPausedUpdateStrategyAnnotationKey(), err)
+		}
+		partition = value
+	}
+
+	patchData := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"updateStrategy": map[string]interface{}{
+				"rollingUpdate": map[string]interface{}{
+					"partition": partition,
+				},
+			},
+		},
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				options.PauseDeploymentTimeAnnotation:  nil,
+				getPausedUpdateStrategyAnnotationKey(): nil,
+			},
+		},
+	}
+
+	return json.Marshal(patchData)
+}
+
+// A daemonSet is paused when pods are only replaced once they are deleted
+func isDaemonSetPaused(item runtime.Object) bool {
+	daemonSet, ok := item.(*app.DaemonSet)
+	return ok && daemonSet.Spec.UpdateStrategy.Type == app.OnDeleteDaemonSetStrategyType
+}
+
+// Switches the update strategy to OnDelete, keeping the original strategy in an annotation
+// so it can be restored on resume
+func createDaemonSetPausePatch(item runtime.Object) ([]byte, error) {
+	daemonSet, ok := item.(*app.DaemonSet)
+	if !ok {
+		return nil, fmt.Errorf("resource is not a DaemonSet")
+	}
+
+	originalStrategy, err := json.Marshal(daemonSet.Spec.UpdateStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	patchData := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"updateStrategy": map[string]interface{}{
+				"type":          app.OnDeleteDaemonSetStrategyType,
+				"rollingUpdate": nil,
+			},
+		},
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				options.PauseDeploymentTimeAnnotation:  pauseScheduler.Now().Format(time.RFC3339),
+				getPausedUpdateStrategyAnnotationKey(): string(originalStrategy),
+			},
+		},
+	}
+
+	return json.Marshal(patchData)
+}
+
+func createDaemonSetResumePatch(item runtime.Object) ([]byte, error) {
+	daemonSet, ok := item.(*app.DaemonSet)
+	if !ok {
+		return nil, fmt.Errorf("resource is not a DaemonSet")
+	}
+
+	originalStrategy := app.DaemonSetUpdateStrategy{Type: app.RollingUpdateDaemonSetStrategyType}
+	if value := daemonSet.Annotations[getPausedUpdateStrategyAnnotationKey()]; value != "" {
+		if err := json.Unmarshal([]byte(value), &originalStrategy); err != nil {
+			return nil, fmt.Errorf("invalid update strategy in annotation '%s': %w", getPausedUpdateStrategyAnnotationKey(), err)
+		}
+	}
+	if originalStrategy.Type == "" {
+		originalStrategy.Type = app.RollingUpdateDaemonSetStrategyType
+	}
+
+	patchData := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"updateStrategy": originalStrategy,
+		},
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				options.PauseDeploymentTimeAnnotation:  nil,
+				getPausedUpdateStrategyAnnotationKey(): nil,
+			},
+		},
+	}
+
+	return json.Marshal(patchData)
+}
+
+func isRolloutPaused(item runtime.Object) bool {
+	rollout, ok := item.(*argorolloutv1alpha1.Rollout)
+	return ok && rollout.Spec.Paused
+}
+
+// Patching a rollout through its rolling upgrade funcs applies its rollout strategy, so the
+// pause state is patched directly
+func patchRolloutPauseState(clients kube.Clients, namespace string, resource runtime.Object, patchType patchtypes.PatchType, bytes []byte) error {
+	rollout, ok := resource.(*argorolloutv1alpha1.Rollout)
+	if !ok {
+		return fmt.Errorf("resource is not a Rollout")
+	}
+	_, err := clients.ArgoRolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Patch(context.TODO(), rollout.Name, patchType, bytes, metav1.PatchOptions{FieldManager: "Reloader"})
+	return err
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	fakeargoclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func TestIsStatefulSetPaused(t *testing.T) {
+	tests := []struct {
+		name        string
+		statefulSet *appsv1.StatefulSet
+		paused      bool
+	}{
+		{
+			name:        "default rolling update",
+			statefulSet: &appsv1.StatefulSet{},
+			paused:      false,
+		},
+		{
+			name: "partition below replicas",
+			statefulSet: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: ptr.To[int32](3),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: ptr.To[int32](1)},
+					},
+				},
+			},
+			paused: false,
+		},
+		{
+			name: "partition covers all replicas",
+			statefulSet: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: ptr.To[int32](3),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: ptr.To[int32](3)},
+					},
+				},
+			},
+			paused: true,
+		},
+		{
+			name: "on delete update strategy",
+			statefulSet: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType},
+				},
+			},
+			paused: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.paused, isStatefulSetPaused(test.statefulSet))
+		})
+	}
+}
+
+func TestPauseAndResumeStatefulSet(t *testing.T) {
+	defer pauseScheduler.Stop()
+
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-statefulset",
+			Namespace: "default",
+			Annotations: map[string]string{
+				options.PauseDeploymentAnnotation: "5m",
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: ptr.To[int32](3),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: ptr.To[int32](1)},
+			},
+		},
+	}
+
+	fakeClient := testclient.NewClientset(statefulSet)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+
+	resource, err := PauseWorkload(statefulSet, clients, "default", "5m")
+	assert.NoError(t, err)
+
+	paused, ok := resource.(*appsv1.StatefulSet)
+	assert.True(t, ok)
+	assert.Equal(t, int32(3), *paused.Spec.UpdateStrategy.RollingUpdate.Partition)
+	assert.Equal(t, "1", paused.Annotations[getPausedUpdateStrategyAnnotationKey()])
+	assert.NotEmpty(t, paused.Annotations[options.PauseDeploymentTimeAnnotation])
+	assert.True(t, pauseScheduler.IsScheduled(getTimerKey("StatefulSet", "default", "test-statefulset")))
+
+	resumeWorkload(GetStatefulSetPauseFuncs(), statefulSet.Name, "default", clients)
+
+	resumed, err := fakeClient.AppsV1().StatefulSets("default").Get(context.TODO(), statefulSet.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *resumed.Spec.UpdateStrategy.RollingUpdate.Partition)
+	assert.NotContains(t, resumed.Annotations, getPausedUpdateStrategyAnnotationKey())
+	assert.NotContains(t, resumed.Annotations, options.PauseDeploymentTimeAnnotation)
+	assert.False(t, pauseScheduler.IsScheduled(getTimerKey("StatefulSet", "default", "test-statefulset")))
+}
+
+func TestPauseAndResumeDaemonSet(t *testing.T) {
+	defer pauseScheduler.Stop()
+
+	maxUnavailable := intstr.FromInt32(2)
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-daemonset",
+			Namespace: "default",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type:          appsv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxUnavailable: &maxUnavailable},
+			},
+		},
+	}
+
+	fakeClient := testclient.NewClientset(daemonSet)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+
+	resource, err := PauseWorkload(daemonSet, clients, "default", "5m")
+	assert.NoError(t, err)
+
+	paused, ok := resource.(*appsv1.DaemonSet)
+	assert.True(t, ok)
+	assert.Equal(t, appsv1.OnDeleteDaemonSetStrategyType, paused.Spec.UpdateStrategy.Type)
+	assert.Nil(t, paused.Spec.UpdateStrategy.RollingUpdate)
+	assert.NotEmpty(t, paused.Annotations[getPausedUpdateStrategyAnnotationKey()])
+
+	resumeWorkload(GetDaemonSetPauseFuncs(), daemonSet.Name, "default", clients)
+
+	resumed, err := fakeClient.AppsV1().DaemonSets("default").Get(context.TODO(), daemonSet.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, appsv1.RollingUpdateDaemonSetStrategyType, resumed.Spec.UpdateStrategy.Type)
+	assert.Equal(t, maxUnavailable, *resumed.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable)
+	assert.NotContains(t, resumed.Annotations, getPausedUpdateStrategyAnnotationKey())
+}
+
+func TestPauseDaemonSetAlreadyOnDelete(t *testing.T) {
+	defer pauseScheduler.Stop()
+
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-daemonset-on-delete",
+			Namespace: "default",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType},
+		},
+	}
+
+	clients := kube.Clients{KubernetesClient: testclient.NewClientset(daemonSet)}
+
+	resource, err := PauseWorkload(daemonSet, clients, "default", "5m")
+	assert.NoError(t, err)
+	assert.Same(t, daemonSet, resource, "Workload not paused by reloader should be returned unchanged")
+	assert.False(t, pauseScheduler.IsScheduled(getTimerKey("DaemonSet", "default", "test-daemonset-on-delete")))
+}
+
+func TestPauseAndResumeRollout(t *testing.T) {
+	defer pauseScheduler.Stop()
+
+	rollout := &argorolloutv1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-rollout",
+			Namespace: "default",
+		},
+	}
+
+	fakeArgoClient := fakeargoclientset.NewSimpleClientset(rollout)
+	clients := kube.Clients{ArgoRolloutClient: fakeArgoClient}
+
+	resource, err := PauseWorkload(rollout, clients, "default", "5m")
+	assert.NoError(t, err)
+
+	paused, ok := resource.(*argorolloutv1alpha1.Rollout)
+	assert.True(t, ok)
+	assert.True(t, paused.Spec.Paused)
+	assert.True(t, isPausedByReloader(GetArgoRolloutPauseFuncs(), paused))
+
+	resumeWorkload(GetArgoRolloutPauseFuncs(), rollout.Name, "default", clients)
+
+	resumed, err := fakeArgoClient.ArgoprojV1alpha1().Rollouts("default").Get(context.TODO(), rollout.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, resumed.Spec.Paused)
+	assert.NotContains(t, resumed.Annotations, options.PauseDeploymentTimeAnnotation)
+}
+
+func TestPauseWorkloadUnsupportedKind(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-job",
+			Namespace: "default",
+		},
+	}
+
+	resource, err := PauseWorkload(job, kube.Clients{}, "default", "5m")
+	assert.NoError(t, err)
+	assert.Same(t, job, resource)
+}
+
+func TestHandleMissingTimerStatefulSet(t *testing.T) {
+	defer pauseScheduler.Stop()
+
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-statefulset-expired",
+			Namespace: "default",
+			Annotations: map[string]string{
+				options.PauseDeploymentTimeAnnotation:  time.Now().Add(-6 * time.Minute).Format(time.RFC3339),
+				getPausedUpdateStrategyAnnotationKey(): "",
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: ptr.To[int32](2),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: ptr.To[int32](2)},
+			},
+		},
+	}
+
+	fakeClient := testclient.NewClientset(statefulSet)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+
+	handleMissingTimer(GetStatefulSetPauseFuncs(), statefulSet, 5*time.Minute, clients, "default")
+
+	resumed, err := fakeClient.AppsV1().StatefulSets("default").Get(context.TODO(), statefulSet.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, isStatefulSetPaused(resumed), "StatefulSet should be resumed once the pause period expired")
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// PolicyDebugPath is the path of the endpoint showing the effective reload rules of a workload
+const PolicyDebugPath = "/debug/policy"
+
+// EffectiveRules are the rules a workload is reloaded with, merged from its annotations, the annotations of its
+// namespace, the ReloaderPolicies and ClusterReloaderPolicies selecting it and the flags
+type EffectiveRules struct {
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload"`
+	// Strategy is the reload strategy of the workload
+	Strategy string `json:"strategy"`
+	// PausePeriod is how long the workload is paused after a reload, empty if it isn't
+	PausePeriod string `json:"pausePeriod,omitempty"`
+	// Policies are the merged rules of the policies selecting the workload
+	Policies common.PolicyRules `json:"policies"`
+}
+
+// SetupPolicyDebugEndpoint sets up the endpoint showing the effective reload rules of the workload given as
+// ?namespace=<namespace>&workload=<kind>/<name>. Only workloads in the watched namespaces are shown.
+func SetupPolicyDebugEndpoint() {
+	http.Handle(PolicyDebugPath, newPolicyDebugHandler(kube.GetClients()))
+}
+
+func newPolicyDebugHandler(clients kube.Clients) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		namespace := req.URL.Query().Get("namespace")
+		workload := req.URL.Query().Get("workload")
+		kind, name, found := strings.Cut(workload, "/")
+		if namespace == "" || !found || kind == "" || name == "" {
+			http.Error(w, "namespace and workload=<kind>/<name> are required", http.StatusBadRequest)
+			return
+		}
+
+		options.RLock()
+		watched := isWatchedNamespace(namespace)
+		var rules EffectiveRules
+		var err error
+		if watched {
+			rules, err = getEffectiveRules(clients, namespace, kind, name)
+		}
+		options.RUnlock()
+		if !watched {
+			http.Error(w, fmt.Sprintf("namespace '%s' is not watched", namespace), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rules); err != nil {
+			logrus.Infof("failed to write policy debug response, got err: %s", err)
+		}
+	}
+}
+
+// getEffectiveRules returns the rules a workload of one of the kinds reloaded by Reloader is reloaded with
+func getEffectiveRules(clients kube.Clients, namespace, kind, name string) (EffectiveRules, error) {
+	var upgradeFuncs *callbacks.RollingUpgradeFuncs
+	for _, funcs := range getReloadedWorkloadFuncs() {
+		if strings.EqualFold(funcs.ResourceType, kind) {
+			upgradeFuncs = &funcs
+			break
+		}
+	}
+	if upgradeFuncs == nil {
+		return EffectiveRules{}, fmt.Errorf("workloads of kind '%s' are not reloaded", kind)
+	}
+
+	item, err := upgradeFuncs.ItemFunc(clients, name, namespace)
+	if err != nil {
+		return EffectiveRules{}, fmt.Errorf("failed to get %s '%s' in namespace '%s': %w", upgradeFuncs.ResourceType, name, namespace, err)
+	}
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		return EffectiveRules{}, err
+	}
+
+	annotations := upgradeFuncs.AnnotationsFunc(item)
+	policyRules := common.GetPolicyRules(namespace, upgradeFuncs.ResourceType, accessor.GetLabels())
+	var pausePeriod string
+	if _, ok := getPauseFuncs(item); ok {
+		pausePeriod, _ = getPausePeriod(upgradeFuncs.ResourceType, namespace, annotations, accessor.GetLabels())
+	}
+	return EffectiveRules{
+		Namespace:   namespace,
+		Workload:    upgradeFuncs.ResourceType + "/" + name,
+		Strategy:    getReloadStrategy(*upgradeFuncs, name, namespace, annotations, upgradeFuncs.PodAnnotationsFunc(item), policyRules.Strategy),
+		PausePeriod: pausePeriod,
+		Policies:    policyRules,
+	}, nil
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func TestPolicyDebugHandler(t *testing.T) {
+	originalReloadStrategy := options.ReloadStrategy
+	defer func() { options.ReloadStrategy = originalReloadStrategy }()
+	options.ReloadStrategy = constants.EnvVarsReloadStrategy
+
+	fakeClient := testclient.NewClientset()
+	for _, statefulSet := range []*appsv1.StatefulSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "team-a", Labels: map[string]string{"tier": "db"}}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:        "redis",
+			Namespace:   "team-a",
+			Labels:      map[string]string{"tier": "db"},
+			Annotations: map[string]string{options.ReloadStrategyAnnotation: constants.AnnotationsReloadStrategy},
+		}},
+	} {
+		_, err := fakeClient.AppsV1().StatefulSets(statefulSet.Namespace).Create(context.TODO(), statefulSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, common.SetClusterReloaderPolicy(&v1alpha1.ClusterReloaderPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "databases"},
+		Spec: v1alpha1.ClusterReloaderPolicySpec{
+			ReloaderPolicySpec: v1alpha1.ReloaderPolicySpec{
+				WorkloadSelector: v1alpha1.WorkloadSelector{
+					Kinds:    []string{"StatefulSet"},
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "db"}},
+				},
+				Strategy:    constants.RestartedAtReloadStrategy,
+				PausePeriod: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+		},
+	}))
+	defer common.DeleteClusterReloaderPolicy("databases")
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedCode     int
+		expectedStrategy string
+	}{
+		{
+			name:             "Strategy of the cluster policy",
+			query:            "namespace=team-a&workload=StatefulSet/postgres",
+			expectedCode:     http.StatusOK,
+			expectedStrategy: constants.RestartedAtReloadStrategy,
+		},
+		{
+			name:             "Annotation overrides the cluster policy",
+			query:            "namespace=team-a&workload=statefulset/redis",
+			expectedCode:     http.StatusOK,
+			expectedStrategy: constants.AnnotationsReloadStrategy,
+		},
+		{
+			name:         "Missing workload",
+			query:        "namespace=team-a&workload=StatefulSet/missing",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Namespace not watched",
+			query:        "namespace=kube-system&workload=StatefulSet/postgres",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Workload without kind",
+			query:        "namespace=team-a&workload=postgres",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	defer SetNamespaceFilter(isWatchedNamespace)
+	SetNamespaceFilter(func(namespace string) bool { return namespace == "team-a" })
+
+	handler := newPolicyDebugHandler(kube.Clients{KubernetesClient: fakeClient})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler(recorder, httptest.NewRequest(http.MethodGet, PolicyDebugPath+"?"+tt.query, nil))
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var rules EffectiveRules
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rules))
+			assert.Equal(t, tt.expectedStrategy, rules.Strategy)
+			assert.Equal(t, "5m0s", rules.PausePeriod)
+			assert.Equal(t, []string{"databases"}, rules.Policies.ClusterPolicies)
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/internal/pkg/util"
+	"github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+const (
+	// PolicyStatusRefreshPeriod is how often the workloads selected by a policy are listed again for its status, in
+	// addition to changes of its spec
+	PolicyStatusRefreshPeriod = 5 * time.Minute
+	// maxMatchedWorkloads is the number of workloads the status of a policy lists as sample of the selected workloads
+	maxMatchedWorkloads = 20
+)
+
+var (
+	// policyStatusClock is the clock deciding whether the selected workloads of a policy are listed again
+	policyStatusClock clock.Clock = clock.RealClock{}
+	// policyWorkloadsListed holds when and for which generation the selected workloads of a policy were last listed,
+	// by namespace/name
+	policyWorkloadsListed sync.Map
+)
+
+// listedWorkloads is when the selected workloads of a policy were listed for a generation
+type listedWorkloads struct {
+	generation int64
+	time       time.Time
+}
+
+// ReloaderPolicyHandler contains an added or updated ReloaderPolicy
+type ReloaderPolicyHandler struct {
+	Resource    *v1alpha1.ReloaderPolicy
+	Collectors  metrics.Collectors
+	EnqueueTime time.Time // Time when this handler was added to the queue
+}
+
+// GetEnqueueTime returns when this handler was enqueued
+func (r ReloaderPolicyHandler) GetEnqueueTime() time.Time {
+	return r.EnqueueTime
+}
+
+// Handle applies a ReloaderPolicy to the workloads it selects and reports them in its status
+func (r ReloaderPolicyHandler) Handle() error {
+	if r.Resource == nil {
+		logrus.Errorf("ReloaderPolicy handler received nil resource")
+		return nil
+	}
+	return syncReloaderPolicy(kube.GetClients(), r.Resource)
+}
+
+// GetConfig returns the namespace and name of the ReloaderPolicy, it has no SHA
+func (r ReloaderPolicyHandler) GetConfig() (common.Config, string) {
+	return common.Config{Namespace: r.Resource.Namespace, ResourceName: r.Resource.Name}, ""
+}
+
+// ClusterReloaderPolicyHandler contains an added or updated ClusterReloaderPolicy
+type ClusterReloaderPolicyHandler struct {
+	Resource    *v1alpha1.ClusterReloaderPolicy
+	Collectors  metrics.Collectors
+	EnqueueTime time.Time // Time when this handler was added to the queue
+}
+
+// GetEnqueueTime returns when this handler was enqueued
+func (r ClusterReloaderPolicyHandler) GetEnqueueTime() time.Time {
+	return r.EnqueueTime
+}
+
+// Handle applies a ClusterReloaderPolicy to the workloads it selects and reports them in its status
+func (r ClusterReloaderPolicyHandler) Handle() error {
+	if r.Resource == nil {
+		logrus.Errorf("ClusterReloaderPolicy handler received nil resource")
+		return nil
+	}
+	return syncClusterReloaderPolicy(kube.GetClients(), r.Resource)
+}
+
+// GetConfig returns the name of the ClusterReloaderPolicy, it has no SHA
+func (r ClusterReloaderPolicyHandler) GetConfig() (common.Config, string) {
+	return common.Config{ResourceName: r.Resource.Name}, ""
+}
+
+// syncReloaderPolicy remembers a ReloaderPolicy and updates its status with the workloads it selects
+func syncReloaderPolicy(clients kube.Clients, policy *v1alpha1.ReloaderPolicy) error {
+	err := common.SetReloaderPolicy(policy)
+	if err != nil {
+		logrus.Errorf("Ignoring invalid ReloaderPolicy '%s' in namespace '%s': %v", policy.Name, policy.Namespace, err)
+	}
+	status := getPolicyStatus(policy.Namespace+"/"+policy.Name, policy.Generation, policy.Status, err, func() []string {
+		return getPolicyWorkloads(clients, policy.Namespace, func(rules common.PolicyRules) bool {
+			return slices.Contains(rules.Policies, policy.Name)
+		})
+	})
+	if apiequality.Semantic.DeepEqual(status, policy.Status) {
+		return nil
+	}
+
+	if clients.ReloaderClient == nil {
+		return fmt.Errorf("no client to update the status of ReloaderPolicy '%s' in namespace '%s'", policy.Name, policy.Namespace)
+	}
+	updated := policy.DeepCopy()
+	updated.Status = status
+	_, err = clients.ReloaderClient.ReloaderV1alpha1().ReloaderPolicies(policy.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// syncClusterReloaderPolicy remembers a ClusterReloaderPolicy and updates its status with the workloads it selects in
+// all namespaces
+func syncClusterReloaderPolicy(clients kube.Clients, policy *v1alpha1.ClusterReloaderPolicy) error {
+	err := common.SetClusterReloaderPolicy(policy)
+	if err != nil {
+		logrus.Errorf("Ignoring invalid ClusterReloaderPolicy '%s': %v", policy.Name, err)
+	}
+	status := getPolicyStatus("/"+policy.Name, policy.Generation, policy.Status, err, func() []string {
+		return getPolicyWorkloads(clients, metav1.NamespaceAll, func(rules common.PolicyRules) bool {
+			return slices.Contains(rules.ClusterPolicies, policy.Name)
+		})
+	})
+	if apiequality.Semantic.DeepEqual(status, policy.Status) {
+		return nil
+	}
+
+	if clients.ReloaderClient == nil {
+		return fmt.Errorf("no client to update the status of ClusterReloaderPolicy '%s'", policy.Name)
+	}
+	updated := policy.DeepCopy()
+	updated.Status = status
+	_, err = clients.ReloaderClient.ReloaderV1alpha1().ClusterReloaderPolicies().UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// getPolicyStatus returns the status of a policy given the error of remembering it. getWorkloads is only called for
+// valid policies whose spec changed or whose workloads were listed more than PolicyStatusRefreshPeriod ago, otherwise
+// the workloads of the current status are kept.
+func getPolicyStatus(key string, generation int64, current v1alpha1.ReloaderPolicyStatus, err error, getWorkloads func() []string) v1alpha1.ReloaderPolicyStatus {
+	condition := metav1.Condition{
+		Type:               v1alpha1.ReadyCondition,
+		ObservedGeneration: generation,
+	}
+	var matchedWorkloads []string
+	var matchedWorkloadCount int32
+
+	if err != nil {
+		policyWorkloadsListed.Delete(key)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.InvalidSpecReason
+		condition.Message = err.Error()
+	} else {
+		matchedWorkloads, matchedWorkloadCount = current.MatchedWorkloads, current.MatchedWorkloadCount
+		if shouldListPolicyWorkloads(key, generation) {
+			workloads := getWorkloads()
+			matchedWorkloads, matchedWorkloadCount = workloads[:min(len(workloads), maxMatchedWorkloads)], int32(len(workloads))
+			policyWorkloadsListed.Store(key, listedWorkloads{generation: generation, time: policyStatusClock.Now()})
+		}
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = v1alpha1.WorkloadsMatchedReason
+		condition.Message = fmt.Sprintf("Policy selects %d workloads", matchedWorkloadCount)
+		if matchedWorkloadCount == 0 {
+			condition.Reason = v1alpha1.NoWorkloadsMatchedReason
+			condition.Message = "Policy selects no workloads"
+		}
+	}
+
+	status := *current.DeepCopy()
+	status.ObservedGeneration = generation
+	status.MatchedWorkloads = matchedWorkloads
+	status.MatchedWorkloadCount = matchedWorkloadCount
+	meta.SetStatusCondition(&status.Conditions, condition)
+	return status
+}
+
+// shouldListPolicyWorkloads checks whether the selected workloads of a policy have to be listed, as they were not yet
+// listed for its generation or the last time is more than PolicyStatusRefreshPeriod ago
+func shouldListPolicyWorkloads(key string, generation int64) bool {
+	value, found := policyWorkloadsListed.Load(key)
+	if !found {
+		return true
+	}
+	listed := value.(listedWorkloads)
+	return listed.generation != generation || policyStatusClock.Since(listed.time) >= PolicyStatusRefreshPeriod
+}
+
+// ForgetReloaderPolicyStatus forgets when the selected workloads of a deleted ReloaderPolicy were listed
+func ForgetReloaderPolicyStatus(namespace, name string) {
+	policyWorkloadsListed.Delete(namespace + "/" + name)
+}
+
+// ForgetClusterReloaderPolicyStatus forgets when the selected workloads of a deleted ClusterReloaderPolicy were listed
+func ForgetClusterReloaderPolicyStatus(name string) {
+	ForgetReloaderPolicyStatus("", name)
+}
+
+// getPolicyWorkloads returns the workloads of a namespace whose policy rules are selected by a policy as kind/name, or
+// of all namespaces as namespace/kind/name
+func getPolicyWorkloads(clients kube.Clients, namespace string, isSelected func(common.PolicyRules) bool) []string {
+	var matched []string
+	for _, upgradeFuncs := range getReloadedWorkloadFuncs() {
+		for _, item := range upgradeFuncs.ItemsFunc(clients, namespace) {
+			accessor, err := meta.Accessor(item)
+			if err != nil || slices.Contains(options.NamespacesToIgnore, accessor.GetNamespace()) {
+				continue
+			}
+			rules := common.GetPolicyRules(accessor.GetNamespace(), upgradeFuncs.ResourceType, accessor.GetLabels())
+			if !isSelected(rules) {
+				continue
+			}
+			workload := upgradeFuncs.ResourceType + "/" + accessor.GetName()
+			if namespace == metav1.NamespaceAll {
+				workload = accessor.GetNamespace() + "/" + workload
+			}
+			matched = append(matched, workload)
+		}
+	}
+	slices.Sort(matched)
+	return matched
+}
+
+// getReloadedWorkloadFuncs returns the funcs of the workload kinds reloaded on changes, as reloaded by doRollingUpgrade
+func getReloadedWorkloadFuncs() []callbacks.RollingUpgradeFuncs {
+	ignoredWorkloadTypes, err := util.GetIgnoredWorkloadTypesList()
+	if err != nil {
+		ignoredWorkloadTypes = util.List{}
+	}
+
+	funcs := []callbacks.RollingUpgradeFuncs{GetDeploymentRollingUpgradeFuncs()}
+	if !ignoredWorkloadTypes.Contains("cronjobs") {
+		funcs = append(funcs, GetCronJobCreateJobFuncs())
+	}
+	if !ignoredWorkloadTypes.Contains("jobs") {
+		funcs = append(funcs, GetJobCreateJobFuncs())
+	}
+	funcs = append(funcs, GetDaemonSetRollingUpgradeFuncs(), GetStatefulSetRollingUpgradeFuncs())
+	if options.IsArgoRollouts == "true" {
+		funcs = append(funcs, GetArgoRolloutRollingUpgradeFuncs())
+	}
+	if kube.IsKnativeInstalled {
+		funcs = append(funcs, GetKnativeServiceRollingUpgradeFuncs())
+	}
+	if options.ReloadUnmanagedWorkloads {
+		funcs = append(funcs, GetReplicaSetRollingUpgradeFuncs(), GetPodReloadFuncs())
+	}
+	return funcs
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
+	reloaderfake "github.com/stakater/Reloader/pkg/client/clientset/versioned/fake"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func TestSyncReloaderPolicy(t *testing.T) {
+	deployments := []*appsv1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "payments-api", Namespace: "team-a", Labels: map[string]string{"app": "payments"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "orders-api", Namespace: "team-a", Labels: map[string]string{"app": "orders"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "payments-api", Namespace: "team-b", Labels: map[string]string{"app": "payments"}}},
+	}
+
+	tests := []struct {
+		name             string
+		spec             v1alpha1.ReloaderPolicySpec
+		expectedStatus   metav1.ConditionStatus
+		expectedReason   string
+		expectedWorkload []string
+	}{
+		{
+			name: "Selects workloads by labels",
+			spec: v1alpha1.ReloaderPolicySpec{
+				WorkloadSelector: v1alpha1.WorkloadSelector{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "payments"}},
+				},
+			},
+			expectedStatus:   metav1.ConditionTrue,
+			expectedReason:   v1alpha1.WorkloadsMatchedReason,
+			expectedWorkload: []string{"Deployment/payments-api"},
+		},
+		{
+			name: "Selects no workloads",
+			spec: v1alpha1.ReloaderPolicySpec{
+				WorkloadSelector: v1alpha1.WorkloadSelector{Kinds: []string{"StatefulSet"}},
+			},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1alpha1.NoWorkloadsMatchedReason,
+		},
+		{
+			name: "Invalid name regex",
+			spec: v1alpha1.ReloaderPolicySpec{
+				Sources: []v1alpha1.SourceSelector{{NameRegex: "payments-("}},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.InvalidSpecReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := testclient.NewClientset()
+			for _, deployment := range deployments {
+				_, err := fakeClient.AppsV1().Deployments(deployment.Namespace).Create(context.TODO(), deployment, metav1.CreateOptions{})
+				assert.NoError(t, err)
+			}
+			policy := &v1alpha1.ReloaderPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "team-a", Generation: 2},
+				Spec:       tt.spec,
+			}
+			reloaderClient := reloaderfake.NewSimpleClientset(policy)
+			defer common.DeleteReloaderPolicy(policy.Namespace, policy.Name)
+			defer ForgetReloaderPolicyStatus(policy.Namespace, policy.Name)
+
+			clients := kube.Clients{KubernetesClient: fakeClient, ReloaderClient: reloaderClient}
+			assert.NoError(t, syncReloaderPolicy(clients, policy))
+
+			updated, err := reloaderClient.ReloaderV1alpha1().ReloaderPolicies("team-a").Get(context.TODO(), "payments", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), updated.Status.ObservedGeneration)
+			assert.Equal(t, tt.expectedWorkload, updated.Status.MatchedWorkloads)
+			assert.Equal(t, int32(len(tt.expectedWorkload)), updated.Status.MatchedWorkloadCount)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.ReadyCondition)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, tt.expectedStatus, condition.Status)
+				assert.Equal(t, tt.expectedReason, condition.Reason)
+			}
+
+			// An unchanged status is not updated again
+			reloaderClient.ClearActions()
+			assert.NoError(t, syncReloaderPolicy(clients, updated))
+			assert.Empty(t, reloaderClient.Actions())
+		})
+	}
+}
+
+func TestSyncClusterReloaderPolicy(t *testing.T) {
+	fakeClient := testclient.NewClientset()
+	for _, deployment := range []*appsv1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "team-a", Labels: map[string]string{"tier": "db"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "team-b", Labels: map[string]string{"tier": "db"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-b", Labels: map[string]string{"tier": "web"}}},
+	} {
+		_, err := fakeClient.AppsV1().Deployments(deployment.Namespace).Create(context.TODO(), deployment, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	policy := &v1alpha1.ClusterReloaderPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "databases", Generation: 1},
+		Spec: v1alpha1.ClusterReloaderPolicySpec{
+			ReloaderPolicySpec: v1alpha1.ReloaderPolicySpec{
+				WorkloadSelector: v1alpha1.WorkloadSelector{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "db"}},
+				},
+			},
+		},
+	}
+	reloaderClient := reloaderfake.NewSimpleClientset(policy)
+	defer common.DeleteClusterReloaderPolicy(policy.Name)
+	defer ForgetClusterReloaderPolicyStatus(policy.Name)
+
+	clients := kube.Clients{KubernetesClient: fakeClient, ReloaderClient: reloaderClient}
+	assert.NoError(t, syncClusterReloaderPolicy(clients, policy))
+
+	updated, err := reloaderClient.ReloaderV1alpha1().ClusterReloaderPolicies().Get(context.TODO(), "databases", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a/Deployment/postgres", "team-b/Deployment/postgres"}, updated.Status.MatchedWorkloads)
+	condition := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.ReadyCondition)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, v1alpha1.WorkloadsMatchedReason, condition.Reason)
+	}
+}
+
+func TestSyncReloaderPolicy_MatchedWorkloads(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	policyStatusClock = fakeClock
+	defer func() { policyStatusClock = clock.RealClock{} }()
+
+	fakeClient := testclient.NewClientset()
+	createDeployment := func(name string) {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"}}
+		_, err := fakeClient.AppsV1().Deployments("team-a").Create(context.TODO(), deployment, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	for i := 0; i < 25; i++ {
+		createDeployment(fmt.Sprintf("api-%02d", i))
+	}
+	policy := &v1alpha1.ReloaderPolicy{ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "team-a", Generation: 1}}
+	reloaderClient := reloaderfake.NewSimpleClientset(policy)
+	defer common.DeleteReloaderPolicy(policy.Namespace, policy.Name)
+	defer ForgetReloaderPolicyStatus(policy.Namespace, policy.Name)
+	clients := kube.Clients{KubernetesClient: fakeClient, ReloaderClient: reloaderClient}
+	getPolicy := func() *v1alpha1.ReloaderPolicy {
+		updated, err := reloaderClient.ReloaderV1alpha1().ReloaderPolicies("team-a").Get(context.TODO(), "all", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return updated
+	}
+
+	// The status counts all selected workloads and lists a sample of them
+	assert.NoError(t, syncReloaderPolicy(clients, policy))
+	updated := getPolicy()
+	assert.Equal(t, int32(25), updated.Status.MatchedWorkloadCount)
+	assert.Len(t, updated.Status.MatchedWorkloads, maxMatchedWorkloads)
+	assert.Equal(t, "Deployment/api-00", updated.Status.MatchedWorkloads[0])
+
+	// The workloads are not listed again on every sync
+	createDeployment("api-25")
+	fakeClient.ClearActions()
+	assert.NoError(t, syncReloaderPolicy(clients, updated))
+	assert.Empty(t, fakeClient.Actions())
+	assert.Equal(t, int32(25), getPolicy().Status.MatchedWorkloadCount)
+
+	// They are listed again once the refresh period passed
+	fakeClock.Step(PolicyStatusRefreshPeriod)
+	assert.NoError(t, syncReloaderPolicy(clients, updated))
+	assert.Equal(t, int32(26), getPolicy().Status.MatchedWorkloadCount)
+}
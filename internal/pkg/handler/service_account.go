@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/stakater/Reloader/internal/pkg/callbacks"
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// defaultServiceAccountName is the ServiceAccount of pods not naming one
+const defaultServiceAccountName = "default"
+
+// serviceAccountLister reads ServiceAccounts from the caches of informers, set by SetServiceAccountLister
+var serviceAccountLister = func(namespace, name string) (*v1.ServiceAccount, bool) { return nil, false }
+
+// SetServiceAccountLister sets the func reading the ServiceAccounts of workloads from the caches of informers
+func SetServiceAccountLister(lister func(namespace, name string) (*v1.ServiceAccount, bool)) {
+	serviceAccountLister = lister
+}
+
+// isPodCredentialsReference checks whether a secret is used by the pods of a workload without containers referencing
+// it, i.e. as image pull secret of the workload or its ServiceAccount, or listed in the secrets of its ServiceAccount
+func isPodCredentialsReference(clients kube.Clients, upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config) bool {
+	if config.Type != constants.SecretEnvVarPostfix || upgradeFuncs.PodSpecFunc == nil {
+		return false
+	}
+	spec := upgradeFuncs.PodSpecFunc(item)
+	if spec == nil {
+		return false
+	}
+	if containsLocalObjectReference(spec.ImagePullSecrets, config.ResourceName) {
+		return true
+	}
+
+	serviceAccountName := spec.ServiceAccountName
+	if serviceAccountName == "" {
+		serviceAccountName = defaultServiceAccountName
+	}
+	serviceAccount, err := getServiceAccount(clients, config.Namespace, serviceAccountName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logrus.Errorf("Failed to get ServiceAccount '%s' in namespace '%s': %v", serviceAccountName, config.Namespace, err)
+		}
+		return false
+	}
+	return isReferencedByServiceAccount(serviceAccount, config.ResourceName)
+}
+
+// getServiceAccount returns a ServiceAccount from the caches of informers, or from the API server if it isn't cached,
+// e.g. before the informers synced
+func getServiceAccount(clients kube.Clients, namespace, name string) (*v1.ServiceAccount, error) {
+	if serviceAccount, found := serviceAccountLister(namespace, name); found {
+		return serviceAccount, nil
+	}
+	return clients.KubernetesClient.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func containsLocalObjectReference(references []v1.LocalObjectReference, name string) bool {
+	return slices.ContainsFunc(references, func(reference v1.LocalObjectReference) bool { return reference.Name == name })
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func TestIsPodCredentialsReference(t *testing.T) {
+	serviceAccounts := []*v1.ServiceAccount{
+		{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "default"},
+			ImagePullSecrets: []v1.LocalObjectReference{{Name: "default-registry"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Secrets:    []v1.ObjectReference{{Name: "app-token"}},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		resourceName       string
+		resourceType       string
+		imagePullSecrets   []v1.LocalObjectReference
+		serviceAccountName string
+		expected           bool
+	}{
+		{
+			name:             "Image pull secret of the workload",
+			resourceName:     "registry",
+			resourceType:     constants.SecretEnvVarPostfix,
+			imagePullSecrets: []v1.LocalObjectReference{{Name: "registry"}},
+			expected:         true,
+		},
+		{
+			name:         "Image pull secret of the default ServiceAccount",
+			resourceName: "default-registry",
+			resourceType: constants.SecretEnvVarPostfix,
+			expected:     true,
+		},
+		{
+			name:               "Secret of the ServiceAccount",
+			resourceName:       "app-token",
+			resourceType:       constants.SecretEnvVarPostfix,
+			serviceAccountName: "app",
+			expected:           true,
+		},
+		{
+			name:               "Image pull secret of another ServiceAccount",
+			resourceName:       "default-registry",
+			resourceType:       constants.SecretEnvVarPostfix,
+			serviceAccountName: "app",
+			expected:           false,
+		},
+		{
+			name:               "Missing ServiceAccount",
+			resourceName:       "app-token",
+			resourceType:       constants.SecretEnvVarPostfix,
+			serviceAccountName: "missing",
+			expected:           false,
+		},
+		{
+			name:             "ConfigMap with the name of an image pull secret",
+			resourceName:     "registry",
+			resourceType:     constants.ConfigmapEnvVarPostfix,
+			imagePullSecrets: []v1.LocalObjectReference{{Name: "registry"}},
+			expected:         false,
+		},
+	}
+
+	clients := kube.Clients{KubernetesClient: testclient.NewClientset(serviceAccounts[0], serviceAccounts[1])}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := createTestDeployment([]v1.Container{{Name: "app"}}, []v1.Container{}, []v1.Volume{})
+			deployment.Spec.Template.Spec.ImagePullSecrets = tt.imagePullSecrets
+			deployment.Spec.Template.Spec.ServiceAccountName = tt.serviceAccountName
+			config := common.Config{ResourceName: tt.resourceName, Type: tt.resourceType, Namespace: "default"}
+
+			result := isPodCredentialsReference(clients, GetDeploymentRollingUpgradeFuncs(), deployment, config)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestIsPodCredentialsReferenceFromCache(t *testing.T) {
+	defer SetServiceAccountLister(serviceAccountLister)
+	SetServiceAccountLister(func(namespace, name string) (*v1.ServiceAccount, bool) {
+		if namespace != "default" || name != "app" {
+			return nil, false
+		}
+		return &v1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			ImagePullSecrets: []v1.LocalObjectReference{{Name: "registry"}},
+		}, true
+	})
+	// The API server has no ServiceAccounts, so they can only be read from the cache
+	clients := kube.Clients{KubernetesClient: testclient.NewClientset()}
+
+	deployment := createTestDeployment([]v1.Container{{Name: "app"}}, []v1.Container{}, []v1.Volume{})
+	deployment.Spec.Template.Spec.ServiceAccountName = "app"
+	config := common.Config{ResourceName: "registry", Type: constants.SecretEnvVarPostfix, Namespace: "default"}
+
+	assert.True(t, isPodCredentialsReference(clients, GetDeploymentRollingUpgradeFuncs(), deployment, config))
+}
+
+func TestUpgradeResourceWithImagePullSecret(t *testing.T) {
+	originalWatchImagePullSecrets := options.WatchImagePullSecrets
+	defer func() { options.WatchImagePullSecrets = originalWatchImagePullSecrets }()
+
+	for _, watchImagePullSecrets := range []bool{false, true} {
+		options.WatchImagePullSecrets = watchImagePullSecrets
+
+		deployment := createTestDeployment([]v1.Container{{Name: "app"}}, []v1.Container{}, []v1.Volume{})
+		deployment.Annotations = map[string]string{options.ReloaderAutoAnnotation: "true"}
+		deployment.Spec.Template.Spec.ImagePullSecrets = []v1.LocalObjectReference{{Name: "registry"}}
+
+		fakeClient := testclient.NewClientset(deployment)
+		clients := kube.Clients{KubernetesClient: fakeClient}
+		config := common.Config{
+			ResourceName: "registry",
+			Type:         constants.SecretEnvVarPostfix,
+			SHAValue:     "abc123",
+			Namespace:    deployment.Namespace,
+			Annotation:   options.SecretUpdateOnChangeAnnotation,
+		}
+
+		updated, err := upgradeResource(clients, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment, false)
+		assert.NoError(t, err)
+		assert.Equal(t, watchImagePullSecrets, updated)
+
+		result, err := fakeClient.AppsV1().Deployments(deployment.Namespace).Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		if watchImagePullSecrets {
+			assert.Equal(t, []v1.EnvVar{{Name: "STAKATER_REGISTRY_SECRET", Value: "abc123"}}, result.Spec.Template.Spec.Containers[0].Env)
+		} else {
+			assert.Empty(t, result.Spec.Template.Spec.Containers[0].Env)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+// getSignalReload returns the in-place reload running the signal command in the pods
+func getSignalReload(annotations, podAnnotations map[string]string) inPlaceReload {
+	command := getSignalCommand(annotations, podAnnotations)
+	return inPlaceReload{
+		description:   fmt.Sprintf("run '%s'", strings.Join(command, " ")),
+		successReason: "Signaled",
+		failureReason: "SignalFailed",
+		reloadFunc: func(ctx context.Context, clients kube.Clients, target reloadTarget) error {
+			_, err := execInPod(ctx, clients, target.pod.Namespace, target.pod.Name, target.container, command)
+			return err
+		},
+	}
+}
+
+// getSignalCommand returns the command to run in the pods, defined by annotation on the workload or its pod template
+func getSignalCommand(annotations, podAnnotations map[string]string) []string {
+	command, found := getWorkloadAnnotation(annotations, podAnnotations, options.SignalCommandAnnotation)
+	if !found || strings.TrimSpace(command) == "" {
+		command = constants.DefaultSignalCommand
+	}
+	return strings.Fields(command)
+}
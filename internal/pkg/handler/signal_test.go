@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/crypto"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
+	"github.com/stakater/Reloader/internal/pkg/options"
+	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
+)
+
+func TestGetSignalCommand(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		podAnnotations map[string]string
+		expected       []string
+	}{
+		{
+			name:     "Default command",
+			expected: []string{"kill", "-HUP", "1"},
+		},
+		{
+			name:        "Workload annotation",
+			annotations: map[string]string{options.SignalCommandAnnotation: "nginx -s reload"},
+			expected:    []string{"nginx", "-s", "reload"},
+		},
+		{
+			name:           "Pod template annotation",
+			podAnnotations: map[string]string{options.SignalCommandAnnotation: "kill -USR1 1"},
+			expected:       []string{"kill", "-USR1", "1"},
+		},
+		{
+			name:        "Empty annotation uses default command",
+			annotations: map[string]string{options.SignalCommandAnnotation: " "},
+			expected:    []string{"kill", "-HUP", "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, getSignalCommand(tt.annotations, tt.podAnnotations))
+		})
+	}
+}
+
+func TestSignalPods(t *testing.T) {
+	fake := useFakeExec(t)
+
+	pod := createReloadTestPod("pod", v1.Volume{}, v1.VolumeMount{})
+	targets := []reloadTarget{
+		{pod: &pod, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")},
+	}
+	fake.setContent("pod", "old")
+	recorder := record.NewFakeRecorder(10)
+
+	done := make(chan struct{})
+	go func() {
+		reloadPods(context.Background(), kube.Clients{}, recorder, targets, getSignalReload(nil, nil), time.Minute)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	fake.mutex.Lock()
+	assert.Empty(t, fake.commands["pod"], "Pod should not be signaled before its files are updated")
+	fake.mutex.Unlock()
+
+	fake.setContent("pod", "new")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for pods to be signaled")
+	}
+
+	assert.Equal(t, [][]string{{"kill", "-HUP", "1"}}, fake.commands["pod"])
+	event := <-recorder.Events
+	assert.True(t, strings.HasPrefix(event, "Normal Signaled"), event)
+}
+
+func TestSignalPodsFailure(t *testing.T) {
+	fake := useFakeExec(t)
+	fake.err = errors.New("kill: executable file not found")
+
+	updated := createReloadTestPod("updated", v1.Volume{}, v1.VolumeMount{})
+	stale := createReloadTestPod("stale", v1.Volume{}, v1.VolumeMount{})
+	targets := []reloadTarget{
+		{pod: &updated, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")},
+		{pod: &stale, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")},
+	}
+	fake.setContent("updated", "new")
+	fake.setContent("stale", "old")
+	recorder := record.NewFakeRecorder(10)
+
+	reloadPods(context.Background(), kube.Clients{}, recorder, targets, getSignalReload(nil, nil), 100*time.Millisecond)
+
+	assert.Len(t, fake.commands["updated"], 1)
+	assert.Empty(t, fake.commands["stale"], "Pod should not be signaled if its files are never updated")
+	assert.Len(t, recorder.Events, 2)
+	for range 2 {
+		event := <-recorder.Events
+		assert.True(t, strings.HasPrefix(event, "Warning SignalFailed"), event)
+	}
+}
+
+func TestUpgradeResourceSignalStrategy(t *testing.T) {
+	fake := useFakeExec(t)
+
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-configmap", Namespace: "default"},
+		Data:       map[string]string{"app.conf": "new"},
+	}
+	volume := v1.Volume{
+		Name: "config",
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}},
+		},
+	}
+	mount := v1.VolumeMount{Name: "config", MountPath: "/etc/app"}
+	pod := createReloadTestPod("test-deployment-pod", volume, mount)
+
+	deployment := createTestDeployment([]v1.Container{{Name: "app", VolumeMounts: []v1.VolumeMount{mount}}}, []v1.Container{}, []v1.Volume{volume})
+	deployment.Annotations = map[string]string{
+		options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+		options.ReloadStrategyAnnotation:          constants.SignalReloadStrategy,
+	}
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	fakeClient := testclient.NewClientset(configmap, &pod, deployment)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+	config := common.Config{
+		ResourceName: "my-configmap",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    "default",
+		Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+	}
+	fake.setContent(pod.Name, "new")
+
+	updated, err := upgradeResource(clients, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment, false)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	assert.Eventually(t, func() bool {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		return len(fake.commands[pod.Name]) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	result, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Spec.Template.Spec.Containers[0].Env, "Signaled workload should not be rolled")
+	assert.Empty(t, result.Spec.Template.Annotations)
+}
+
+func TestUpgradeResourceSignalStrategyFallback(t *testing.T) {
+	originalStrategy := options.ReloadStrategy
+	defer func() { options.ReloadStrategy = originalStrategy }()
+	options.ReloadStrategy = constants.SignalReloadStrategy
+
+	deployment := createTestDeployment(
+		[]v1.Container{
+			{
+				Name: "app",
+				EnvFrom: []v1.EnvFromSource{
+					{
+						ConfigMapRef: &v1.ConfigMapEnvSource{
+							LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"},
+						},
+					},
+				},
+			},
+		},
+		[]v1.Container{},
+		[]v1.Volume{},
+	)
+	deployment.Annotations = map[string]string{
+		options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+	}
+
+	fakeClient := testclient.NewClientset(deployment)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+	config := common.Config{
+		ResourceName: "my-configmap",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    "default",
+		Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+	}
+
+	updated, err := upgradeResource(clients, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment, false)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	result, err := fakeClient.AppsV1().Deployments("default").Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.EnvVar{{Name: getEnvVarName("my-configmap", constants.ConfigmapEnvVarPostfix), Value: "sha256:abc123"}},
+		result.Spec.Template.Spec.Containers[0].Env, "Resource used as env vars should be reloaded with env-vars strategy")
+}
+
+func TestSignalPodsWithoutCat(t *testing.T) {
+	fake := useFakeExec(t)
+	fake.catErr = errors.New(`exec: "cat": executable file not found in $PATH`)
+
+	pod := createReloadTestPod("pod", v1.Volume{}, v1.VolumeMount{})
+	targets := []reloadTarget{
+		{pod: &pod, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")},
+	}
+	recorder := record.NewFakeRecorder(10)
+
+	done := make(chan bool)
+	go func() {
+		done <- reloadPods(context.Background(), kube.Clients{}, recorder, targets, getSignalReload(nil, nil), time.Minute)
+	}()
+
+	select {
+	case succeeded := <-done:
+		assert.False(t, succeeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pods without cat should fail without waiting for the timeout")
+	}
+	assert.Empty(t, fake.commands["pod"])
+	event := <-recorder.Events
+	assert.Contains(t, event, "requires cat in the container")
+}
+
+func TestStopInPlaceReloads(t *testing.T) {
+	fake := useFakeExec(t)
+
+	pod := createReloadTestPod("pod", v1.Volume{}, v1.VolumeMount{})
+	targets := []reloadTarget{
+		{pod: &pod, container: "app", files: []string{"/etc/app/a.conf"}, contentHash: crypto.GenerateSHA("new")},
+	}
+	fake.setContent("pod", "old")
+	recorder := record.NewFakeRecorder(10)
+
+	done := make(chan bool)
+	go func() {
+		done <- reloadPods(inPlaceReloads.context(), kube.Clients{}, recorder, targets, getSignalReload(nil, nil), time.Hour)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	StopInPlaceReloads()
+	fake.setContent("pod", "new")
+
+	select {
+	case succeeded := <-done:
+		assert.False(t, succeeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stopped reloads should not wait for the mounted files")
+	}
+	assert.Empty(t, fake.commands["pod"], "Pods should not be signaled once reloads are stopped")
+	assert.Empty(t, recorder.Events, "Stopped reloads should not be recorded as failures")
+}
+
+func TestRollResourceAfterFailedReloadStopped(t *testing.T) {
+	deployment := createTestDeployment([]v1.Container{{Name: "app"}}, []v1.Container{}, []v1.Volume{})
+	fakeClient := testclient.NewClientset(deployment)
+	config := common.Config{
+		ResourceName: "my-configmap",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    "default",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rollResourceAfterFailedReload(ctx, kube.Clients{KubernetesClient: fakeClient}, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment.Name, true)
+
+	assert.Empty(t, fakeClient.Actions(), "Workload should not be rolled once in-place reloads are stopped")
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+// secretLister reads secrets from the caches of the secrets controllers, set by SetSecretLister
+var secretLister = func(namespace, name string) (*v1.Secret, bool) { return nil, false }
+
+// SetSecretLister sets the func reading the secrets of custom resources, like the target secrets of ExternalSecrets,
+// from the caches of the secrets controllers
+func SetSecretLister(lister func(namespace, name string) (*v1.Secret, bool)) {
+	secretLister = lister
+}
+
+// getSecret returns a secret from the caches of the secrets controllers, or from the API server if it isn't cached,
+// e.g. when it doesn't match the resource selector
+func getSecret(client kubernetes.Interface, namespace, name string) (*v1.Secret, error) {
+	if secret, found := secretLister(namespace, name); found {
+		return secret, nil
+	}
+	return client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// syncReadyResource remembers the fingerprint of the secret a custom resource like an ExternalSecret or a Certificate
+// manages and returns whether a new sync of the resource changed it. Only Ready resources are considered, whose sync
+// changed or who just became Ready, syncs are identified by syncVersion. The fingerprint of resources seen for the
+// first time, i.e. added ones and those that only became Ready now, is only remembered. getFingerprint returns an
+// empty fingerprint if the secret can't be read yet.
+func syncReadyResource(fingerprints *sync.Map, resource, old *unstructured.Unstructured, syncVersion func(*unstructured.Unstructured) string, getFingerprint func() (string, error)) (string, bool, error) {
+	if !isResourceReady(resource) {
+		return "", false, nil
+	}
+	if old != nil && isResourceReady(old) && syncVersion(old) == syncVersion(resource) {
+		return "", false, nil
+	}
+
+	fingerprint, err := getFingerprint()
+	if err != nil || fingerprint == "" {
+		return "", false, err
+	}
+
+	previous, found := fingerprints.Swap(getResourceKey(resource), fingerprint)
+	return fingerprint, old != nil && found && previous != fingerprint, nil
+}
+
+// getResourceKey returns the namespace/name key of a custom resource
+func getResourceKey(resource *unstructured.Unstructured) string {
+	return resource.GetNamespace() + "/" + resource.GetName()
+}
+
+// isResourceReady checks whether the Ready condition of a custom resource is true
+func isResourceReady(resource *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(resource.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Ready" {
+			return condition["status"] == string(v1.ConditionTrue)
+		}
+	}
+	return false
+}
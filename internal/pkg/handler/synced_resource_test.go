@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func createTestReadyResource(version string, ready string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "app", "namespace": "default"},
+		"status": map[string]interface{}{
+			"version":    version,
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": ready}},
+		},
+	}}
+}
+
+func TestSyncReadyResource(t *testing.T) {
+	syncVersion := func(resource *unstructured.Unstructured) string {
+		version, _, _ := unstructured.NestedString(resource.Object, "status", "version")
+		return version
+	}
+
+	tests := []struct {
+		name        string
+		remembered  string
+		resource    *unstructured.Unstructured
+		old         *unstructured.Unstructured
+		fingerprint string
+		err         error
+		expected    bool
+		wantErr     bool
+		remembers   string
+	}{
+		{
+			name:        "Added resource is only remembered",
+			remembered:  "a",
+			resource:    createTestReadyResource("1", "True"),
+			fingerprint: "b",
+			remembers:   "b",
+		},
+		{
+			name:        "First Ready update is only remembered",
+			resource:    createTestReadyResource("1", "True"),
+			old:         createTestReadyResource("", "False"),
+			fingerprint: "a",
+			remembers:   "a",
+		},
+		{
+			name:        "New sync with changed fingerprint",
+			remembered:  "a",
+			resource:    createTestReadyResource("2", "True"),
+			old:         createTestReadyResource("1", "True"),
+			fingerprint: "b",
+			expected:    true,
+			remembers:   "b",
+		},
+		{
+			name:        "New sync with same fingerprint",
+			remembered:  "a",
+			resource:    createTestReadyResource("2", "True"),
+			old:         createTestReadyResource("1", "True"),
+			fingerprint: "a",
+			remembers:   "a",
+		},
+		{
+			name:        "Same sync",
+			remembered:  "a",
+			resource:    createTestReadyResource("1", "True"),
+			old:         createTestReadyResource("1", "True"),
+			fingerprint: "b",
+			remembers:   "a",
+		},
+		{
+			name:        "Not Ready",
+			remembered:  "a",
+			resource:    createTestReadyResource("2", "False"),
+			old:         createTestReadyResource("1", "True"),
+			fingerprint: "b",
+			remembers:   "a",
+		},
+		{
+			name:       "Secret not found yet",
+			remembered: "a",
+			resource:   createTestReadyResource("2", "True"),
+			old:        createTestReadyResource("1", "True"),
+			remembers:  "a",
+		},
+		{
+			name:       "Failed to read secret",
+			remembered: "a",
+			resource:   createTestReadyResource("2", "True"),
+			old:        createTestReadyResource("1", "True"),
+			err:        errors.New("forbidden"),
+			wantErr:    true,
+			remembers:  "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fingerprints sync.Map
+			if tt.remembered != "" {
+				fingerprints.Store("default/app", tt.remembered)
+			}
+
+			_, changed, err := syncReadyResource(&fingerprints, tt.resource, tt.old, syncVersion, func() (string, error) {
+				return tt.fingerprint, tt.err
+			})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, changed)
+			remembered, _ := fingerprints.Load("default/app")
+			assert.Equal(t, tt.remembers, remembered)
+		})
+	}
+}
@@ -7,14 +7,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"slices"
 	"strings"
 	"time"
 
+	argorolloutv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	"github.com/parnurzeal/gorequest"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	app "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -48,6 +50,7 @@ func GetDeploymentRollingUpgradeFuncs() callbacks.RollingUpgradeFuncs {
 		PatchFunc:          callbacks.PatchDeployment,
 		PatchTemplatesFunc: callbacks.GetPatchTemplates,
 		VolumesFunc:        callbacks.GetDeploymentVolumes,
+		PodSpecFunc:        callbacks.GetDeploymentPodSpec,
 		ResourceType:       "Deployment",
 		SupportsPatch:      true,
 	}
@@ -62,12 +65,13 @@ func GetCronJobCreateJobFuncs() callbacks.RollingUpgradeFuncs {
 		PodAnnotationsFunc: callbacks.GetCronJobPodAnnotations,
 		ContainersFunc:     callbacks.GetCronJobContainers,
 		InitContainersFunc: callbacks.GetCronJobInitContainers,
-		UpdateFunc:         callbacks.CreateJobFromCronjob,
+		UpdateFunc:         callbacks.UpdateCronJob,
 		PatchFunc:          callbacks.PatchCronJob,
-		PatchTemplatesFunc: func() callbacks.PatchTemplates { return callbacks.PatchTemplates{} },
+		PatchTemplatesFunc: callbacks.GetCronJobPatchTemplates,
 		VolumesFunc:        callbacks.GetCronJobVolumes,
+		PodSpecFunc:        callbacks.GetCronJobPodSpec,
 		ResourceType:       "CronJob",
-		SupportsPatch:      false,
+		SupportsPatch:      true,
 	}
 }
 
@@ -84,6 +88,7 @@ func GetJobCreateJobFuncs() callbacks.RollingUpgradeFuncs {
 		PatchFunc:          callbacks.PatchJob,
 		PatchTemplatesFunc: func() callbacks.PatchTemplates { return callbacks.PatchTemplates{} },
 		VolumesFunc:        callbacks.GetJobVolumes,
+		PodSpecFunc:        callbacks.GetJobPodSpec,
 		ResourceType:       "Job",
 		SupportsPatch:      false,
 	}
@@ -102,6 +107,7 @@ func GetDaemonSetRollingUpgradeFuncs() callbacks.RollingUpgradeFuncs {
 		PatchFunc:          callbacks.PatchDaemonSet,
 		PatchTemplatesFunc: callbacks.GetPatchTemplates,
 		VolumesFunc:        callbacks.GetDaemonSetVolumes,
+		PodSpecFunc:        callbacks.GetDaemonSetPodSpec,
 		ResourceType:       "DaemonSet",
 		SupportsPatch:      true,
 	}
@@ -120,6 +126,7 @@ func GetStatefulSetRollingUpgradeFuncs() callbacks.RollingUpgradeFuncs {
 		PatchFunc:          callbacks.PatchStatefulSet,
 		PatchTemplatesFunc: callbacks.GetPatchTemplates,
 		VolumesFunc:        callbacks.GetStatefulSetVolumes,
+		PodSpecFunc:        callbacks.GetStatefulSetPodSpec,
 		ResourceType:       "StatefulSet",
 		SupportsPatch:      true,
 	}
@@ -136,9 +143,67 @@ func GetArgoRolloutRollingUpgradeFuncs() callbacks.RollingUpgradeFuncs {
 		InitContainersFunc: callbacks.GetRolloutInitContainers,
 		UpdateFunc:         callbacks.UpdateRollout,
 		PatchFunc:          callbacks.PatchRollout,
-		PatchTemplatesFunc: func() callbacks.PatchTemplates { return callbacks.PatchTemplates{} },
+		PatchTemplatesFunc: callbacks.GetPatchTemplates,
 		VolumesFunc:        callbacks.GetRolloutVolumes,
+		PodSpecFunc:        callbacks.GetRolloutPodSpec,
 		ResourceType:       "Rollout",
+		SupportsPatch:      true,
+	}
+}
+
+// GetKnativeServiceRollingUpgradeFuncs returns all callback funcs for a Knative Service
+func GetKnativeServiceRollingUpgradeFuncs() callbacks.RollingUpgradeFuncs {
+	return callbacks.RollingUpgradeFuncs{
+		ItemFunc:           callbacks.GetKnativeServiceItem,
+		ItemsFunc:          callbacks.GetKnativeServiceItems,
+		AnnotationsFunc:    callbacks.GetKnativeServiceAnnotations,
+		PodAnnotationsFunc: callbacks.GetKnativeServicePodAnnotations,
+		ContainersFunc:     callbacks.GetKnativeServiceContainers,
+		InitContainersFunc: callbacks.GetKnativeServiceInitContainers,
+		UpdateFunc:         callbacks.UpdateKnativeService,
+		PatchFunc:          callbacks.PatchKnativeService,
+		PatchTemplatesFunc: callbacks.GetPatchTemplates,
+		VolumesFunc:        callbacks.GetKnativeServiceVolumes,
+		PodSpecFunc:        callbacks.GetKnativeServicePodSpec,
+		ResourceType:       "KnativeService",
+		SupportsPatch:      true,
+	}
+}
+
+// GetReplicaSetRollingUpgradeFuncs returns all callback funcs for a replicaSet without managing controller
+func GetReplicaSetRollingUpgradeFuncs() callbacks.RollingUpgradeFuncs {
+	return callbacks.RollingUpgradeFuncs{
+		ItemFunc:           callbacks.GetReplicaSetItem,
+		ItemsFunc:          callbacks.GetReplicaSetItems,
+		AnnotationsFunc:    callbacks.GetReplicaSetAnnotations,
+		PodAnnotationsFunc: callbacks.GetReplicaSetPodAnnotations,
+		ContainersFunc:     callbacks.GetReplicaSetContainers,
+		InitContainersFunc: callbacks.GetReplicaSetInitContainers,
+		UpdateFunc:         callbacks.UpdateReplicaSet,
+		PatchFunc:          callbacks.PatchReplicaSet,
+		PatchTemplatesFunc: callbacks.GetPatchTemplates,
+		VolumesFunc:        callbacks.GetReplicaSetVolumes,
+		PodSpecFunc:        callbacks.GetReplicaSetPodSpec,
+		ResourceType:       "ReplicaSet",
+		SupportsPatch:      true,
+	}
+}
+
+// GetPodReloadFuncs returns all callback funcs for a pod without managing controller
+func GetPodReloadFuncs() callbacks.RollingUpgradeFuncs {
+	return callbacks.RollingUpgradeFuncs{
+		ItemFunc:           callbacks.GetPodItem,
+		ItemsFunc:          callbacks.GetPodItems,
+		AnnotationsFunc:    callbacks.GetPodAnnotations,
+		PodAnnotationsFunc: callbacks.GetPodAnnotations,
+		ContainersFunc:     callbacks.GetPodContainers,
+		InitContainersFunc: callbacks.GetPodInitContainers,
+		UpdateFunc:         callbacks.ReloadPod,
+		PatchFunc:          callbacks.PatchPod,
+		PatchTemplatesFunc: func() callbacks.PatchTemplates { return callbacks.PatchTemplates{} },
+		VolumesFunc:        callbacks.GetPodVolumes,
+		PodSpecFunc:        callbacks.GetPodPodSpec,
+		ResourceType:       "Pod",
 		SupportsPatch:      false,
 	}
 }
@@ -226,6 +291,24 @@ func doRollingUpgrade(config common.Config, collectors metrics.Collectors, recor
 		}
 	}
 
+	if kube.IsKnativeInstalled {
+		err = rollingUpgrade(clients, config, GetKnativeServiceRollingUpgradeFuncs(), collectors, recorder, invoke)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.ReloadUnmanagedWorkloads {
+		err = rollingUpgrade(clients, config, GetReplicaSetRollingUpgradeFuncs(), collectors, recorder, invoke)
+		if err != nil {
+			return err
+		}
+		err = rollingUpgrade(clients, config, GetPodReloadFuncs(), collectors, recorder, invoke)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -239,16 +322,26 @@ func rollingUpgrade(clients kube.Clients, config common.Config, upgradeFuncs cal
 
 // PerformAction invokes the deployment if there is any change in configmap or secret data
 func PerformAction(clients kube.Clients, config common.Config, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, strategy invokeStrategy) error {
-	items := upgradeFuncs.ItemsFunc(clients, config.Namespace)
+	items := getWatchingItems(clients, config, upgradeFuncs)
 
 	// Record workloads scanned
 	collectors.RecordWorkloadsScanned(upgradeFuncs.ResourceType, len(items))
 
 	matchedCount := 0
 	for _, item := range items {
+		itemConfig, err := getWorkloadConfig(config, item)
+		if err != nil {
+			logrus.Errorf("Skipping %s watching '%s' in namespace '%s': %v", upgradeFuncs.ResourceType, config.ResourceName, config.Namespace, err)
+			continue
+		}
 		matched, err := retryOnConflict(retry.DefaultRetry, func(fetchResource bool) (bool, error) {
-			return upgradeResource(clients, config, upgradeFuncs, collectors, recorder, strategy, item, fetchResource)
+			return upgradeResource(clients, itemConfig, upgradeFuncs, collectors, recorder, strategy, item, fetchResource)
 		})
+		if err != nil && itemConfig.SourceNamespace != "" {
+			// A workload of another namespace must not stop the workloads of the namespace of the resource from reloading
+			logrus.Errorf("Skipping %s in namespace '%s' watching '%s' in namespace '%s': %v", upgradeFuncs.ResourceType, itemConfig.Namespace, config.ResourceName, config.Namespace, err)
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -301,6 +394,10 @@ func upgradeResource(clients kube.Clients, config common.Config, upgradeFuncs ca
 		if err != nil {
 			return false, err
 		}
+		accessor, err = meta.Accessor(resource)
+		if err != nil {
+			return false, err
+		}
 	}
 	if config.Type == constants.SecretProviderClassEnvVarPostfix {
 		populateAnnotationsFromSecretProviderClass(clients, &config)
@@ -308,7 +405,7 @@ func upgradeResource(clients kube.Clients, config common.Config, upgradeFuncs ca
 
 	annotations := upgradeFuncs.AnnotationsFunc(resource)
 	podAnnotations := upgradeFuncs.PodAnnotationsFunc(resource)
-	result := common.ShouldReload(config, upgradeFuncs.ResourceType, annotations, podAnnotations, common.GetCommandLineOptions())
+	result := common.ShouldReload(config, upgradeFuncs.ResourceType, annotations, podAnnotations, accessor.GetLabels(), common.GetCommandLineOptions())
 
 	for _, reloadErr := range result.Errors {
 		logrus.Errorf("Skipping invalid reload annotation on %s '%s' in namespace '%s': %v", upgradeFuncs.ResourceType, resourceName, config.Namespace, reloadErr)
@@ -319,7 +416,108 @@ func upgradeResource(clients kube.Clients, config common.Config, upgradeFuncs ca
 		return false, nil
 	}
 
-	strategyResult := strategy(upgradeFuncs, resource, config, result.AutoReload)
+	// Pull secrets and ServiceAccount secrets aren't referenced by a container, the first container carries the
+	// reload as for resources named in reload annotations
+	if result.AutoReload && options.WatchImagePullSecrets && getContainerUsingResource(upgradeFuncs, resource, config, true) == nil &&
+		isPodCredentialsReference(clients, upgradeFuncs, resource, config) {
+		result.AutoReload = false
+	}
+
+	policyRules := common.GetPolicyRules(config.Namespace, upgradeFuncs.ResourceType, accessor.GetLabels())
+	if policyRules.Debounce != nil && policyRules.Debounce.Duration > 0 {
+		reload := func() error {
+			if _, err := upgradeResource(clients, config, upgradeFuncs, collectors, recorder, strategy, resource, true); err != nil {
+				logrus.Errorf("Failed to reload %s '%s' in namespace '%s' after debouncing: %v", upgradeFuncs.ResourceType, resourceName, config.Namespace, err)
+				return err
+			}
+			return nil
+		}
+		if debouncer.Debounce(getDebounceKey(upgradeFuncs.ResourceType, resourceName, config), policyRules.Debounce.Duration, reload) {
+			logrus.Infof("Debouncing reload of %s '%s' in namespace '%s' on changes in '%s' of type '%s' for %s",
+				upgradeFuncs.ResourceType, resourceName, config.Namespace, config.ResourceName, config.Type, policyRules.Debounce.Duration)
+			collectors.RecordSkipped("debounced")
+			return false, nil
+		}
+	}
+
+	if job, ok := resource.(*batchv1.Job); ok {
+		reason, err := getJobRerunSkipReason(job)
+		if err != nil {
+			logrus.Errorf("Failed to rerun Job '%s' in namespace '%s': %v", resourceName, config.Namespace, err)
+			return false, err
+		}
+		if reason != "" {
+			logrus.Infof("Skipping rerun of Job '%s' in namespace '%s' according to its reload policy: %s", resourceName, config.Namespace, reason)
+			collectors.RecordSkipped(reason)
+			return false, nil
+		}
+	}
+	if rollout, ok := resource.(*argorolloutv1alpha1.Rollout); ok {
+		deferred, err := deferRolloutReload(clients, config, upgradeFuncs, collectors, recorder, strategy, rollout)
+		if err != nil {
+			logrus.Errorf("Failed to reload Rollout '%s' in namespace '%s': %v", resourceName, config.Namespace, err)
+			return false, err
+		}
+		if deferred {
+			return false, nil
+		}
+	}
+
+	if isGitOpsSyncEnabled(upgradeFuncs, resourceName, config.Namespace, annotations, podAnnotations) {
+		if owner, found := getGitOpsOwner(accessor.GetLabels(), annotations); found {
+			return syncGitOpsOwner(clients, config, upgradeFuncs, collectors, recorder, resource, owner, actionStartTime)
+		}
+	}
+
+	reloadStrategy := getReloadStrategy(upgradeFuncs, resourceName, config.Namespace, annotations, podAnnotations, policyRules.Strategy)
+	if isInPlaceReloadStrategy(reloadStrategy) {
+		reload, err := getInPlaceReload(reloadStrategy, annotations, podAnnotations)
+		switch {
+		case err != nil:
+			logrus.Errorf("Invalid %s reload configuration on %s '%s' in namespace '%s': %v", reloadStrategy, upgradeFuncs.ResourceType, resourceName, config.Namespace, err)
+		case !canReloadInPlace(upgradeFuncs, resource, config):
+			logrus.Infof("'%s' of type '%s' is not mounted as a volume by %s '%s' in namespace '%s', it can't be reloaded in place",
+				config.ResourceName, config.Type, upgradeFuncs.ResourceType, resourceName, config.Namespace)
+		default:
+			rollOnFailure := func(ctx context.Context) {
+				rollResourceAfterFailedReload(ctx, clients, config, upgradeFuncs, collectors, recorder, strategy, resourceName, result.AutoReload)
+			}
+			return reloadWorkloadInPlace(clients, config, upgradeFuncs, collectors, recorder, resource, reload, rollOnFailure, actionStartTime)
+		}
+		reloadStrategy = getFallbackReloadStrategy()
+	}
+
+	// ReplicaSets never replace pods after their template changed, so their pods are always deleted
+	_, isReplicaSet := resource.(*app.ReplicaSet)
+	if reloadStrategy == constants.DeletePodsReloadStrategy || isReplicaSet {
+		if reloadStrategy == constants.DeletePodsReloadStrategy {
+			reloadStrategy = getFallbackReloadStrategy()
+		}
+		updated, err := rollResource(clients, config, upgradeFuncs, collectors, recorder, strategy, resource, result.AutoReload, reloadStrategy, actionStartTime)
+		// Workloads with another update strategy are restarted by their controller once the pod template is updated
+		if updated && err == nil && isOnDeleteWorkload(resource) {
+			deleteWorkloadPods(clients, config.Namespace, upgradeFuncs, recorder, resource)
+		}
+		return updated, err
+	}
+	if isOnDeleteWorkload(resource) {
+		logrus.Warnf("%s '%s' in namespace '%s' uses the OnDelete update strategy, its pods are only restarted by Reloader with reload strategy '%s'",
+			upgradeFuncs.ResourceType, resourceName, config.Namespace, constants.DeletePodsReloadStrategy)
+	}
+
+	return rollResource(clients, config, upgradeFuncs, collectors, recorder, strategy, resource, result.AutoReload, reloadStrategy, actionStartTime)
+}
+
+// rollResource triggers a rolling update of the workload by applying the given reload strategy to its pod template
+func rollResource(clients kube.Clients, config common.Config, upgradeFuncs callbacks.RollingUpgradeFuncs, collectors metrics.Collectors, recorder record.EventRecorder, strategy invokeStrategy, resource runtime.Object, autoReload bool, reloadStrategy string, actionStartTime time.Time) (bool, error) {
+	accessor, err := meta.Accessor(resource)
+	if err != nil {
+		return false, err
+	}
+	resourceName := accessor.GetName()
+	annotations := upgradeFuncs.AnnotationsFunc(resource)
+
+	strategyResult := strategy(upgradeFuncs, resource, config, autoReload, reloadStrategy)
 
 	if strategyResult.Result != constants.Updated {
 		collectors.RecordSkipped("strategy_not_updated")
@@ -327,18 +525,19 @@ func upgradeResource(clients kube.Clients, config common.Config, upgradeFuncs ca
 	}
 
 	// find correct annotation and update the resource
-	pauseInterval, foundPauseInterval := annotations[options.PauseDeploymentAnnotation]
+	pauseInterval, foundPauseInterval := getPausePeriod(upgradeFuncs.ResourceType, config.Namespace, annotations, accessor.GetLabels())
 
 	if foundPauseInterval {
-		deployment, ok := resource.(*app.Deployment)
-		if !ok {
-			logrus.Warnf("Annotation '%s' only applicable for deployments", options.PauseDeploymentAnnotation)
-		} else {
-			_, err = PauseDeployment(deployment, clients, config.Namespace, pauseInterval)
-			if err != nil {
-				logrus.Errorf("Failed to pause deployment '%s' in namespace '%s': %v", resourceName, config.Namespace, err)
-				return true, err
-			}
+		pausedResource, err := PauseWorkload(resource, clients, config.Namespace, pauseInterval)
+		if err != nil {
+			logrus.Errorf("Failed to pause %s '%s' in namespace '%s': %v", upgradeFuncs.ResourceType, resourceName, config.Namespace, err)
+			return true, err
+		}
+		// Pausing changed the resource version, so workloads that are updated rather than patched
+		// need the strategy applied again on the latest version to avoid a conflict
+		if !upgradeFuncs.SupportsPatch && pausedResource != nil && pausedResource != resource {
+			resource = pausedResource
+			strategyResult = strategy(upgradeFuncs, resource, config, autoReload, reloadStrategy)
 		}
 	}
 
@@ -370,11 +569,10 @@ func upgradeResource(clients kube.Clients, config common.Config, upgradeFuncs ca
 		collectors.Reloaded.With(prometheus.Labels{"success": "true"}).Inc()
 		collectors.ReloadedByNamespace.With(prometheus.Labels{"success": "true", "namespace": config.Namespace}).Inc()
 		collectors.RecordAction(upgradeFuncs.ResourceType, "success", actionLatency)
-		alert_on_reload, ok := os.LookupEnv("ALERT_ON_RELOAD")
 		if recorder != nil {
 			recorder.Event(resource, v1.EventTypeNormal, "Reloaded", message)
 		}
-		if ok && alert_on_reload == "true" {
+		if options.AlertOnReload {
 			msg := fmt.Sprintf(
 				"Reloader detected changes in *%s* of type *%s* in namespace *%s*. Hence reloaded *%s* of type *%s* in namespace *%s*",
 				config.ResourceName, config.Type, config.Namespace, resourceName, upgradeFuncs.ResourceType, config.Namespace)
@@ -522,11 +720,53 @@ type InvokeStrategyResult struct {
 	Patch  *Patch
 }
 
-type invokeStrategy func(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config, autoReload bool) InvokeStrategyResult
+type invokeStrategy func(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config, autoReload bool, reloadStrategy string) InvokeStrategyResult
+
+// ReloadStrategies contains the valid values of the reload strategy flag and annotation
+var ReloadStrategies = []string{constants.EnvVarsReloadStrategy, constants.AnnotationsReloadStrategy, constants.RestartedAtReloadStrategy, constants.SignalReloadStrategy, constants.HTTPReloadStrategy, constants.DeletePodsReloadStrategy}
 
-func invokeReloadStrategy(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config, autoReload bool) InvokeStrategyResult {
-	if options.ReloadStrategy == constants.AnnotationsReloadStrategy {
+// getReloadStrategy returns the reload strategy of a workload. The global strategy can be overridden per workload
+// by the reload strategy annotation on the workload or its pod template, by the ReloaderPolicies selecting it, or on its
+// namespace if namespace annotations are enabled. Workloads defining an HTTP reload endpoint use the http strategy
+// unless the annotation says otherwise.
+func getReloadStrategy(upgradeFuncs callbacks.RollingUpgradeFuncs, resourceName, namespace string, annotations, podAnnotations map[string]string, policyStrategy string) string {
+	reloadStrategy, found := getWorkloadAnnotation(annotations, podAnnotations, options.ReloadStrategyAnnotation)
+	if !found {
+		if _, ok := getWorkloadAnnotation(annotations, podAnnotations, options.HTTPReloadAnnotation); ok {
+			return constants.HTTPReloadStrategy
+		}
+		reloadStrategy, found = policyStrategy, policyStrategy != ""
+		if !found && options.EnableNamespaceAnnotations {
+			reloadStrategy, found = common.GetNamespaceAnnotations(namespace)[options.ReloadStrategyAnnotation]
+		}
+		if !found {
+			return options.ReloadStrategy
+		}
+	}
+
+	if !slices.Contains(ReloadStrategies, reloadStrategy) {
+		logrus.Warnf("Invalid value '%s' of annotation '%s' on %s '%s' in namespace '%s', using reload strategy '%s'. Valid values are: %s",
+			reloadStrategy, options.ReloadStrategyAnnotation, upgradeFuncs.ResourceType, resourceName, namespace, options.ReloadStrategy, strings.Join(ReloadStrategies, ", "))
+		return options.ReloadStrategy
+	}
+	return reloadStrategy
+}
+
+// getFallbackReloadStrategy returns the reload strategy updating the pod template of workloads that can't be reloaded
+// in place, or whose pods are deleted by Reloader afterwards
+func getFallbackReloadStrategy() string {
+	if isInPlaceReloadStrategy(options.ReloadStrategy) || options.ReloadStrategy == constants.DeletePodsReloadStrategy {
+		return constants.EnvVarsReloadStrategy
+	}
+	return options.ReloadStrategy
+}
+
+func invokeReloadStrategy(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config, autoReload bool, reloadStrategy string) InvokeStrategyResult {
+	switch reloadStrategy {
+	case constants.AnnotationsReloadStrategy:
 		return updatePodAnnotations(upgradeFuncs, item, config, autoReload)
+	case constants.RestartedAtReloadStrategy:
+		return updatePodRestartedAt(upgradeFuncs, item, config, autoReload)
 	}
 	return updateContainerEnvVars(upgradeFuncs, item, config, autoReload)
 }
@@ -563,9 +803,74 @@ func updatePodAnnotations(upgradeFuncs callbacks.RollingUpgradeFuncs, item runti
 	return InvokeStrategyResult{constants.Updated, &Patch{Type: patchtypes.StrategicMergePatchType, Bytes: patch}}
 }
 
+// updatePodRestartedAt sets the restartedAt annotation on the pod template the same way `kubectl rollout restart`
+// does, so tools already ignoring that annotation don't report the reload as drift. The hashes of the resources the
+// workload was restarted for are remembered in the restarted-for annotation of the workload, outside of the pod
+// template, so the workload isn't restarted again for unchanged resources, e.g. on add events after Reloader restarted.
+func updatePodRestartedAt(upgradeFuncs callbacks.RollingUpgradeFuncs, item runtime.Object, config common.Config, autoReload bool) InvokeStrategyResult {
+	container := getContainerUsingResource(upgradeFuncs, item, config, autoReload)
+	if container == nil {
+		return InvokeStrategyResult{constants.NoContainerFound, nil}
+	}
+
+	pa := upgradeFuncs.PodAnnotationsFunc(item)
+	annotations := upgradeFuncs.AnnotationsFunc(item)
+	if pa == nil || annotations == nil {
+		return InvokeStrategyResult{constants.NotUpdated, nil}
+	}
+
+	restartedForKey := getRestartedForAnnotationKey()
+	restartedFor := map[string]string{}
+	if value, found := annotations[restartedForKey]; found {
+		if err := json.Unmarshal([]byte(value), &restartedFor); err != nil {
+			logrus.Warnf("Ignoring invalid annotation '%s': %v", restartedForKey, err)
+			restartedFor = map[string]string{}
+		}
+	}
+	envVar := getEnvVarName(config.ResourceName, config.Type)
+	if sha := restartedFor[envVar]; sha == config.SHAValue || (config.LegacySHAValue != "" && sha == config.LegacySHAValue) {
+		return InvokeStrategyResult{constants.NotUpdated, nil}
+	}
+	restartedFor[envVar] = config.SHAValue
+	restartedForValue, err := json.Marshal(restartedFor)
+	if err != nil {
+		logrus.Errorf("Failed to create annotation '%s' for %s! error = %v", restartedForKey, config.ResourceName, err)
+		return InvokeStrategyResult{constants.NotUpdated, nil}
+	}
+
+	restartedAt := time.Now().Format(time.RFC3339)
+	pa[constants.RestartedAtAnnotation] = restartedAt
+	annotations[restartedForKey] = string(restartedForValue)
+
+	var patch []byte
+	if upgradeFuncs.SupportsPatch {
+		workloadPatch, err := json.Marshal(map[string]any{
+			"metadata": map[string]any{"annotations": map[string]string{restartedForKey: string(restartedForValue)}},
+		})
+		if err == nil {
+			template := upgradeFuncs.PatchTemplatesFunc().AnnotationTemplate
+			patch, err = mergePatches(fmt.Appendf(nil, template, constants.RestartedAtAnnotation, restartedAt), workloadPatch)
+		}
+		if err != nil {
+			logrus.Errorf("Failed to create annotation '%s' for %s! error = %v", restartedForKey, config.ResourceName, err)
+			return InvokeStrategyResult{constants.NotUpdated, nil}
+		}
+	}
+
+	return InvokeStrategyResult{constants.Updated, &Patch{Type: patchtypes.StrategicMergePatchType, Bytes: patch}}
+}
+
+func getRestartedForAnnotationKey() string {
+	return fmt.Sprintf("%s/%s",
+		constants.ReloaderAnnotationPrefix,
+		constants.RestartedForAnnotation,
+	)
+}
+
 func secretProviderClassAnnotationReloaded(oldAnnotations map[string]string, newConfig common.Config) bool {
 	annotation := oldAnnotations[getReloaderAnnotationKey()]
-	return strings.Contains(annotation, newConfig.ResourceName) && strings.Contains(annotation, newConfig.SHAValue)
+	return strings.Contains(annotation, newConfig.ResourceName) &&
+		(strings.Contains(annotation, newConfig.SHAValue) || (newConfig.LegacySHAValue != "" && strings.Contains(annotation, newConfig.LegacySHAValue)))
 }
 
 func getReloaderAnnotationKey() string {
@@ -618,7 +923,14 @@ func updateContainerEnvVars(upgradeFuncs callbacks.RollingUpgradeFuncs, item run
 		return InvokeStrategyResult{constants.NoContainerFound, nil}
 	}
 
-	if config.Type == constants.SecretProviderClassEnvVarPostfix && secretProviderClassEnvReloaded(upgradeFuncs.ContainersFunc(item), envVar, config.SHAValue) {
+	if config.Type == constants.SecretProviderClassEnvVarPostfix && secretProviderClassEnvReloaded(upgradeFuncs.ContainersFunc(item), envVar, config) {
+		return InvokeStrategyResult{constants.NotUpdated, nil}
+	}
+
+	// Workloads reloaded before the hash algorithm was changed keep the SHA-1 of resources unchanged since
+	if config.LegacySHAValue != "" && slices.ContainsFunc(container.Env, func(env v1.EnvVar) bool {
+		return env.Name == envVar && env.Value == config.LegacySHAValue
+	}) {
 		return InvokeStrategyResult{constants.NotUpdated, nil}
 	}
 
@@ -658,11 +970,11 @@ func updateEnvVar(container *v1.Container, envVar string, shaData string) consta
 	return constants.NoEnvVarFound
 }
 
-func secretProviderClassEnvReloaded(containers []v1.Container, envVar string, shaData string) bool {
+func secretProviderClassEnvReloaded(containers []v1.Container, envVar string, config common.Config) bool {
 	for _, container := range containers {
 		for _, env := range container.Env {
 			if env.Name == envVar {
-				return env.Value == shaData
+				return env.Value == config.SHAValue || (config.LegacySHAValue != "" && env.Value == config.LegacySHAValue)
 			}
 		}
 	}
@@ -684,6 +996,31 @@ func populateAnnotationsFromSecretProviderClass(clients kube.Clients, config *co
 	config.ResourceAnnotations = annotations
 }
 
+// mergePatches merges two strategic merge patches setting fields of objects, the second one wins on conflicts
+func mergePatches(first, second []byte) ([]byte, error) {
+	var merged, other map[string]interface{}
+	if err := json.Unmarshal(first, &merged); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(second, &other); err != nil {
+		return nil, err
+	}
+	mergeObjects(merged, other)
+	return json.Marshal(merged)
+}
+
+func mergeObjects(target, source map[string]interface{}) {
+	for key, value := range source {
+		sourceObject, isObject := value.(map[string]interface{})
+		targetObject, targetIsObject := target[key].(map[string]interface{})
+		if isObject && targetIsObject {
+			mergeObjects(targetObject, sourceObject)
+			continue
+		}
+		target[key] = value
+	}
+}
+
 func jsonEscape(toEscape string) (string, error) {
 	data, err := json.Marshal(toEscape)
 	if err != nil {
@@ -1,22 +1,30 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	patchtypes "k8s.io/apimachinery/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/util/retry"
 
 	"github.com/stakater/Reloader/internal/pkg/callbacks"
 	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/internal/pkg/metrics"
 	"github.com/stakater/Reloader/internal/pkg/options"
 	"github.com/stakater/Reloader/pkg/common"
+	"github.com/stakater/Reloader/pkg/kube"
 )
 
 func TestGetRollingUpgradeFuncs(t *testing.T) {
@@ -36,7 +44,7 @@ func TestGetRollingUpgradeFuncs(t *testing.T) {
 			name:          "CronJob",
 			getFuncs:      GetCronJobCreateJobFuncs,
 			resourceType:  "CronJob",
-			supportsPatch: false,
+			supportsPatch: true,
 		},
 		{
 			name:          "Job",
@@ -60,7 +68,13 @@ func TestGetRollingUpgradeFuncs(t *testing.T) {
 			name:          "ArgoRollout",
 			getFuncs:      GetArgoRolloutRollingUpgradeFuncs,
 			resourceType:  "Rollout",
-			supportsPatch: false,
+			supportsPatch: true,
+		},
+		{
+			name:          "KnativeService",
+			getFuncs:      GetKnativeServiceRollingUpgradeFuncs,
+			resourceType:  "KnativeService",
+			supportsPatch: true,
 		},
 	}
 
@@ -1249,6 +1263,18 @@ func TestSecretProviderClassAnnotationReloaded(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "Annotation contains SPC name and legacy SHA",
+			oldAnnotations: map[string]string{
+				"reloader.stakater.com/last-reloaded-from": `{"name":"my-spc","sha":"abc123"}`,
+			},
+			newConfig: common.Config{
+				ResourceName:   "my-spc",
+				SHAValue:       "sha256:def456",
+				LegacySHAValue: "abc123",
+			},
+			expected: true,
+		},
 		{
 			name: "Annotation contains different SPC name",
 			oldAnnotations: map[string]string{
@@ -1300,9 +1326,6 @@ func TestSecretProviderClassAnnotationReloaded(t *testing.T) {
 }
 
 func TestInvokeReloadStrategy(t *testing.T) {
-	originalStrategy := options.ReloadStrategy
-	defer func() { options.ReloadStrategy = originalStrategy }()
-
 	deployment := createTestDeployment(
 		[]v1.Container{
 			{
@@ -1331,6 +1354,9 @@ func TestInvokeReloadStrategy(t *testing.T) {
 		InitContainersFunc: func(item runtime.Object) []v1.Container {
 			return deployment.Spec.Template.Spec.InitContainers
 		},
+		AnnotationsFunc: func(item runtime.Object) map[string]string {
+			return deployment.Annotations
+		},
 		PodAnnotationsFunc: func(item runtime.Object) map[string]string {
 			return deployment.Spec.Template.Annotations
 		},
@@ -1362,15 +1388,415 @@ func TestInvokeReloadStrategy(t *testing.T) {
 			autoReload:     false,
 			expectResult:   constants.Updated,
 		},
+		{
+			name:           "RestartedAt strategy",
+			reloadStrategy: constants.RestartedAtReloadStrategy,
+			autoReload:     false,
+			expectResult:   constants.Updated,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			options.ReloadStrategy = tt.reloadStrategy
+			deployment.Annotations = map[string]string{}
 			deployment.Spec.Template.Annotations = map[string]string{}
 
-			result := invokeReloadStrategy(funcs, deployment, config, tt.autoReload)
+			result := invokeReloadStrategy(funcs, deployment, config, tt.autoReload, tt.reloadStrategy)
 			assert.Equal(t, tt.expectResult, result.Result)
 		})
 	}
 }
+
+func TestUpdateContainerEnvVarsWithLegacySHA(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		expectResult constants.Result
+	}{
+		{
+			name:         "Legacy SHA-1 of unchanged resource",
+			envValue:     "abc123",
+			expectResult: constants.NotUpdated,
+		},
+		{
+			name:         "Legacy SHA-1 of changed resource",
+			envValue:     "old123",
+			expectResult: constants.Updated,
+		},
+	}
+
+	config := common.Config{
+		ResourceName:   "my-configmap",
+		Type:           constants.ConfigmapEnvVarPostfix,
+		SHAValue:       "sha256:def456",
+		LegacySHAValue: "abc123",
+		Namespace:      "default",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := createTestDeployment(
+				[]v1.Container{
+					{
+						Name: "app",
+						EnvFrom: []v1.EnvFromSource{
+							{
+								ConfigMapRef: &v1.ConfigMapEnvSource{
+									LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"},
+								},
+							},
+						},
+						Env: []v1.EnvVar{{Name: "STAKATER_MY_CONFIGMAP_CONFIGMAP", Value: tt.envValue}},
+					},
+				},
+				[]v1.Container{},
+				[]v1.Volume{},
+			)
+
+			result := updateContainerEnvVars(GetDeploymentRollingUpgradeFuncs(), deployment, config, false)
+			assert.Equal(t, tt.expectResult, result.Result)
+		})
+	}
+}
+
+func TestUpdatePodRestartedAt(t *testing.T) {
+	deployment := createTestDeployment(
+		[]v1.Container{
+			{
+				Name: "app",
+				EnvFrom: []v1.EnvFromSource{
+					{
+						ConfigMapRef: &v1.ConfigMapEnvSource{
+							LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"},
+						},
+					},
+				},
+			},
+		},
+		[]v1.Container{},
+		[]v1.Volume{},
+	)
+	deployment.Spec.Template.Annotations = map[string]string{}
+
+	funcs := callbacks.RollingUpgradeFuncs{
+		VolumesFunc: func(item runtime.Object) []v1.Volume {
+			return deployment.Spec.Template.Spec.Volumes
+		},
+		ContainersFunc: func(item runtime.Object) []v1.Container {
+			return deployment.Spec.Template.Spec.Containers
+		},
+		InitContainersFunc: func(item runtime.Object) []v1.Container {
+			return deployment.Spec.Template.Spec.InitContainers
+		},
+		AnnotationsFunc: func(item runtime.Object) map[string]string {
+			if deployment.Annotations == nil {
+				deployment.Annotations = map[string]string{}
+			}
+			return deployment.Annotations
+		},
+		PodAnnotationsFunc: func(item runtime.Object) map[string]string {
+			return deployment.Spec.Template.Annotations
+		},
+		PatchTemplatesFunc: callbacks.GetPatchTemplates,
+		SupportsPatch:      true,
+	}
+
+	t.Run("Sets restartedAt annotation", func(t *testing.T) {
+		config := common.Config{
+			ResourceName: "my-configmap",
+			Type:         constants.ConfigmapEnvVarPostfix,
+			SHAValue:     "sha256:abc123",
+			Namespace:    "default",
+		}
+
+		result := updatePodRestartedAt(funcs, deployment, config, false)
+		assert.Equal(t, constants.Updated, result.Result)
+
+		restartedAt := deployment.Spec.Template.Annotations[constants.RestartedAtAnnotation]
+		_, err := time.Parse(time.RFC3339, restartedAt)
+		assert.NoError(t, err)
+		assert.NotContains(t, deployment.Spec.Template.Annotations, getReloaderAnnotationKey())
+
+		assert.Len(t, deployment.Spec.Template.Annotations, 1, "Only restartedAt should be set on the pod template")
+
+		restartedFor := deployment.Annotations[getRestartedForAnnotationKey()]
+		assert.Equal(t, `{"STAKATER_MY_CONFIGMAP_CONFIGMAP":"sha256:abc123"}`, restartedFor)
+
+		assert.Equal(t, patchtypes.StrategicMergePatchType, result.Patch.Type)
+		expectedPatch := fmt.Sprintf(`{"metadata":{"annotations":{"%s":"{\"STAKATER_MY_CONFIGMAP_CONFIGMAP\":\"sha256:abc123\"}"}},"spec":{"template":{"metadata":{"annotations":{"%s":"%s"}}}}}`,
+			getRestartedForAnnotationKey(), constants.RestartedAtAnnotation, restartedAt)
+		assert.Equal(t, expectedPatch, string(result.Patch.Bytes))
+	})
+
+	t.Run("Already restarted for the hash", func(t *testing.T) {
+		deployment.Annotations = map[string]string{getRestartedForAnnotationKey(): `{"STAKATER_MY_CONFIGMAP_CONFIGMAP":"sha256:abc123"}`}
+		deployment.Spec.Template.Annotations = map[string]string{constants.RestartedAtAnnotation: "2026-01-01T00:00:00Z"}
+		config := common.Config{
+			ResourceName: "my-configmap",
+			Type:         constants.ConfigmapEnvVarPostfix,
+			SHAValue:     "sha256:abc123",
+			Namespace:    "default",
+		}
+
+		result := updatePodRestartedAt(funcs, deployment, config, false)
+		assert.Equal(t, constants.NotUpdated, result.Result)
+		assert.Equal(t, "2026-01-01T00:00:00Z", deployment.Spec.Template.Annotations[constants.RestartedAtAnnotation])
+	})
+
+	t.Run("Restarted for another hash", func(t *testing.T) {
+		deployment.Annotations = map[string]string{getRestartedForAnnotationKey(): `{"STAKATER_MY_CONFIGMAP_CONFIGMAP":"sha256:old","STAKATER_MY_SECRET_SECRET":"sha256:secret"}`}
+		deployment.Spec.Template.Annotations = map[string]string{constants.RestartedAtAnnotation: "2026-01-01T00:00:00Z"}
+		config := common.Config{
+			ResourceName: "my-configmap",
+			Type:         constants.ConfigmapEnvVarPostfix,
+			SHAValue:     "sha256:abc123",
+			Namespace:    "default",
+		}
+
+		result := updatePodRestartedAt(funcs, deployment, config, false)
+		assert.Equal(t, constants.Updated, result.Result)
+		assert.NotEqual(t, "2026-01-01T00:00:00Z", deployment.Spec.Template.Annotations[constants.RestartedAtAnnotation])
+		assert.Equal(t, `{"STAKATER_MY_CONFIGMAP_CONFIGMAP":"sha256:abc123","STAKATER_MY_SECRET_SECRET":"sha256:secret"}`,
+			deployment.Annotations[getRestartedForAnnotationKey()])
+	})
+
+	t.Run("No container using resource", func(t *testing.T) {
+		config := common.Config{
+			ResourceName: "other-configmap",
+			Type:         constants.ConfigmapEnvVarPostfix,
+			SHAValue:     "sha256:abc123",
+			Namespace:    "default",
+		}
+
+		result := updatePodRestartedAt(funcs, deployment, config, true)
+		assert.Equal(t, constants.NoContainerFound, result.Result)
+		assert.Nil(t, result.Patch)
+	})
+}
+
+func TestGetReloadStrategy(t *testing.T) {
+	originalStrategy := options.ReloadStrategy
+	originalEnableNamespaceAnnotations := options.EnableNamespaceAnnotations
+	defer func() {
+		options.ReloadStrategy = originalStrategy
+		options.EnableNamespaceAnnotations = originalEnableNamespaceAnnotations
+	}()
+	options.ReloadStrategy = constants.EnvVarsReloadStrategy
+	common.SetNamespaceAnnotations("team-a", map[string]string{options.ReloadStrategyAnnotation: constants.RestartedAtReloadStrategy})
+	defer common.DeleteNamespaceAnnotations("team-a")
+
+	tests := []struct {
+		name                       string
+		namespace                  string
+		enableNamespaceAnnotations bool
+		annotations                map[string]string
+		podAnnotations             map[string]string
+		policyStrategy             string
+		expected                   string
+	}{
+		{
+			name:     "No annotation uses global strategy",
+			expected: constants.EnvVarsReloadStrategy,
+		},
+		{
+			name:        "Workload annotation overrides global strategy",
+			annotations: map[string]string{options.ReloadStrategyAnnotation: constants.AnnotationsReloadStrategy},
+			expected:    constants.AnnotationsReloadStrategy,
+		},
+		{
+			name:           "Pod template annotation overrides global strategy",
+			podAnnotations: map[string]string{options.ReloadStrategyAnnotation: constants.RestartedAtReloadStrategy},
+			expected:       constants.RestartedAtReloadStrategy,
+		},
+		{
+			name:           "Workload annotation takes precedence over pod template annotation",
+			annotations:    map[string]string{options.ReloadStrategyAnnotation: constants.AnnotationsReloadStrategy},
+			podAnnotations: map[string]string{options.ReloadStrategyAnnotation: constants.RestartedAtReloadStrategy},
+			expected:       constants.AnnotationsReloadStrategy,
+		},
+		{
+			name:        "Invalid annotation falls back to global strategy",
+			annotations: map[string]string{options.ReloadStrategyAnnotation: "invalid"},
+			expected:    constants.EnvVarsReloadStrategy,
+		},
+		{
+			name:                       "Namespace annotation overrides global strategy",
+			namespace:                  "team-a",
+			enableNamespaceAnnotations: true,
+			expected:                   constants.RestartedAtReloadStrategy,
+		},
+		{
+			name:      "Namespace annotation ignored unless enabled",
+			namespace: "team-a",
+			expected:  constants.EnvVarsReloadStrategy,
+		},
+		{
+			name:                       "Workload annotation takes precedence over namespace annotation",
+			namespace:                  "team-a",
+			enableNamespaceAnnotations: true,
+			annotations:                map[string]string{options.ReloadStrategyAnnotation: constants.AnnotationsReloadStrategy},
+			expected:                   constants.AnnotationsReloadStrategy,
+		},
+		{
+			name:           "Policy strategy overrides global strategy",
+			policyStrategy: constants.AnnotationsReloadStrategy,
+			expected:       constants.AnnotationsReloadStrategy,
+		},
+		{
+			name:           "Workload annotation takes precedence over policy strategy",
+			annotations:    map[string]string{options.ReloadStrategyAnnotation: constants.RestartedAtReloadStrategy},
+			policyStrategy: constants.AnnotationsReloadStrategy,
+			expected:       constants.RestartedAtReloadStrategy,
+		},
+		{
+			name:                       "Policy strategy takes precedence over namespace annotation",
+			namespace:                  "team-a",
+			enableNamespaceAnnotations: true,
+			policyStrategy:             constants.AnnotationsReloadStrategy,
+			expected:                   constants.AnnotationsReloadStrategy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options.EnableNamespaceAnnotations = tt.enableNamespaceAnnotations
+			namespace := tt.namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+			result := getReloadStrategy(GetDeploymentRollingUpgradeFuncs(), "test-deployment", namespace, tt.annotations, tt.podAnnotations, tt.policyStrategy)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestUpgradeResourceReloadStrategyOverride(t *testing.T) {
+	originalStrategy := options.ReloadStrategy
+	defer func() { options.ReloadStrategy = originalStrategy }()
+	options.ReloadStrategy = constants.EnvVarsReloadStrategy
+
+	deployment := createTestDeployment(
+		[]v1.Container{
+			{
+				Name: "app",
+				EnvFrom: []v1.EnvFromSource{
+					{
+						ConfigMapRef: &v1.ConfigMapEnvSource{
+							LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"},
+						},
+					},
+				},
+			},
+		},
+		[]v1.Container{},
+		[]v1.Volume{},
+	)
+	deployment.Annotations = map[string]string{
+		options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+		options.ReloadStrategyAnnotation:          constants.AnnotationsReloadStrategy,
+	}
+
+	fakeClient := testclient.NewClientset(deployment)
+	clients := kube.Clients{KubernetesClient: fakeClient}
+	config := common.Config{
+		ResourceName: "my-configmap",
+		Type:         constants.ConfigmapEnvVarPostfix,
+		SHAValue:     "sha256:abc123",
+		Namespace:    deployment.Namespace,
+		Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+	}
+
+	updated, err := upgradeResource(clients, config, GetDeploymentRollingUpgradeFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, deployment, false)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	result, err := fakeClient.AppsV1().Deployments(deployment.Namespace).Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Contains(t, result.Spec.Template.Annotations, getReloaderAnnotationKey())
+	assert.Empty(t, result.Spec.Template.Spec.Containers[0].Env, "Env vars strategy should not be used")
+}
+
+func TestUpgradeResourceCronJobReloadPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		expectedEnv  bool
+		expectedJobs int
+	}{
+		{
+			name:         "Trigger",
+			policy:       constants.TriggerCronJobReloadPolicy,
+			expectedJobs: 1,
+		},
+		{
+			name:        "Update template",
+			policy:      constants.UpdateTemplateCronJobReloadPolicy,
+			expectedEnv: true,
+		},
+		{
+			name:         "Update template and trigger",
+			policy:       constants.UpdateAndTriggerCronJobReloadPolicy,
+			expectedEnv:  true,
+			expectedJobs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob := &batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "nightly",
+					Namespace: "default",
+					Annotations: map[string]string{
+						options.ConfigmapUpdateOnChangeAnnotation: "my-configmap",
+						options.CronJobReloadPolicyAnnotation:     tt.policy,
+					},
+				},
+				Spec: batchv1.CronJobSpec{
+					JobTemplate: batchv1.JobTemplateSpec{
+						Spec: batchv1.JobSpec{
+							Template: v1.PodTemplateSpec{
+								Spec: v1.PodSpec{
+									Containers: []v1.Container{
+										{
+											Name: "batch",
+											EnvFrom: []v1.EnvFromSource{
+												{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "my-configmap"}}},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fakeClient := testclient.NewClientset(cronJob)
+			config := common.Config{
+				ResourceName: "my-configmap",
+				Type:         constants.ConfigmapEnvVarPostfix,
+				SHAValue:     "sha256:abc123",
+				Namespace:    "default",
+				Annotation:   options.ConfigmapUpdateOnChangeAnnotation,
+			}
+
+			updated, err := upgradeResource(kube.Clients{KubernetesClient: fakeClient}, config, GetCronJobCreateJobFuncs(), metrics.NewCollectors(), nil, invokeReloadStrategy, cronJob, false)
+			assert.NoError(t, err)
+			assert.True(t, updated)
+
+			result, err := fakeClient.BatchV1().CronJobs("default").Get(context.TODO(), "nightly", metav1.GetOptions{})
+			assert.NoError(t, err)
+			env := result.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env
+			if tt.expectedEnv {
+				assert.Equal(t, []v1.EnvVar{{Name: getEnvVarName("my-configmap", constants.ConfigmapEnvVarPostfix), Value: "sha256:abc123"}}, env,
+					"Job template should be updated for the next scheduled run")
+			} else {
+				assert.Empty(t, env, "Job template should not be updated")
+			}
+
+			jobs, err := fakeClient.BatchV1().Jobs("default").List(context.TODO(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, jobs.Items, tt.expectedJobs)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 
 	"github.com/stakater/Reloader/internal/pkg/controller"
+	"github.com/stakater/Reloader/internal/pkg/handler"
 
 	coordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
 )
@@ -72,10 +73,16 @@ func RunLeaderElection(lock *resourcelock.LeaseLock, ctx context.Context, cancel
 							ctrl.Run(1, stopCh)
 						}(ctrl, stopChannels[i])
 					}
+					// Resume timers of workloads paused by the previous leader only lived in its memory
+					go handler.RestorePauseTimers(controllerNamespaces(controllers), c.Done(), controller.PauseTimersSynced(controllers)...)
 				},
 				OnStoppedLeading: func() {
 					logrus.Info("no longer leader, shutting down")
 					stopControllers(stopChannels)
+					handler.StopPauseTimers()
+					handler.StopRolloutReloads()
+					handler.StopPodDeletions()
+					handler.StopInPlaceReloads()
 					// Wait for all controller.Run goroutines to fully exit.
 					// controller.Run blocks until its informer and workers exit,
 					// so this guarantees no controller goroutine is still running
@@ -127,3 +134,11 @@ func healthz(w http.ResponseWriter, req *http.Request) {
 
 	w.WriteHeader(http.StatusInternalServerError)
 }
+
+func controllerNamespaces(controllers []*controller.Controller) []string {
+	var namespaces []string
+	for _, c := range controllers {
+		namespaces = append(namespaces, c.Namespace())
+	}
+	return namespaces
+}
@@ -137,6 +137,21 @@ func TestRunLeaderElectionWithControllers(t *testing.T) {
 		if k == constants.SecretProviderClassController {
 			continue
 		}
+		// Skip External Secrets controller when External Secrets Operator is not installed
+		// (mirrors production behavior in startReloader).
+		if k == constants.ExternalSecretController {
+			continue
+		}
+		// Skip cert-manager controller when cert-manager is not installed
+		// (mirrors production behavior in startReloader).
+		if k == constants.CertificateController {
+			continue
+		}
+		// Skip ReloaderPolicy controllers when the ReloaderPolicy CRDs are not installed
+		// (mirrors production behavior in startReloader).
+		if k == constants.ReloaderPolicyController || k == constants.ClusterReloaderPolicyController {
+			continue
+		}
 		c, err := controller.NewController(testutil.Clients.KubernetesClient, k, testutil.Namespace, []string{}, "", "", metrics.NewCollectors())
 		if err != nil {
 			logrus.Fatalf("%s", err)
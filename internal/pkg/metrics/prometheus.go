@@ -75,6 +75,8 @@ type Collectors struct {
 	EventsProcessed   *prometheus.CounterVec   // Events processed by type and result
 	WorkloadsScanned  *prometheus.CounterVec   // Workloads scanned by kind
 	WorkloadsMatched  *prometheus.CounterVec   // Workloads matched for reload by kind
+
+	CertificateNotAfter *prometheus.GaugeVec // Expiry of watched cert-manager certificates
 }
 
 // RecordReload records a reload event with the given success status and namespace.
@@ -197,6 +199,22 @@ func (c *Collectors) RecordWorkloadsMatched(kind string, count int) {
 	c.WorkloadsMatched.With(prometheus.Labels{"kind": kind}).Add(float64(count))
 }
 
+// SetCertificateNotAfter records the expiry of the certificate issued for a watched cert-manager Certificate.
+func (c *Collectors) SetCertificateNotAfter(namespace string, certificate string, notAfter time.Time) {
+	if c == nil || c.CertificateNotAfter == nil {
+		return
+	}
+	c.CertificateNotAfter.With(prometheus.Labels{"namespace": namespace, "certificate": certificate}).Set(float64(notAfter.Unix()))
+}
+
+// DeleteCertificateNotAfter removes the expiry of a deleted cert-manager Certificate.
+func (c *Collectors) DeleteCertificateNotAfter(namespace string, certificate string) {
+	if c == nil || c.CertificateNotAfter == nil {
+		return
+	}
+	c.CertificateNotAfter.Delete(prometheus.Labels{"namespace": namespace, "certificate": certificate})
+}
+
 func NewCollectors() Collectors {
 	// Existing metrics (preserved)
 	reloaded := prometheus.NewCounterVec(
@@ -345,6 +363,15 @@ func NewCollectors() Collectors {
 		[]string{"kind"},
 	)
 
+	certificateNotAfter := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "reloader",
+			Name:      "certificate_not_after_timestamp_seconds",
+			Help:      "Expiry of the certificates issued for watched cert-manager Certificates, in seconds since epoch.",
+		},
+		[]string{"namespace", "certificate"},
+	)
+
 	return Collectors{
 		Reloaded:            reloaded,
 		ReloadedByNamespace: reloadedByNamespace,
@@ -364,6 +391,8 @@ func NewCollectors() Collectors {
 		EventsProcessed:   eventsProcessed,
 		WorkloadsScanned:  workloadsScanned,
 		WorkloadsMatched:  workloadsMatched,
+
+		CertificateNotAfter: certificateNotAfter,
 	}
 }
 
@@ -385,6 +414,7 @@ func SetupPrometheusEndpoint() Collectors {
 	prometheus.MustRegister(collectors.EventsProcessed)
 	prometheus.MustRegister(collectors.WorkloadsScanned)
 	prometheus.MustRegister(collectors.WorkloadsMatched)
+	prometheus.MustRegister(collectors.CertificateNotAfter)
 
 	if os.Getenv("METRICS_COUNT_BY_NAMESPACE") == "enabled" {
 		prometheus.MustRegister(collectors.ReloadedByNamespace)
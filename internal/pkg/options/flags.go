@@ -1,6 +1,11 @@
 package options
 
-import "github.com/stakater/Reloader/internal/pkg/constants"
+import (
+	"sync"
+	"time"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+)
 
 type ArgoRolloutStrategy int
 
@@ -23,6 +28,12 @@ var (
 	// SecretProviderClassUpdateOnChangeAnnotation is an annotation to detect changes in
 	// secretproviderclasses specified by name
 	SecretProviderClassUpdateOnChangeAnnotation = "secretproviderclass.reloader.stakater.com/reload"
+	// ExternalSecretUpdateOnChangeAnnotation is an annotation to detect successful syncs of
+	// externalsecrets specified by name that changed the data of their target secret
+	ExternalSecretUpdateOnChangeAnnotation = "externalsecret.reloader.stakater.com/reload"
+	// CertificateUpdateOnChangeAnnotation is an annotation to detect renewals of
+	// certificates specified by name that changed the certificate in their secret
+	CertificateUpdateOnChangeAnnotation = "certificate.reloader.stakater.com/reload"
 	// ReloaderAutoAnnotation is an annotation to detect changes in secrets/configmaps
 	ReloaderAutoAnnotation = "reloader.stakater.com/auto"
 	// IgnoreResourceAnnotation is an annotation to ignore changes in secrets/configmaps
@@ -45,8 +56,48 @@ var (
 	// SearchMatchAnnotation is an annotation to tag secrets to be found with
 	// AutoSearchAnnotation
 	SearchMatchAnnotation = "reloader.stakater.com/match"
+	// ContentTypeAnnotation is an annotation on configmaps to hash a canonical form of their data, so cosmetic edits
+	// of structured data don't trigger reloads. Valid values are "yaml", "json", "properties" and "auto"
+	ContentTypeAnnotation = "reloader.stakater.com/content-type"
 	// RolloutStrategyAnnotation is an annotation to define rollout update strategy
 	RolloutStrategyAnnotation = "reloader.stakater.com/rollout-strategy"
+	// RolloutInProgressAnnotation is an annotation to define how an Argo Rollout is reloaded while an update is in progress.
+	// Valid values are "skip", "queue" and "restart-after-promotion"
+	RolloutInProgressAnnotation = "reloader.stakater.com/rollout-in-progress"
+	// ReloadStrategyAnnotation is an annotation to override the reload strategy for a single workload
+	ReloadStrategyAnnotation = "reloader.stakater.com/reload-strategy"
+	// SignalCommandAnnotation is an annotation to define the command run in the pods by the signal reload strategy
+	SignalCommandAnnotation = "reloader.stakater.com/signal-command"
+	// HTTPReloadAnnotation is an annotation to define the endpoint called in the pods by the http reload strategy,
+	// e.g. "POST :9090/-/reload"
+	HTTPReloadAnnotation = "reloader.stakater.com/http-reload"
+	// HTTPReloadDelayAnnotation is an annotation to define the time the http reload strategy waits before calling the
+	// endpoint, instead of running cat in the pods to check that mounted files were updated, e.g. "90s"
+	HTTPReloadDelayAnnotation = "reloader.stakater.com/http-reload-delay"
+	// ReloadUnmanagedAnnotation is an annotation to opt in pods and replicaSets without managing controller to be reloaded.
+	// Valid values are "true", and "recreate" to recreate pods after evicting them
+	ReloadUnmanagedAnnotation = "reloader.stakater.com/reload-unmanaged"
+	// JobReloadPolicyAnnotation is an annotation to define how a Job is rerun. Valid values are "recreate",
+	// "create-new", "rerun-finished" and "skip-running"
+	JobReloadPolicyAnnotation = "reloader.stakater.com/job-reload-policy"
+	// SupersededJobTTLAnnotation is an annotation to define the time a Job superseded by a new Job is kept after it finished.
+	// Valid values are described here: https://pkg.go.dev/time#ParseDuration
+	SupersededJobTTLAnnotation = "reloader.stakater.com/superseded-job-ttl"
+	// CronJobReloadPolicyAnnotation is an annotation to define how a CronJob is reloaded. Valid values are "trigger",
+	// "update-template" and "update-and-trigger"
+	CronJobReloadPolicyAnnotation = "reloader.stakater.com/cronjob-reload-policy"
+	// GitOpsSyncAnnotation is an annotation to enable or disable reloading a workload through the HelmRelease or
+	// Argo CD Application it is deployed by. Valid values are "true" and "false"
+	GitOpsSyncAnnotation = "reloader.stakater.com/gitops-sync"
+	// WatchAnnotation is a comma separated list of configmaps and secrets in other namespaces that trigger a reload,
+	// e.g. "platform/ca-bundle"
+	WatchAnnotation = "reloader.stakater.com/watch"
+	// AllowWatchFromAnnotation is a comma separated list of namespaces whose workloads may watch a configmap or secret,
+	// or "*" for all namespaces
+	AllowWatchFromAnnotation = "reloader.stakater.com/allow-watch-from"
+	// GenerationOfLabel is a label marking a configmap or secret as a generation of a versioned resource, e.g. created
+	// by a Kustomize generator. Its value is the base name shared by all generations
+	GenerationOfLabel = "reloader.stakater.com/generation-of"
 	// PauseDeploymentAnnotation is an annotation to define the time period to pause a deployment after
 	// a configmap/secret change has been detected. Valid values are described here: https://pkg.go.dev/time#ParseDuration
 	// only positive values are allowed
@@ -61,6 +112,12 @@ var (
 	IsArgoRollouts = "false"
 	// ReloadStrategy Specify the update strategy
 	ReloadStrategy = constants.EnvVarsReloadStrategy
+	// SignalTimeout is how long the signal and http reload strategies wait for the kubelet to update mounted files
+	SignalTimeout = 3 * time.Minute
+	// DeletePodsTimeout is how long the delete-pods reload strategy waits for each pod to be evicted and replaced
+	DeletePodsTimeout = 5 * time.Minute
+	// SupersededJobTTL is the time a Job superseded by a new Job is kept after it finished, zero keeps it
+	SupersededJobTTL = time.Duration(0)
 	// ReloadOnCreate Adds support to watch create events
 	ReloadOnCreate = "false"
 	// ReloadOnDelete Adds support to watch delete events
@@ -70,8 +127,39 @@ var (
 	EnableHA = false
 	// Url to send a request to instead of triggering a reload
 	WebhookUrl = ""
+	// ReloadUnmanagedWorkloads adds support to reload pods and replicaSets without managing controller
+	ReloadUnmanagedWorkloads = false
+	// GitOpsSync reloads workloads deployed by a Flux HelmRelease or Argo CD Application through their owner
+	GitOpsSync = false
+	// GitOpsValuesKey is the Helm value under which the hashes of changed resources are passed to GitOps owners
+	GitOpsValuesKey = "reloader"
+	// ArgoCDNamespace is the namespace of Argo CD Applications referenced by workloads without namespace
+	ArgoCDNamespace = "argocd"
+	// FollowGenerations updates workload references to a newly created generation of a versioned configmap or secret
+	FollowGenerations = false
+	// PruneGenerations deletes older generations of a versioned configmap or secret no longer referenced
+	PruneGenerations = false
+	// EnableNamespaceAnnotations uses the reloader annotations of namespaces as defaults for their workloads
+	EnableNamespaceAnnotations = false
+	// WatchImagePullSecrets reloads workloads using a changed secret as image pull secret, directly or through their
+	// ServiceAccount, or listed in the secrets of their ServiceAccount
+	WatchImagePullSecrets = false
+	// HashAlgorithm is the algorithm of the hashes of resources written into workloads, "sha1", "sha256" or "hmac-sha256"
+	HashAlgorithm = "sha1"
+	// HashKeySecret is the [namespace/]name of the secret containing the key of the "hmac-sha256" hash algorithm,
+	// in the namespace of Reloader by default
+	HashKeySecret = ""
 	// EnableCSIIntegration Adds support to watch SecretProviderClassPodStatus and restart deployment based on it
 	EnableCSIIntegration = false
+	// EnableExternalSecretsIntegration Adds support to watch ExternalSecrets and restart workloads once they synced changed data
+	EnableExternalSecretsIntegration = false
+	// EnableCertManagerIntegration Adds support to watch cert-manager Certificates and restart workloads once a different certificate was issued
+	EnableCertManagerIntegration = false
+	// EnableReloaderPolicies Adds support to watch ReloaderPolicies and ClusterReloaderPolicies declaring how workloads selected by labels are reloaded
+	EnableReloaderPolicies = false
+	// EnablePolicyDebug serves the effective reload rules of workloads in the watched namespaces on the metrics port,
+	// requires EnableReloaderPolicies
+	EnablePolicyDebug = false
 	// ResourcesToIgnore is a list of resources to ignore when watching for changes
 	ResourcesToIgnore = []string{}
 	// WorkloadTypesToIgnore is a list of workload types to ignore when watching for changes
@@ -90,8 +178,44 @@ var (
 	// PProfAddr is the address to start pprof server on
 	// Default is :6060
 	PProfAddr = ":6060"
+	// AlertOnReload enables sending alerts to the alert webhook once a workload was reloaded
+	AlertOnReload = false
+	// AlertSink is the kind of the alert webhook, one of slack, teams, gchat or raw
+	AlertSink = ""
+	// AlertWebhookURL is the URL alerts are sent to
+	AlertWebhookURL = ""
+	// AlertWebhookProxy is the proxy used to reach the alert webhook, empty if there is none
+	AlertWebhookProxy = ""
+	// AlertAdditionalInfo is prepended to the message of alerts, e.g. the name of the cluster
+	AlertAdditionalInfo = ""
+	// ConfigFile is the YAML file of options watched for changes, empty if there is none
+	ConfigFile = ""
 )
 
+// mutex guards the options changed at runtime by reloading the config file
+var mutex sync.RWMutex
+
+// Lock locks the options for changing them, waiting until the readers holding the read lock finished
+func Lock() {
+	mutex.Lock()
+}
+
+// Unlock unlocks the options once they were changed
+func Unlock() {
+	mutex.Unlock()
+}
+
+// RLock locks the options for reading, which keeps them from changing until RUnlock is called. It is held by the
+// controllers while handling an event and by the work done outside their queues, such as resuming paused workloads.
+func RLock() {
+	mutex.RLock()
+}
+
+// RUnlock unlocks the options locked for reading
+func RUnlock() {
+	mutex.RUnlock()
+}
+
 func ToArgoRolloutStrategy(s string) ArgoRolloutStrategy {
 	switch s {
 	case "restart":
@@ -0,0 +1,213 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
+)
+
+const (
+	// YAMLContentType normalizes YAML data, key order, comments and formatting are ignored
+	YAMLContentType = "yaml"
+	// JSONContentType normalizes JSON data, key order and whitespace are ignored
+	JSONContentType = "json"
+	// PropertiesContentType normalizes properties data, order, comments and whitespace around keys and values are ignored
+	PropertiesContentType = "properties"
+	// AutoContentType normalizes data based on the extension of its key, e.g. "app.yaml"
+	AutoContentType = "auto"
+)
+
+// contentTypes are the valid values of the content-type annotation
+var contentTypes = []string{YAMLContentType, JSONContentType, PropertiesContentType, AutoContentType}
+
+// normalizeContent returns a canonical form of structured data, so cosmetic edits don't change its hash. Data that
+// can't be parsed as its content type is returned as is.
+func normalizeContent(key string, value string, contentType string) string {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if !slices.Contains(contentTypes, contentType) {
+		logrus.Warnf("Invalid value '%s' of annotation '%s', hashing key '%s' as is. Valid values are: %s",
+			contentType, options.ContentTypeAnnotation, key, strings.Join(contentTypes, ", "))
+		return value
+	}
+	if contentType == AutoContentType {
+		contentType = getContentTypeFromKey(key)
+	}
+
+	var normalized string
+	var err error
+	switch contentType {
+	case YAMLContentType, JSONContentType:
+		// JSON is a subset of YAML, both are normalized to JSON with sorted keys
+		normalized, err = normalizeYAML(value)
+	case PropertiesContentType:
+		normalized = normalizeProperties(value)
+	default:
+		return value
+	}
+	if err != nil {
+		logrus.Debugf("Unable to parse key '%s' as %s, hashing it as is: %v", key, contentType, err)
+		return value
+	}
+	return normalized
+}
+
+func getContentTypeFromKey(key string) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".yaml", ".yml":
+		return YAMLContentType
+	case ".json":
+		return JSONContentType
+	case ".properties":
+		return PropertiesContentType
+	}
+	return ""
+}
+
+// normalizeYAML returns the documents of YAML data as JSON with sorted keys, one document per line. Numbers keep the
+// precision and type they are written with, so only changes of the represented values change the result.
+func normalizeYAML(value string) (string, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(value))
+	var documents []string
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		parsed, err := canonicalizeYAMLNode(&document)
+		if err != nil {
+			return "", err
+		}
+		normalized, err := json.Marshal(parsed)
+		if err != nil {
+			return "", err
+		}
+		documents = append(documents, string(normalized))
+	}
+	if len(documents) == 0 {
+		return "null", nil
+	}
+	return strings.Join(documents, "\n"), nil
+}
+
+// canonicalizeYAMLNode converts a YAML node into values marshaled to canonical JSON, numbers become json.Numbers
+func canonicalizeYAMLNode(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return canonicalizeYAMLNode(node.Content[0])
+	case yaml.AliasNode:
+		return canonicalizeYAMLNode(node.Alias)
+	case yaml.SequenceNode:
+		values := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := canonicalizeYAMLNode(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case yaml.MappingNode:
+		values := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := canonicalizeYAMLKey(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			if values[key], err = canonicalizeYAMLNode(node.Content[i+1]); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case yaml.ScalarNode:
+		return canonicalizeYAMLScalar(node), nil
+	}
+	return nil, fmt.Errorf("unsupported YAML node kind %d", node.Kind)
+}
+
+func canonicalizeYAMLKey(node *yaml.Node) (string, error) {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	key, err := canonicalizeYAMLNode(node)
+	if err != nil {
+		return "", err
+	}
+	normalized, err := json.Marshal(key)
+	return string(normalized), err
+}
+
+func canonicalizeYAMLScalar(node *yaml.Node) interface{} {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!bool":
+		var value bool
+		if err := node.Decode(&value); err == nil {
+			return value
+		}
+	case "!!int":
+		// Integers of any size and base, e.g. 0x1F, are written in decimal
+		value, ok := new(big.Int).SetString(strings.ReplaceAll(node.Value, "_", ""), 0)
+		if ok {
+			return json.Number(value.String())
+		}
+	case "!!float":
+		// Floats are kept as written if valid JSON, so 1.0 stays different from 1
+		if json.Valid([]byte(node.Value)) {
+			return json.Number(node.Value)
+		}
+	}
+	return node.Value
+}
+
+// normalizeProperties parses the lines of Java properties data into sorted key=value pairs, ignoring comments and
+// blank lines. Later definitions of a key override earlier ones.
+func normalizeProperties(value string) string {
+	properties := map[string]string{}
+	lines := strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		// A trailing backslash continues the value on the next line
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + strings.TrimSpace(lines[i])
+		}
+		separator := strings.IndexAny(line, "=: \t")
+		if separator < 0 {
+			properties[line] = ""
+			continue
+		}
+		key := line[:separator]
+		rest := strings.TrimLeft(line[separator:], " \t")
+		if strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, ":") {
+			rest = rest[1:]
+		}
+		properties[key] = strings.TrimSpace(rest)
+	}
+
+	values := make([]string, 0, len(properties))
+	for k, v := range properties {
+		values = append(values, k+"="+v)
+	}
+	sort.Strings(values)
+	return strings.Join(values, "\n")
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stakater/Reloader/internal/pkg/options"
+)
+
+func TestGetSHAfromConfigmapWithContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		key         string
+		old         string
+		new         string
+		expectEqual bool
+	}{
+		{
+			name: "Reformatted YAML without content type",
+			key:  "config", old: "a: 1\nb: [x, y]\n", new: "b:\n  - x\n  - y\n# comment\na: 1\n",
+		},
+		{
+			name: "Reformatted YAML", contentType: "yaml",
+			key: "config", old: "a: 1\nb: [x, y]\n", new: "b:\n  - x\n  - y\n# comment\na: 1\n",
+			expectEqual: true,
+		},
+		{
+			name: "Changed YAML", contentType: "yaml",
+			key: "config", old: "a: 1\nb: [x, y]\n", new: "a: 1\nb: [y, x]\n",
+		},
+		{
+			name: "Reformatted JSON", contentType: "JSON",
+			key: "config", old: `{"a": 1, "b": {"c": true}}`, new: "{\n  \"b\": {\"c\": true},\n  \"a\": 1\n}",
+			expectEqual: true,
+		},
+		{
+			name: "Reformatted properties", contentType: "properties",
+			key: "config", old: "a=1\nb = two words\n", new: "# comment\nb:two words\n\n  a = 1",
+			expectEqual: true,
+		},
+		{
+			name: "Changed properties", contentType: "properties",
+			key: "config", old: "a=1\nb=2", new: "a=1\nb=3",
+		},
+		{
+			name: "Properties continuation line", contentType: "properties",
+			key: "config", old: "a=one two", new: "a=one \\\n    two",
+			expectEqual: true,
+		},
+		{
+			name: "Auto content type by key extension", contentType: "auto",
+			key: "app.yml", old: "a: 1\nb: 2\n", new: "b: 2\na: 1\n",
+			expectEqual: true,
+		},
+		{
+			name: "Auto content type with unknown extension", contentType: "auto",
+			key: "app.conf", old: "a: 1\nb: 2\n", new: "b: 2\na: 1\n",
+		},
+		{
+			name: "Changed later YAML document", contentType: "yaml",
+			key: "config", old: "a: 1\n---\nb: 2\n", new: "a: 1\n---\nb: 3\n",
+		},
+		{
+			name: "Reformatted YAML documents", contentType: "yaml",
+			key: "config", old: "a: 1\n---\nb: 2\nc: 3\n", new: "---\na: 1\n---\nc: 3\nb: 2\n",
+			expectEqual: true,
+		},
+		{
+			name: "Large integer beyond float precision", contentType: "yaml",
+			key: "config", old: "id: 9007199254740992\n", new: "id: 9007199254740993\n",
+		},
+		{
+			name: "Float and integer", contentType: "yaml",
+			key: "config", old: "v: 1\n", new: "v: 1.0\n",
+		},
+		{
+			name: "Large integer in JSON", contentType: "json",
+			key: "config", old: `{"id": 9007199254740992}`, new: `{"id": 9007199254740993}`,
+		},
+		{
+			name: "Quoted and plain string", contentType: "yaml",
+			key: "config", old: "a: text\nb: [x]\n", new: "a: \"text\"\nb: ['x']\n",
+			expectEqual: true,
+		},
+		{
+			name: "Number and string", contentType: "yaml",
+			key: "config", old: "a: 1\n", new: "a: \"1\"\n",
+		},
+		{
+			name: "Invalid YAML hashed as is", contentType: "yaml",
+			key: "config", old: "a: [1", new: "a: [1 ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.contentType != "" {
+				annotations[options.ContentTypeAnnotation] = tt.contentType
+			}
+			old := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}, Data: map[string]string{tt.key: tt.old}}
+			new := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}, Data: map[string]string{tt.key: tt.new}}
+
+			if equal := GetSHAfromConfigmap(old) == GetSHAfromConfigmap(new); equal != tt.expectEqual {
+				t.Errorf("Expected equal hashes to be %v, got %v", tt.expectEqual, equal)
+			}
+		})
+	}
+}
@@ -2,13 +2,22 @@ package util
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
 	csiv1 "sigs.k8s.io/secrets-store-csi-driver/apis/v1"
 
@@ -39,34 +48,85 @@ func ConvertToEnvVarName(text string) string {
 }
 
 func GetSHAfromConfigmap(configmap *v1.ConfigMap) string {
+	return crypto.GenerateSHA(getConfigmapHashData(configmap))
+}
+
+// GetLegacySHAfromConfigmap returns the SHA-1 of a configmap written by earlier versions, empty unless another hash
+// algorithm is configured
+func GetLegacySHAfromConfigmap(configmap *v1.ConfigMap) string {
+	return crypto.GenerateLegacySHA(getConfigmapHashData(configmap))
+}
+
+func getConfigmapHashData(configmap *v1.ConfigMap) string {
+	contentType := configmap.Annotations[options.ContentTypeAnnotation]
 	values := []string{}
 	for k, v := range configmap.Data {
+		if contentType != "" {
+			v = normalizeContent(k, v, contentType)
+		}
 		values = append(values, k+"="+v)
 	}
 	for k, v := range configmap.BinaryData {
 		values = append(values, k+"="+base64.StdEncoding.EncodeToString(v))
 	}
 	sort.Strings(values)
-	return crypto.GenerateSHA(strings.Join(values, ";"))
+	return strings.Join(values, ";")
 }
 
 func GetSHAfromSecret(data map[string][]byte) string {
+	return crypto.GenerateSHA(getSecretHashData(data))
+}
+
+// GetLegacySHAfromSecret returns the SHA-1 of a secret written by earlier versions, empty unless another hash
+// algorithm is configured
+func GetLegacySHAfromSecret(data map[string][]byte) string {
+	return crypto.GenerateLegacySHA(getSecretHashData(data))
+}
+
+func getSecretHashData(data map[string][]byte) string {
 	values := []string{}
 	for k, v := range data {
 		values = append(values, k+"="+string(v[:]))
 	}
 	sort.Strings(values)
-	return crypto.GenerateSHA(strings.Join(values, ";"))
+	return strings.Join(values, ";")
 }
 
 func GetSHAfromSecretProviderClassPodStatus(data csiv1.SecretProviderClassPodStatusStatus) string {
+	return crypto.GenerateSHA(getSecretProviderClassPodStatusHashData(data))
+}
+
+// GetLegacySHAfromSecretProviderClassPodStatus returns the SHA-1 of a secretproviderclasspodstatus written by earlier
+// versions, empty unless another hash algorithm is configured
+func GetLegacySHAfromSecretProviderClassPodStatus(data csiv1.SecretProviderClassPodStatusStatus) string {
+	return crypto.GenerateLegacySHA(getSecretProviderClassPodStatusHashData(data))
+}
+
+func getSecretProviderClassPodStatusHashData(data csiv1.SecretProviderClassPodStatusStatus) string {
 	values := []string{}
 	for _, v := range data.Objects {
 		values = append(values, v.ID+"="+v.Version)
 	}
 	values = append(values, "SecretProviderClassName="+data.SecretProviderClassName)
 	sort.Strings(values)
-	return crypto.GenerateSHA(strings.Join(values, ";"))
+	return strings.Join(values, ";")
+}
+
+// GetCertificateFingerprint returns the SHA-256 fingerprint and expiry of the first certificate in PEM encoded data,
+// e.g. the leaf certificate in the tls.crt of a TLS secret
+func GetCertificateFingerprint(data []byte) (string, time.Time, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		fingerprint := sha256.Sum256(certificate.Raw)
+		return hex.EncodeToString(fingerprint[:]), certificate.NotAfter, nil
+	}
+	return "", time.Time{}, errors.New("no PEM encoded certificate found")
 }
 
 type List []string
@@ -103,13 +163,89 @@ func ConfigureReloaderFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringSliceVar(&options.ResourceSelectors, "resource-label-selector", options.ResourceSelectors, "list of key:value labels to filter on for configmaps and secrets")
 	cmd.PersistentFlags().StringVar(&options.IsArgoRollouts, "is-Argo-Rollouts", "false", "Add support for argo rollouts")
 	cmd.PersistentFlags().StringVar(&options.ReloadStrategy, constants.ReloadStrategyFlag, constants.EnvVarsReloadStrategy, "Specifies the desired reload strategy")
+	cmd.PersistentFlags().DurationVar(&options.SignalTimeout, "signal-timeout", options.SignalTimeout, "Time to wait for mounted files to be updated in the pods before running the signal command or calling the http reload endpoint")
+	cmd.PersistentFlags().DurationVar(&options.DeletePodsTimeout, "delete-pods-timeout", options.DeletePodsTimeout, "Time to wait for each pod to be evicted and replaced by the delete-pods reload strategy")
+	cmd.PersistentFlags().DurationVar(&options.SupersededJobTTL, "superseded-job-ttl", options.SupersededJobTTL, "Time to keep finished Jobs superseded by a new Job, zero keeps them")
 	cmd.PersistentFlags().StringVar(&options.ReloadOnCreate, "reload-on-create", "false", "Add support to watch create events")
 	cmd.PersistentFlags().StringVar(&options.ReloadOnDelete, "reload-on-delete", "false", "Add support to watch delete events")
 	cmd.PersistentFlags().BoolVar(&options.EnableHA, "enable-ha", false, "Adds support for running multiple replicas via leadership election")
 	cmd.PersistentFlags().BoolVar(&options.SyncAfterRestart, "sync-after-restart", false, "Sync add events after reloader restarts")
 	cmd.PersistentFlags().BoolVar(&options.EnablePProf, "enable-pprof", false, "Enable pprof for profiling")
 	cmd.PersistentFlags().StringVar(&options.PProfAddr, "pprof-addr", ":6060", "Address to start pprof server on. Default is :6060")
+	cmd.PersistentFlags().BoolVar(&options.ReloadUnmanagedWorkloads, "reload-unmanaged-workloads", false, "Reload pods and replicaSets without managing controller that opted in with the reload-unmanaged annotation")
+	cmd.PersistentFlags().BoolVar(&options.GitOpsSync, "gitops-sync", false, "Reload workloads deployed by a Flux HelmRelease or Argo CD Application by syncing their owner instead of patching them")
+	cmd.PersistentFlags().StringVar(&options.GitOpsValuesKey, "gitops-values-key", options.GitOpsValuesKey, "Helm value under which the hashes of changed resources are passed to HelmReleases and Argo CD Applications")
+	cmd.PersistentFlags().StringVar(&options.ArgoCDNamespace, "argocd-namespace", options.ArgoCDNamespace, "Namespace of the Argo CD Applications tracked by workloads")
+	cmd.PersistentFlags().BoolVar(&options.FollowGenerations, "follow-generations", false, "Update workload references to a newly created generation of a configmap or secret labeled with the generation-of label")
+	cmd.PersistentFlags().BoolVar(&options.PruneGenerations, "prune-generations", false, "Delete older generations of a configmap or secret once no workload or pod references them, requires follow-generations")
+	cmd.PersistentFlags().BoolVar(&options.EnableNamespaceAnnotations, "enable-namespace-annotations", false, "Use the reloader annotations of namespaces as defaults for workloads and pod templates not setting them, requires watching all namespaces")
+	cmd.PersistentFlags().BoolVar(&options.WatchImagePullSecrets, "watch-image-pull-secrets", false, "Reload workloads using a changed secret as image pull secret, directly or through their ServiceAccount, or listed in the secrets of their ServiceAccount")
+	cmd.PersistentFlags().StringVar(&options.HashAlgorithm, "hash-algorithm", options.HashAlgorithm, "Algorithm of the hashes of resources written into workloads (sha1, sha256 or hmac-sha256)")
+	cmd.PersistentFlags().StringVar(&options.HashKeySecret, "hash-key-secret", options.HashKeySecret, "[namespace/]name of the secret containing the key of the hmac-sha256 hash algorithm in its 'key' entry, in the namespace of Reloader by default")
 	cmd.PersistentFlags().BoolVar(&options.EnableCSIIntegration, "enable-csi-integration", false, "Enables CSI integration. Default is :false")
+	cmd.PersistentFlags().BoolVar(&options.EnableExternalSecretsIntegration, "enable-external-secrets-integration", false, "Watch External Secrets Operator ExternalSecrets and reload workloads once they synced changed data")
+	cmd.PersistentFlags().BoolVar(&options.EnableCertManagerIntegration, "enable-cert-manager-integration", false, "Watch cert-manager Certificates and reload workloads once a different certificate was issued")
+	cmd.PersistentFlags().BoolVar(&options.EnableReloaderPolicies, "enable-reloader-policies", false, "Watch ReloaderPolicies and ClusterReloaderPolicies declaring the sources, strategy, pause period, debounce and exclusions of workloads selected by labels")
+	cmd.PersistentFlags().BoolVar(&options.EnablePolicyDebug, "enable-policy-debug", false, "Serve the effective reload rules of workloads in the watched namespaces on /debug/policy of the metrics port, requires --enable-reloader-policies")
+	cmd.PersistentFlags().BoolVar(&options.AlertOnReload, "alert-on-reload", false, "Send an alert to the alert webhook once a workload was reloaded")
+	cmd.PersistentFlags().StringVar(&options.AlertSink, "alert-sink", "", "Kind of the alert webhook (slack, teams, gchat or raw), raw by default")
+	cmd.PersistentFlags().StringVar(&options.AlertWebhookURL, "alert-webhook-url", "", "URL of the webhook alerts are sent to")
+	cmd.PersistentFlags().StringVar(&options.AlertWebhookProxy, "alert-webhook-proxy", "", "Proxy used to reach the alert webhook")
+	cmd.PersistentFlags().StringVar(&options.AlertAdditionalInfo, "alert-additional-info", "", "Additional information prepended to alerts, e.g. the name of the cluster")
+	cmd.PersistentFlags().StringVar(&options.ConfigFile, "config", "", "YAML file of reloader options, applied to the options not set by flags and watched for changes")
+}
+
+// deprecatedFlagEnvVars are the environment variables read before the flags replacing them existed, still honored when
+// the RELOADER_* variable of the flag isn't set
+var deprecatedFlagEnvVars = map[string]string{
+	"alert-on-reload":       "ALERT_ON_RELOAD",
+	"alert-sink":            "ALERT_SINK",
+	"alert-webhook-url":     "ALERT_WEBHOOK_URL",
+	"alert-webhook-proxy":   "ALERT_WEBHOOK_PROXY",
+	"alert-additional-info": "ALERT_ADDITIONAL_INFO",
+}
+
+// GetFlagEnvVarName returns the environment variable setting a flag, e.g. RELOADER_LOG_LEVEL for log-level
+func GetFlagEnvVarName(flag string) string {
+	return constants.FlagEnvVarPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// SetFlagsFromEnv sets the flags not set on the command line from their environment variables, so that flags take
+// precedence over environment variables
+func SetFlagsFromEnv(flags *pflag.FlagSet) error {
+	var errs []error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || flag.Name == "help" {
+			return
+		}
+		name := GetFlagEnvVarName(flag.Name)
+		value, found := os.LookupEnv(name)
+		if !found {
+			if name, found = deprecatedFlagEnvVars[flag.Name]; found {
+				value, found = os.LookupEnv(name)
+				value = getDeprecatedEnvVarValue(flag, name, value)
+			}
+		}
+		if !found {
+			return
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value of environment variable %s: %w", name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// getDeprecatedEnvVarValue returns the value of a deprecated environment variable setting a boolean flag the way it
+// was read before, where only "true" enabled it and any other value disabled it
+func getDeprecatedEnvVarValue(flag *pflag.Flag, name, value string) string {
+	if flag.Value.Type() != "bool" {
+		return value
+	}
+	if _, err := strconv.ParseBool(value); err != nil {
+		logrus.Warnf("Environment variable %s is %q, it is only enabled by \"true\"", name, value)
+	}
+	return strconv.FormatBool(value == "true")
 }
 
 func GetIgnoredResourcesList() (List, error) {
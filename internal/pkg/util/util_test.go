@@ -3,6 +3,7 @@ package util
 import (
 	"testing"
 
+	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/stakater/Reloader/internal/pkg/options"
@@ -276,3 +277,83 @@ func TestListContains(t *testing.T) {
 		})
 	}
 }
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	var logLevel, reloadStrategy, alertWebhookURL string
+	var alertOnReload bool
+	var namespacesToIgnore []string
+	flags := pflag.NewFlagSet("reloader", pflag.ContinueOnError)
+	flags.StringVar(&logLevel, "log-level", "info", "")
+	flags.StringVar(&reloadStrategy, "reload-strategy", "env-vars", "")
+	flags.StringSliceVar(&namespacesToIgnore, "namespaces-to-ignore", []string{}, "")
+	flags.BoolVar(&alertOnReload, "alert-on-reload", false, "")
+	flags.StringVar(&alertWebhookURL, "alert-webhook-url", "", "")
+	if err := flags.Parse([]string{"--log-level=debug"}); err != nil {
+		t.Fatalf("Unexpected error parsing flags: %v", err)
+	}
+
+	t.Setenv("RELOADER_LOG_LEVEL", "error")
+	t.Setenv("RELOADER_RELOAD_STRATEGY", "annotations")
+	t.Setenv("RELOADER_NAMESPACES_TO_IGNORE", "kube-system,kube-public")
+	t.Setenv("ALERT_ON_RELOAD", "true")
+	t.Setenv("ALERT_WEBHOOK_URL", "https://legacy.example.com")
+	t.Setenv("RELOADER_ALERT_WEBHOOK_URL", "https://hooks.example.com")
+
+	if err := SetFlagsFromEnv(flags); err != nil {
+		t.Fatalf("Unexpected error setting flags from env: %v", err)
+	}
+	if logLevel != "debug" {
+		t.Errorf("Expected the log level set on the command line to win, got %q", logLevel)
+	}
+	if reloadStrategy != "annotations" || !flags.Changed("reload-strategy") {
+		t.Errorf("Expected reload strategy annotations marked as changed, got %q", reloadStrategy)
+	}
+	if len(namespacesToIgnore) != 2 || namespacesToIgnore[0] != "kube-system" || namespacesToIgnore[1] != "kube-public" {
+		t.Errorf("Expected namespaces to ignore kube-system and kube-public, got %v", namespacesToIgnore)
+	}
+	if !alertOnReload {
+		t.Errorf("Expected alert-on-reload to be set by the deprecated ALERT_ON_RELOAD")
+	}
+	if alertWebhookURL != "https://hooks.example.com" {
+		t.Errorf("Expected RELOADER_ALERT_WEBHOOK_URL to win over ALERT_WEBHOOK_URL, got %q", alertWebhookURL)
+	}
+}
+
+func TestSetFlagsFromEnv_DeprecatedEnvVar(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{value: "true", expected: true},
+		{value: "True", expected: false},
+		{value: "yes", expected: false},
+		{value: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var alertOnReload bool
+			flags := pflag.NewFlagSet("reloader", pflag.ContinueOnError)
+			flags.BoolVar(&alertOnReload, "alert-on-reload", false, "")
+			t.Setenv("ALERT_ON_RELOAD", tt.value)
+
+			if err := SetFlagsFromEnv(flags); err != nil {
+				t.Fatalf("Unexpected error setting flags from env: %v", err)
+			}
+			if alertOnReload != tt.expected {
+				t.Errorf("Expected alert-on-reload %v for ALERT_ON_RELOAD=%q, got %v", tt.expected, tt.value, alertOnReload)
+			}
+		})
+	}
+}
+
+func TestSetFlagsFromEnv_InvalidValue(t *testing.T) {
+	var alertOnReload bool
+	flags := pflag.NewFlagSet("reloader", pflag.ContinueOnError)
+	flags.BoolVar(&alertOnReload, "alert-on-reload", false, "")
+	t.Setenv("RELOADER_ALERT_ON_RELOAD", "sometimes")
+
+	if err := SetFlagsFromEnv(flags); err == nil {
+		t.Errorf("Expected an error for an invalid boolean")
+	}
+}
@@ -0,0 +1,4 @@
+// Package v1alpha1 contains the v1alpha1 API of the reloader.stakater.com group
+// +kubebuilder:object:generate=true
+// +groupName=reloader.stakater.com
+package v1alpha1
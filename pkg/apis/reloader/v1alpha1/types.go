@@ -0,0 +1,156 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ReadyCondition is the condition reporting whether a policy is valid and which workloads it selects
+	ReadyCondition = "Ready"
+	// InvalidSpecReason is the reason of the ready condition of a policy with an invalid selector or pattern
+	InvalidSpecReason = "InvalidSpec"
+	// WorkloadsMatchedReason is the reason of the ready condition of a policy selecting at least one workload
+	WorkloadsMatchedReason = "WorkloadsMatched"
+	// NoWorkloadsMatchedReason is the reason of the ready condition of a policy selecting no workload
+	NoWorkloadsMatchedReason = "NoWorkloadsMatched"
+)
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rlp
+// +kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.spec.strategy`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Workloads",type=integer,JSONPath=`.status.matchedWorkloadCount`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ReloaderPolicy declares how the workloads of its namespace selected by labels are reloaded, in addition to their
+// annotations
+type ReloaderPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReloaderPolicySpec   `json:"spec,omitempty"`
+	Status ReloaderPolicyStatus `json:"status,omitempty"`
+}
+
+// ReloaderPolicySpec defines the workloads selected by a policy and how they are reloaded
+type ReloaderPolicySpec struct {
+	// WorkloadSelector selects the workloads of the namespace the policy applies to, all workloads if empty
+	// +optional
+	WorkloadSelector WorkloadSelector `json:"workloadSelector,omitempty"`
+	// Sources select the resources whose changes reload the selected workloads, even if they don't reference them
+	// +optional
+	Sources []SourceSelector `json:"sources,omitempty"`
+	// Exclude selects the resources whose changes never reload the selected workloads
+	// +optional
+	Exclude []SourceSelector `json:"exclude,omitempty"`
+	// Strategy is the reload strategy of the selected workloads, unless overridden by their annotations
+	// +kubebuilder:validation:Enum=env-vars;annotations;restarted-at;signal;http;delete-pods
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+	// PausePeriod is how long rollouts of the selected workloads are paused after a reload, unless overridden by
+	// their annotations
+	// +optional
+	PausePeriod *metav1.Duration `json:"pausePeriod,omitempty"`
+	// Debounce is how long changes of a resource are collected before the selected workloads are reloaded once
+	// +optional
+	Debounce *metav1.Duration `json:"debounce,omitempty"`
+}
+
+// WorkloadSelector selects workloads by kind and labels
+type WorkloadSelector struct {
+	// Kinds are the kinds of the selected workloads, all kinds if empty
+	// +kubebuilder:validation:items:Enum=Deployment;DaemonSet;StatefulSet;CronJob;Job;Rollout;KnativeService;ReplicaSet;Pod
+	// +optional
+	Kinds []string `json:"kinds,omitempty"`
+	// Selector selects workloads by their labels, all workloads if not set
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// SourceSelector selects resources by kind, name, name pattern and labels. A resource is selected if it matches all
+// fields that are set.
+type SourceSelector struct {
+	// Kind is the kind of the selected resources, all kinds if empty
+	// +kubebuilder:validation:Enum=ConfigMap;Secret;SecretProviderClass;ExternalSecret;Certificate
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Name is the name of the selected resource
+	// +optional
+	Name string `json:"name,omitempty"`
+	// NameRegex is a regular expression matching the whole name of the selected resources
+	// +optional
+	NameRegex string `json:"nameRegex,omitempty"`
+	// Selector selects resources by their labels
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ReloaderPolicyStatus reports whether a policy is valid and which workloads it selects
+type ReloaderPolicyStatus struct {
+	// ObservedGeneration is the generation of the policy the status was computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// MatchedWorkloadCount is the number of workloads selected by the policy
+	// +optional
+	MatchedWorkloadCount int32 `json:"matchedWorkloadCount,omitempty"`
+	// MatchedWorkloads are up to 20 of the workloads selected by the policy as kind/name, or namespace/kind/name for
+	// cluster policies
+	// +optional
+	MatchedWorkloads []string `json:"matchedWorkloads,omitempty"`
+	// Conditions are the conditions of the policy
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReloaderPolicyList contains a list of ReloaderPolicies
+type ReloaderPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReloaderPolicy `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=crlp
+// +kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.spec.strategy`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Workloads",type=integer,JSONPath=`.status.matchedWorkloadCount`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterReloaderPolicy declares how the workloads of the namespaces it selects are reloaded, as defaults for the
+// ReloaderPolicies of the namespaces
+type ClusterReloaderPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterReloaderPolicySpec `json:"spec,omitempty"`
+	Status ReloaderPolicyStatus      `json:"status,omitempty"`
+}
+
+// ClusterReloaderPolicySpec defines the namespaces and workloads selected by a cluster policy and how they are reloaded
+type ClusterReloaderPolicySpec struct {
+	// NamespaceSelector selects the namespaces of the workloads by their labels, all namespaces if not set
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	ReloaderPolicySpec `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterReloaderPolicyList contains a list of ClusterReloaderPolicies
+type ClusterReloaderPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterReloaderPolicy `json:"items"`
+}
@@ -37,6 +37,10 @@ type ReloaderOptions struct {
 	SecretUpdateOnChangeAnnotation string `json:"secretUpdateOnChangeAnnotation"`
 	// SecretProviderClassUpdateOnChangeAnnotation is the annotation key used to detect changes in SecretProviderClasses specified by name
 	SecretProviderClassUpdateOnChangeAnnotation string `json:"secretProviderClassUpdateOnChangeAnnotation"`
+	// ExternalSecretUpdateOnChangeAnnotation is the annotation key used to detect synced changes of ExternalSecrets specified by name
+	ExternalSecretUpdateOnChangeAnnotation string `json:"externalSecretUpdateOnChangeAnnotation"`
+	// CertificateUpdateOnChangeAnnotation is the annotation key used to detect renewals of cert-manager Certificates specified by name
+	CertificateUpdateOnChangeAnnotation string `json:"certificateUpdateOnChangeAnnotation"`
 	// ReloaderAutoAnnotation is the annotation key used to detect changes in any referenced ConfigMaps or Secrets
 	ReloaderAutoAnnotation string `json:"reloaderAutoAnnotation"`
 	// IgnoreResourceAnnotation is the annotation key used to ignore resources from being watched
@@ -57,8 +61,36 @@ type ReloaderOptions struct {
 	AutoSearchAnnotation string `json:"autoSearchAnnotation"`
 	// SearchMatchAnnotation is the annotation key used to tag ConfigMaps/Secrets to be found by AutoSearchAnnotation
 	SearchMatchAnnotation string `json:"searchMatchAnnotation"`
+	// ContentTypeAnnotation is the annotation key used on ConfigMaps to hash a canonical form of their structured data
+	ContentTypeAnnotation string `json:"contentTypeAnnotation"`
 	// RolloutStrategyAnnotation is the annotation key used to define the rollout update strategy for workloads
 	RolloutStrategyAnnotation string `json:"rolloutStrategyAnnotation"`
+	// RolloutInProgressAnnotation is the annotation key used to define how an Argo Rollout is reloaded while an update is in progress
+	RolloutInProgressAnnotation string `json:"rolloutInProgressAnnotation"`
+	// ReloadStrategyAnnotation is the annotation key used to override the reload strategy for a single workload
+	ReloadStrategyAnnotation string `json:"reloadStrategyAnnotation"`
+	// SignalCommandAnnotation is the annotation key used to define the command run in the pods by the signal reload strategy
+	SignalCommandAnnotation string `json:"signalCommandAnnotation"`
+	// HTTPReloadAnnotation is the annotation key used to define the endpoint called in the pods by the http reload strategy
+	HTTPReloadAnnotation string `json:"httpReloadAnnotation"`
+	// HTTPReloadDelayAnnotation is the annotation key used to define the time the http reload strategy waits before calling the endpoint
+	HTTPReloadDelayAnnotation string `json:"httpReloadDelayAnnotation"`
+	// ReloadUnmanagedAnnotation is the annotation key used to opt in pods and ReplicaSets without managing controller to be reloaded
+	ReloadUnmanagedAnnotation string `json:"reloadUnmanagedAnnotation"`
+	// JobReloadPolicyAnnotation is the annotation key used to define how a Job is rerun
+	JobReloadPolicyAnnotation string `json:"jobReloadPolicyAnnotation"`
+	// SupersededJobTTLAnnotation is the annotation key used to define how long a Job superseded by a new Job is kept
+	SupersededJobTTLAnnotation string `json:"supersededJobTTLAnnotation"`
+	// CronJobReloadPolicyAnnotation is the annotation key used to define how a CronJob is reloaded
+	CronJobReloadPolicyAnnotation string `json:"cronJobReloadPolicyAnnotation"`
+	// GitOpsSyncAnnotation is the annotation key used to enable or disable reloading a workload through its GitOps owner
+	GitOpsSyncAnnotation string `json:"gitOpsSyncAnnotation"`
+	// WatchAnnotation is the annotation key used to list ConfigMaps and Secrets in other namespaces that trigger a reload
+	WatchAnnotation string `json:"watchAnnotation"`
+	// AllowWatchFromAnnotation is the annotation key used to list the namespaces whose workloads may watch a ConfigMap or Secret
+	AllowWatchFromAnnotation string `json:"allowWatchFromAnnotation"`
+	// GenerationOfLabel is the label key used to mark a ConfigMap or Secret as a generation of a versioned resource
+	GenerationOfLabel string `json:"generationOfLabel"`
 	// PauseDeploymentAnnotation is the annotation key used to define the time period to pause a deployment after
 	PauseDeploymentAnnotation string `json:"pauseDeploymentAnnotation"`
 	// PauseDeploymentTimeAnnotation is the annotation key used to indicate when a deployment was paused by Reloader
@@ -70,8 +102,14 @@ type ReloaderOptions struct {
 	LogLevel string `json:"logLevel"`
 	// IsArgoRollouts indicates whether support for Argo Rollouts is enabled
 	IsArgoRollouts bool `json:"isArgoRollouts"`
-	// ReloadStrategy specifies the strategy used to trigger resource reloads (env-vars or annotations)
+	// ReloadStrategy specifies the strategy used to trigger resource reloads (env-vars, annotations, restarted-at, signal, http or delete-pods)
 	ReloadStrategy string `json:"reloadStrategy"`
+	// SignalTimeout is how long the signal and http reload strategies wait for mounted files to be updated in the pods
+	SignalTimeout string `json:"signalTimeout"`
+	// DeletePodsTimeout is how long the delete-pods reload strategy waits for each pod to be evicted and replaced
+	DeletePodsTimeout string `json:"deletePodsTimeout"`
+	// SupersededJobTTL is how long a finished Job superseded by a new Job is kept, zero keeps it
+	SupersededJobTTL string `json:"supersededJobTTL"`
 	// ReloadOnCreate indicates whether to trigger reloads when ConfigMaps/Secrets are created
 	ReloadOnCreate bool `json:"reloadOnCreate"`
 	// ReloadOnDelete indicates whether to trigger reloads when ConfigMaps/Secrets are deleted
@@ -80,8 +118,36 @@ type ReloaderOptions struct {
 	SyncAfterRestart bool `json:"syncAfterRestart"`
 	// EnableHA indicates whether High Availability mode is enabled with leader election
 	EnableHA bool `json:"enableHA"`
+	// EnableNamespaceAnnotations indicates whether the annotations of namespaces are defaults for their workloads
+	EnableNamespaceAnnotations bool `json:"enableNamespaceAnnotations"`
+	// WatchImagePullSecrets indicates whether secrets used by workloads as image pull secrets or through their ServiceAccount are references
+	WatchImagePullSecrets bool `json:"watchImagePullSecrets"`
+	// HashAlgorithm is the algorithm of the hashes of resources written into workloads
+	HashAlgorithm string `json:"hashAlgorithm"`
+	// HashKeySecret is the secret containing the key of the hmac-sha256 hash algorithm
+	HashKeySecret string `json:"hashKeySecret"`
 	// EnableCSIIntegration indicates whether CSI integration is enabled to watch SecretProviderClassPodStatus
 	EnableCSIIntegration bool `json:"enableCSIIntegration"`
+	// EnableExternalSecretsIntegration indicates whether External Secrets Operator integration is enabled to watch ExternalSecrets
+	EnableExternalSecretsIntegration bool `json:"enableExternalSecretsIntegration"`
+	// EnableCertManagerIntegration indicates whether cert-manager integration is enabled to watch Certificates
+	EnableCertManagerIntegration bool `json:"enableCertManagerIntegration"`
+	// EnableReloaderPolicies indicates whether ReloaderPolicies are watched to declare how workloads selected by labels are reloaded
+	EnableReloaderPolicies bool `json:"enableReloaderPolicies"`
+	// EnablePolicyDebug indicates whether the effective reload rules of workloads are served on the metrics port
+	EnablePolicyDebug bool `json:"enablePolicyDebug"`
+	// ReloadUnmanagedWorkloads indicates whether pods and ReplicaSets without managing controller are reloaded
+	ReloadUnmanagedWorkloads bool `json:"reloadUnmanagedWorkloads"`
+	// GitOpsSync indicates whether workloads deployed by a Flux HelmRelease or Argo CD Application are reloaded through their owner
+	GitOpsSync bool `json:"gitOpsSync"`
+	// GitOpsValuesKey is the Helm value under which the hashes of changed resources are passed to GitOps owners
+	GitOpsValuesKey string `json:"gitOpsValuesKey"`
+	// ArgoCDNamespace is the namespace of the Argo CD Applications tracked by workloads
+	ArgoCDNamespace string `json:"argoCDNamespace"`
+	// FollowGenerations indicates whether workload references are updated to a newly created generation of a ConfigMap or Secret
+	FollowGenerations bool `json:"followGenerations"`
+	// PruneGenerations indicates whether older generations of a ConfigMap or Secret are deleted once unreferenced
+	PruneGenerations bool `json:"pruneGenerations"`
 	// WebhookUrl is the URL to send webhook notifications to instead of performing reloads
 	WebhookUrl string `json:"webhookUrl"`
 	// ResourcesToIgnore is a list of resource types to ignore (e.g., "configmaps" or "secrets")
@@ -94,6 +160,20 @@ type ReloaderOptions struct {
 	ResourceSelectors []string `json:"resourceSelectors"`
 	// NamespacesToIgnore is a list of namespace names to ignore when watching for changes
 	NamespacesToIgnore []string `json:"namespacesToIgnore"`
+	// AlertOnReload indicates whether alerts are sent to the alert webhook once a workload was reloaded
+	AlertOnReload bool `json:"alertOnReload"`
+	// AlertSink is the kind of the alert webhook (slack, teams, gchat or raw)
+	AlertSink string `json:"alertSink"`
+	// AlertWebhookURL is the URL alerts are sent to, not published as it carries the token of the webhook
+	AlertWebhookURL string `json:"-"`
+	// AlertWebhookURLSet indicates whether the URL alerts are sent to is set
+	AlertWebhookURLSet bool `json:"alertWebhookUrlSet"`
+	// AlertWebhookProxy is the proxy used to reach the alert webhook, not published as it can carry credentials
+	AlertWebhookProxy string `json:"-"`
+	// AlertWebhookProxySet indicates whether a proxy is used to reach the alert webhook
+	AlertWebhookProxySet bool `json:"alertWebhookProxySet"`
+	// AlertAdditionalInfo is prepended to the message of alerts
+	AlertAdditionalInfo string `json:"alertAdditionalInfo"`
 	// EnablePProf enables pprof for profiling
 	EnablePProf bool `json:"enablePProf"`
 	// PProfAddr is the address to start pprof server on
@@ -156,9 +236,8 @@ func GetNamespaceLabelSelector(slice []string) (string, error) {
 	}
 
 	namespaceLabelSelector := strings.Join(slice[:], ",")
-	_, err := labels.Parse(namespaceLabelSelector)
-	if err != nil {
-		logrus.Fatal(err)
+	if _, err := labels.Parse(namespaceLabelSelector); err != nil {
+		return "", err
 	}
 
 	return namespaceLabelSelector, nil
@@ -185,16 +264,16 @@ func GetResourceLabelSelector(slice []string) (string, error) {
 	}
 
 	resourceLabelSelector := strings.Join(slice[:], ",")
-	_, err := labels.Parse(resourceLabelSelector)
-	if err != nil {
-		logrus.Fatal(err)
+	if _, err := labels.Parse(resourceLabelSelector); err != nil {
+		return "", err
 	}
 
 	return resourceLabelSelector, nil
 }
 
-// ShouldReload checks if a resource should be reloaded based on its annotations and the provided options.
-func ShouldReload(config Config, resourceType string, annotations Map, podAnnotations Map, reloaderOpts *ReloaderOptions) ReloadCheckResult {
+// ShouldReload checks if a resource should be reloaded based on its annotations, the ReloaderPolicies selecting it by
+// its labels and the provided options.
+func ShouldReload(config Config, resourceType string, annotations Map, podAnnotations Map, workloadLabels Map, reloaderOpts *ReloaderOptions) ReloadCheckResult {
 
 	// Check if this workload type should be ignored.
 	// Use reloaderOpts.WorkloadTypesToIgnore directly instead of re-reading the
@@ -235,46 +314,57 @@ func ShouldReload(config Config, resourceType string, annotations Map, podAnnota
 		}
 	}
 
+	// Resources in other namespaces only reload workloads watching them explicitly
+	if config.SourceNamespace != "" && config.SourceNamespace != config.Namespace {
+		return ReloadCheckResult{ShouldReload: isWatchingResource(config, annotations, podAnnotations, reloaderOpts)}
+	}
+
+	// Annotations of the namespace are defaults for all its workloads
+	var namespaceAnnotations Map
+	if reloaderOpts.EnableNamespaceAnnotations {
+		namespaceAnnotations = GetNamespaceAnnotations(config.Namespace)
+	}
+
 	annotationValue, found := annotations[config.Annotation]
 	searchAnnotationValue, foundSearchAnn := annotations[reloaderOpts.AutoSearchAnnotation]
 	reloaderEnabledValue, foundAuto := annotations[reloaderOpts.ReloaderAutoAnnotation]
 	typedAutoAnnotationEnabledValue, foundTypedAuto := annotations[config.TypedAutoAnnotation]
-	excludeConfigmapAnnotationValue, foundExcludeConfigmap := annotations[reloaderOpts.ConfigmapExcludeReloaderAnnotation]
-	excludeSecretAnnotationValue, foundExcludeSecret := annotations[reloaderOpts.SecretExcludeReloaderAnnotation]
-	excludeSecretProviderClassProviderAnnotationValue, foundExcludeSecretProviderClass := annotations[reloaderOpts.SecretProviderClassExcludeReloaderAnnotation]
-
-	if !found && !foundAuto && !foundTypedAuto && !foundSearchAnn {
-		annotations = podAnnotations
-		annotationValue = annotations[config.Annotation]
-		searchAnnotationValue = annotations[reloaderOpts.AutoSearchAnnotation]
-		reloaderEnabledValue = annotations[reloaderOpts.ReloaderAutoAnnotation]
-		typedAutoAnnotationEnabledValue = annotations[config.TypedAutoAnnotation]
-	}
-
-	isResourceExcluded := false
 
+	var excludeAnnotation string
 	switch config.Type {
 	case constants.ConfigmapEnvVarPostfix:
-		if foundExcludeConfigmap {
-			isResourceExcluded = checkIfResourceIsExcluded(config.ResourceName, excludeConfigmapAnnotationValue)
-		}
+		excludeAnnotation = reloaderOpts.ConfigmapExcludeReloaderAnnotation
 	case constants.SecretEnvVarPostfix:
-		if foundExcludeSecret {
-			isResourceExcluded = checkIfResourceIsExcluded(config.ResourceName, excludeSecretAnnotationValue)
-		}
-
+		excludeAnnotation = reloaderOpts.SecretExcludeReloaderAnnotation
 	case constants.SecretProviderClassEnvVarPostfix:
-		if foundExcludeSecretProviderClass {
-			isResourceExcluded = checkIfResourceIsExcluded(config.ResourceName, excludeSecretProviderClassProviderAnnotationValue)
+		excludeAnnotation = reloaderOpts.SecretProviderClassExcludeReloaderAnnotation
+	}
+	if excludeAnnotation != "" {
+		excludeAnnotationValue, _ := lookupAnnotation(excludeAnnotation, annotations, podAnnotations, namespaceAnnotations)
+		if checkIfResourceIsExcluded(config.ResourceName, excludeAnnotationValue) {
+			return ReloadCheckResult{
+				ShouldReload: false,
+			}
 		}
 	}
 
-	if isResourceExcluded {
+	policyRules := GetPolicyRules(config.Namespace, resourceType, workloadLabels)
+	if policyRules.ExcludesSource(config) {
 		return ReloadCheckResult{
 			ShouldReload: false,
 		}
 	}
 
+	for _, fallback := range []Map{podAnnotations, namespaceAnnotations} {
+		if found || foundAuto || foundTypedAuto || foundSearchAnn {
+			break
+		}
+		annotationValue, found = fallback[config.Annotation]
+		searchAnnotationValue, foundSearchAnn = fallback[reloaderOpts.AutoSearchAnnotation]
+		reloaderEnabledValue, foundAuto = fallback[reloaderOpts.ReloaderAutoAnnotation]
+		typedAutoAnnotationEnabledValue, foundTypedAuto = fallback[config.TypedAutoAnnotation]
+	}
+
 	var regexErrors []error
 	values := strings.Split(annotationValue, ",")
 	for _, value := range values {
@@ -314,12 +404,47 @@ func ShouldReload(config Config, resourceType string, annotations Map, podAnnota
 		}
 	}
 
+	// Sources of policies are reloaded like resources named in the reload annotations
+	if policyRules.SelectsSource(config) {
+		return ReloadCheckResult{
+			ShouldReload: true,
+			AutoReload:   false,
+			Errors:       regexErrors,
+		}
+	}
+
 	return ReloadCheckResult{
 		ShouldReload: false,
 		Errors:       regexErrors,
 	}
 }
 
+// isWatchingResource checks whether the watch annotation of a workload or its pod template lists the changed resource
+func isWatchingResource(config Config, annotations Map, podAnnotations Map, reloaderOpts *ReloaderOptions) bool {
+	watchAnnotationValue, found := annotations[reloaderOpts.WatchAnnotation]
+	if !found {
+		watchAnnotationValue = podAnnotations[reloaderOpts.WatchAnnotation]
+	}
+
+	watched := config.SourceNamespace + "/" + config.ResourceName
+	for _, value := range strings.Split(watchAnnotationValue, ",") {
+		if strings.TrimSpace(value) == watched {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupAnnotation returns the value of an annotation from the first of the given annotations defining it
+func lookupAnnotation(key string, annotations ...Map) (string, bool) {
+	for _, a := range annotations {
+		if value, found := a[key]; found {
+			return value, true
+		}
+	}
+	return "", false
+}
+
 func checkIfResourceIsExcluded(resourceName, excludedResources string) bool {
 	if excludedResources == "" {
 		return false
@@ -348,6 +473,8 @@ func GetCommandLineOptions() *ReloaderOptions {
 	CommandLineOptions.ConfigmapUpdateOnChangeAnnotation = options.ConfigmapUpdateOnChangeAnnotation
 	CommandLineOptions.SecretUpdateOnChangeAnnotation = options.SecretUpdateOnChangeAnnotation
 	CommandLineOptions.SecretProviderClassUpdateOnChangeAnnotation = options.SecretProviderClassUpdateOnChangeAnnotation
+	CommandLineOptions.ExternalSecretUpdateOnChangeAnnotation = options.ExternalSecretUpdateOnChangeAnnotation
+	CommandLineOptions.CertificateUpdateOnChangeAnnotation = options.CertificateUpdateOnChangeAnnotation
 	CommandLineOptions.ReloaderAutoAnnotation = options.ReloaderAutoAnnotation
 	CommandLineOptions.IgnoreResourceAnnotation = options.IgnoreResourceAnnotation
 	CommandLineOptions.ConfigmapReloaderAutoAnnotation = options.ConfigmapReloaderAutoAnnotation
@@ -358,15 +485,46 @@ func GetCommandLineOptions() *ReloaderOptions {
 	CommandLineOptions.SecretProviderClassExcludeReloaderAnnotation = options.SecretProviderClassExcludeReloaderAnnotation
 	CommandLineOptions.AutoSearchAnnotation = options.AutoSearchAnnotation
 	CommandLineOptions.SearchMatchAnnotation = options.SearchMatchAnnotation
+	CommandLineOptions.ContentTypeAnnotation = options.ContentTypeAnnotation
 	CommandLineOptions.RolloutStrategyAnnotation = options.RolloutStrategyAnnotation
+	CommandLineOptions.RolloutInProgressAnnotation = options.RolloutInProgressAnnotation
+	CommandLineOptions.ReloadStrategyAnnotation = options.ReloadStrategyAnnotation
+	CommandLineOptions.SignalCommandAnnotation = options.SignalCommandAnnotation
+	CommandLineOptions.HTTPReloadAnnotation = options.HTTPReloadAnnotation
+	CommandLineOptions.HTTPReloadDelayAnnotation = options.HTTPReloadDelayAnnotation
+	CommandLineOptions.ReloadUnmanagedAnnotation = options.ReloadUnmanagedAnnotation
+	CommandLineOptions.JobReloadPolicyAnnotation = options.JobReloadPolicyAnnotation
+	CommandLineOptions.SupersededJobTTLAnnotation = options.SupersededJobTTLAnnotation
+	CommandLineOptions.CronJobReloadPolicyAnnotation = options.CronJobReloadPolicyAnnotation
+	CommandLineOptions.GitOpsSyncAnnotation = options.GitOpsSyncAnnotation
+	CommandLineOptions.WatchAnnotation = options.WatchAnnotation
+	CommandLineOptions.AllowWatchFromAnnotation = options.AllowWatchFromAnnotation
+	CommandLineOptions.GenerationOfLabel = options.GenerationOfLabel
 	CommandLineOptions.PauseDeploymentAnnotation = options.PauseDeploymentAnnotation
 	CommandLineOptions.PauseDeploymentTimeAnnotation = options.PauseDeploymentTimeAnnotation
 	CommandLineOptions.LogFormat = options.LogFormat
 	CommandLineOptions.LogLevel = options.LogLevel
 	CommandLineOptions.ReloadStrategy = options.ReloadStrategy
+	CommandLineOptions.SignalTimeout = options.SignalTimeout.String()
+	CommandLineOptions.DeletePodsTimeout = options.DeletePodsTimeout.String()
+	CommandLineOptions.SupersededJobTTL = options.SupersededJobTTL.String()
 	CommandLineOptions.SyncAfterRestart = options.SyncAfterRestart
 	CommandLineOptions.EnableHA = options.EnableHA
+	CommandLineOptions.EnableNamespaceAnnotations = options.EnableNamespaceAnnotations
+	CommandLineOptions.WatchImagePullSecrets = options.WatchImagePullSecrets
+	CommandLineOptions.HashAlgorithm = options.HashAlgorithm
+	CommandLineOptions.HashKeySecret = options.HashKeySecret
 	CommandLineOptions.EnableCSIIntegration = options.EnableCSIIntegration
+	CommandLineOptions.EnableExternalSecretsIntegration = options.EnableExternalSecretsIntegration
+	CommandLineOptions.EnableCertManagerIntegration = options.EnableCertManagerIntegration
+	CommandLineOptions.EnableReloaderPolicies = options.EnableReloaderPolicies
+	CommandLineOptions.EnablePolicyDebug = options.EnablePolicyDebug
+	CommandLineOptions.ReloadUnmanagedWorkloads = options.ReloadUnmanagedWorkloads
+	CommandLineOptions.GitOpsSync = options.GitOpsSync
+	CommandLineOptions.GitOpsValuesKey = options.GitOpsValuesKey
+	CommandLineOptions.ArgoCDNamespace = options.ArgoCDNamespace
+	CommandLineOptions.FollowGenerations = options.FollowGenerations
+	CommandLineOptions.PruneGenerations = options.PruneGenerations
 	CommandLineOptions.WebhookUrl = options.WebhookUrl
 	CommandLineOptions.ResourcesToIgnore = options.ResourcesToIgnore
 	CommandLineOptions.WorkloadTypesToIgnore = options.WorkloadTypesToIgnore
@@ -376,6 +534,13 @@ func GetCommandLineOptions() *ReloaderOptions {
 	CommandLineOptions.IsArgoRollouts = parseBool(options.IsArgoRollouts)
 	CommandLineOptions.ReloadOnCreate = parseBool(options.ReloadOnCreate)
 	CommandLineOptions.ReloadOnDelete = parseBool(options.ReloadOnDelete)
+	CommandLineOptions.AlertOnReload = options.AlertOnReload
+	CommandLineOptions.AlertSink = options.AlertSink
+	CommandLineOptions.AlertWebhookURL = options.AlertWebhookURL
+	CommandLineOptions.AlertWebhookURLSet = options.AlertWebhookURL != ""
+	CommandLineOptions.AlertWebhookProxy = options.AlertWebhookProxy
+	CommandLineOptions.AlertWebhookProxySet = options.AlertWebhookProxy != ""
+	CommandLineOptions.AlertAdditionalInfo = options.AlertAdditionalInfo
 	CommandLineOptions.EnablePProf = options.EnablePProf
 	CommandLineOptions.PProfAddr = options.PProfAddr
 
@@ -2,6 +2,7 @@ package common
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	csiv1 "sigs.k8s.io/secrets-store-csi-driver/apis/v1"
 
 	"github.com/stakater/Reloader/internal/pkg/constants"
@@ -11,7 +12,11 @@ import (
 
 // Config contains rolling upgrade configuration parameters
 type Config struct {
-	Namespace           string
+	// Namespace is the namespace of the workloads to reload
+	Namespace string
+	// SourceNamespace is the namespace of the changed resource if it differs from the namespace of the workloads,
+	// which only reload it when watching it explicitly
+	SourceNamespace     string
 	ResourceName        string
 	ResourceAnnotations map[string]string
 	Annotation          string
@@ -19,6 +24,9 @@ type Config struct {
 	SHAValue            string
 	Type                string
 	Labels              map[string]string
+	// LegacySHAValue is the SHA-1 of the resource written by earlier versions, set when another hash algorithm is
+	// configured to recognize workloads already reloaded with the current data
+	LegacySHAValue string
 }
 
 // GetConfigmapConfig provides utility config for configmap
@@ -30,6 +38,7 @@ func GetConfigmapConfig(configmap *v1.ConfigMap) Config {
 		Annotation:          options.ConfigmapUpdateOnChangeAnnotation,
 		TypedAutoAnnotation: options.ConfigmapReloaderAutoAnnotation,
 		SHAValue:            util.GetSHAfromConfigmap(configmap),
+		LegacySHAValue:      util.GetLegacySHAfromConfigmap(configmap),
 		Type:                constants.ConfigmapEnvVarPostfix,
 		Labels:              configmap.Labels,
 	}
@@ -44,6 +53,7 @@ func GetSecretConfig(secret *v1.Secret) Config {
 		Annotation:          options.SecretUpdateOnChangeAnnotation,
 		TypedAutoAnnotation: options.SecretReloaderAutoAnnotation,
 		SHAValue:            util.GetSHAfromSecret(secret.Data),
+		LegacySHAValue:      util.GetLegacySHAfromSecret(secret.Data),
 		Type:                constants.SecretEnvVarPostfix,
 		Labels:              secret.Labels,
 	}
@@ -58,6 +68,35 @@ func GetSecretProviderClassPodStatusConfig(podStatus *csiv1.SecretProviderClassP
 		Annotation:          options.SecretProviderClassUpdateOnChangeAnnotation,
 		TypedAutoAnnotation: options.SecretProviderClassReloaderAutoAnnotation,
 		SHAValue:            util.GetSHAfromSecretProviderClassPodStatus(podStatus.Status),
+		LegacySHAValue:      util.GetLegacySHAfromSecretProviderClassPodStatus(podStatus.Status),
 		Type:                constants.SecretProviderClassEnvVarPostfix,
 	}
 }
+
+// GetExternalSecretConfig provides utility config for an ExternalSecret, shaValue is the SHA of the data it synced
+// into its target secret
+func GetExternalSecretConfig(externalSecret *unstructured.Unstructured, shaValue string) Config {
+	return Config{
+		Namespace:           externalSecret.GetNamespace(),
+		ResourceName:        externalSecret.GetName(),
+		ResourceAnnotations: externalSecret.GetAnnotations(),
+		Annotation:          options.ExternalSecretUpdateOnChangeAnnotation,
+		SHAValue:            shaValue,
+		Type:                constants.ExternalSecretEnvVarPostfix,
+		Labels:              externalSecret.GetLabels(),
+	}
+}
+
+// GetCertificateConfig provides utility config for a cert-manager Certificate, shaValue is the fingerprint of the
+// certificate issued into its secret
+func GetCertificateConfig(certificate *unstructured.Unstructured, shaValue string) Config {
+	return Config{
+		Namespace:           certificate.GetNamespace(),
+		ResourceName:        certificate.GetName(),
+		ResourceAnnotations: certificate.GetAnnotations(),
+		Annotation:          options.CertificateUpdateOnChangeAnnotation,
+		SHAValue:            shaValue,
+		Type:                constants.CertificateEnvVarPostfix,
+		Labels:              certificate.GetLabels(),
+	}
+}
@@ -0,0 +1,55 @@
+package common
+
+import "sync"
+
+// namespaceAnnotations holds the annotations of the watched namespaces, keyed by name. It is written by the namespace
+// controller and read when checking whether the workloads of a namespace should be reloaded.
+var namespaceAnnotations sync.Map
+
+// SetNamespaceAnnotations remembers the annotations of an added or updated namespace
+func SetNamespaceAnnotations(namespace string, annotations map[string]string) {
+	if len(annotations) == 0 {
+		namespaceAnnotations.Delete(namespace)
+		return
+	}
+	namespaceAnnotations.Store(namespace, Map(annotations))
+}
+
+// DeleteNamespaceAnnotations forgets the annotations of a deleted namespace
+func DeleteNamespaceAnnotations(namespace string) {
+	namespaceAnnotations.Delete(namespace)
+}
+
+// GetNamespaceAnnotations returns the annotations of a namespace, empty if unknown
+func GetNamespaceAnnotations(namespace string) Map {
+	if annotations, ok := namespaceAnnotations.Load(namespace); ok {
+		return annotations.(Map)
+	}
+	return Map{}
+}
+
+// namespaceLabels holds the labels of the watched namespaces, keyed by name. It is written by the namespace controller
+// and read when checking which ClusterReloaderPolicies select the workloads of a namespace.
+var namespaceLabels sync.Map
+
+// SetNamespaceLabels remembers the labels of an added or updated namespace
+func SetNamespaceLabels(namespace string, labels map[string]string) {
+	if len(labels) == 0 {
+		namespaceLabels.Delete(namespace)
+		return
+	}
+	namespaceLabels.Store(namespace, Map(labels))
+}
+
+// DeleteNamespaceLabels forgets the labels of a deleted namespace
+func DeleteNamespaceLabels(namespace string) {
+	namespaceLabels.Delete(namespace)
+}
+
+// GetNamespaceLabels returns the labels of a namespace, empty if unknown
+func GetNamespaceLabels(namespace string) Map {
+	if labels, ok := namespaceLabels.Load(namespace); ok {
+		return labels.(Map)
+	}
+	return Map{}
+}
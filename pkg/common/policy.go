@@ -0,0 +1,258 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
+)
+
+// policySourceKinds are the kinds of the sources selected by ReloaderPolicies, keyed by the type of the changed resource
+var policySourceKinds = map[string]string{
+	constants.ConfigmapEnvVarPostfix:           "ConfigMap",
+	constants.SecretEnvVarPostfix:              "Secret",
+	constants.SecretProviderClassEnvVarPostfix: "SecretProviderClass",
+	constants.ExternalSecretEnvVarPostfix:      "ExternalSecret",
+	constants.CertificateEnvVarPostfix:         "Certificate",
+}
+
+// reloaderPolicies holds the valid ReloaderPolicies by namespace and name, and the ClusterReloaderPolicies by name under
+// the empty namespace. It is written by the policy controllers and read when checking whether a workload should be
+// reloaded.
+var (
+	reloaderPolicies      = map[string]map[string]*compiledPolicy{}
+	reloaderPoliciesMutex sync.RWMutex
+)
+
+// compiledPolicy is a ReloaderPolicy or ClusterReloaderPolicy with parsed selectors and patterns
+type compiledPolicy struct {
+	name              string
+	namespaceSelector labels.Selector
+	kinds             []string
+	workloadSelector  labels.Selector
+	sources           []sourceSelector
+	exclude           []sourceSelector
+	spec              v1alpha1.ReloaderPolicySpec
+}
+
+// sourceSelector is a parsed SourceSelector of a ReloaderPolicy
+type sourceSelector struct {
+	kind      string
+	name      string
+	nameRegex *regexp.Regexp
+	selector  labels.Selector
+}
+
+// PolicyRules are the merged rules of the ReloaderPolicies and ClusterReloaderPolicies selecting a workload
+type PolicyRules struct {
+	// Policies are the names of the ReloaderPolicies selecting the workload
+	Policies []string `json:"policies,omitempty"`
+	// ClusterPolicies are the names of the ClusterReloaderPolicies selecting the workload
+	ClusterPolicies []string `json:"clusterPolicies,omitempty"`
+	// Strategy is the reload strategy of the first policy defining one, empty if none does
+	Strategy string `json:"strategy,omitempty"`
+	// PausePeriod is the pause period of the first policy defining one
+	PausePeriod *metav1.Duration `json:"pausePeriod,omitempty"`
+	// Debounce is the debounce period of the first policy defining one
+	Debounce *metav1.Duration `json:"debounce,omitempty"`
+	// Sources are the combined sources of the policies
+	Sources []v1alpha1.SourceSelector `json:"sources,omitempty"`
+	// Exclude are the combined exclusions of the policies
+	Exclude []v1alpha1.SourceSelector `json:"exclude,omitempty"`
+
+	sourceSelectors  []sourceSelector
+	excludeSelectors []sourceSelector
+}
+
+// SetReloaderPolicy remembers an added or updated ReloaderPolicy. Policies with an invalid selector or pattern are
+// forgotten and the error is returned.
+func SetReloaderPolicy(policy *v1alpha1.ReloaderPolicy) error {
+	compiled, err := compilePolicy(policy.Name, policy.Spec)
+	return storePolicy(policy.Namespace, policy.Name, compiled, err)
+}
+
+// SetClusterReloaderPolicy remembers an added or updated ClusterReloaderPolicy. Policies with an invalid selector or
+// pattern are forgotten and the error is returned.
+func SetClusterReloaderPolicy(policy *v1alpha1.ClusterReloaderPolicy) error {
+	compiled, err := compilePolicy(policy.Name, policy.Spec.ReloaderPolicySpec)
+	if err == nil && policy.Spec.NamespaceSelector != nil {
+		compiled.namespaceSelector, err = metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+		if err != nil {
+			err = fmt.Errorf("invalid namespace selector: %w", err)
+		}
+	}
+	return storePolicy("", policy.Name, compiled, err)
+}
+
+func storePolicy(namespace, name string, compiled *compiledPolicy, err error) error {
+	reloaderPoliciesMutex.Lock()
+	defer reloaderPoliciesMutex.Unlock()
+	if err != nil {
+		delete(reloaderPolicies[namespace], name)
+		return err
+	}
+	if reloaderPolicies[namespace] == nil {
+		reloaderPolicies[namespace] = map[string]*compiledPolicy{}
+	}
+	reloaderPolicies[namespace][name] = compiled
+	return nil
+}
+
+// DeleteClusterReloaderPolicy forgets a deleted ClusterReloaderPolicy
+func DeleteClusterReloaderPolicy(name string) {
+	DeleteReloaderPolicy("", name)
+}
+
+// DeleteReloaderPolicy forgets a deleted ReloaderPolicy
+func DeleteReloaderPolicy(namespace, name string) {
+	reloaderPoliciesMutex.Lock()
+	defer reloaderPoliciesMutex.Unlock()
+	delete(reloaderPolicies[namespace], name)
+	if len(reloaderPolicies[namespace]) == 0 {
+		delete(reloaderPolicies, namespace)
+	}
+}
+
+// GetPolicyRules returns the merged rules of the ReloaderPolicies of a namespace and the ClusterReloaderPolicies selecting
+// a workload. ReloaderPolicies are applied before ClusterReloaderPolicies, each in the order of their names: the first
+// policy setting the strategy, pause period or debounce wins, while the sources and exclusions of all policies are
+// combined.
+func GetPolicyRules(namespace, kind string, workloadLabels map[string]string) PolicyRules {
+	reloaderPoliciesMutex.RLock()
+	defer reloaderPoliciesMutex.RUnlock()
+
+	rules := PolicyRules{}
+	if namespace != "" {
+		for _, policy := range sortedPolicies(reloaderPolicies[namespace]) {
+			if policy.selectsWorkload(kind, workloadLabels) {
+				rules.Policies = append(rules.Policies, policy.name)
+				rules.merge(policy)
+			}
+		}
+	}
+
+	namespaceLabels := labels.Set(GetNamespaceLabels(namespace))
+	for _, policy := range sortedPolicies(reloaderPolicies[""]) {
+		if policy.namespaceSelector != nil && !policy.namespaceSelector.Matches(namespaceLabels) {
+			continue
+		}
+		if policy.selectsWorkload(kind, workloadLabels) {
+			rules.ClusterPolicies = append(rules.ClusterPolicies, policy.name)
+			rules.merge(policy)
+		}
+	}
+	return rules
+}
+
+// SelectsSource checks whether a source of the policies selecting a workload matches the changed resource
+func (r PolicyRules) SelectsSource(config Config) bool {
+	return slices.ContainsFunc(r.sourceSelectors, func(source sourceSelector) bool { return source.matches(config) })
+}
+
+// ExcludesSource checks whether an exclusion of the policies selecting a workload matches the changed resource
+func (r PolicyRules) ExcludesSource(config Config) bool {
+	return slices.ContainsFunc(r.excludeSelectors, func(source sourceSelector) bool { return source.matches(config) })
+}
+
+func (r *PolicyRules) merge(policy *compiledPolicy) {
+	r.Sources = append(r.Sources, policy.spec.Sources...)
+	r.Exclude = append(r.Exclude, policy.spec.Exclude...)
+	r.sourceSelectors = append(r.sourceSelectors, policy.sources...)
+	r.excludeSelectors = append(r.excludeSelectors, policy.exclude...)
+	if r.Strategy == "" {
+		r.Strategy = policy.spec.Strategy
+	}
+	if r.PausePeriod == nil {
+		r.PausePeriod = policy.spec.PausePeriod
+	}
+	if r.Debounce == nil {
+		r.Debounce = policy.spec.Debounce
+	}
+}
+
+func sortedPolicies(policies map[string]*compiledPolicy) []*compiledPolicy {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	sorted := make([]*compiledPolicy, 0, len(names))
+	for _, name := range names {
+		sorted = append(sorted, policies[name])
+	}
+	return sorted
+}
+
+func compilePolicy(name string, spec v1alpha1.ReloaderPolicySpec) (*compiledPolicy, error) {
+	compiled := &compiledPolicy{
+		name:             name,
+		kinds:            spec.WorkloadSelector.Kinds,
+		workloadSelector: labels.Everything(),
+		spec:             spec,
+	}
+
+	var err error
+	if spec.WorkloadSelector.Selector != nil {
+		compiled.workloadSelector, err = metav1.LabelSelectorAsSelector(spec.WorkloadSelector.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workload selector: %w", err)
+		}
+	}
+	if compiled.sources, err = compileSourceSelectors(spec.Sources, "sources"); err != nil {
+		return nil, err
+	}
+	if compiled.exclude, err = compileSourceSelectors(spec.Exclude, "exclude"); err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+func compileSourceSelectors(selectors []v1alpha1.SourceSelector, field string) ([]sourceSelector, error) {
+	compiled := make([]sourceSelector, 0, len(selectors))
+	for i, selector := range selectors {
+		source := sourceSelector{kind: selector.Kind, name: selector.Name}
+		if selector.NameRegex != "" {
+			re, err := regexp.Compile("^(?:" + selector.NameRegex + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid name regex %q in %s[%d]: %w", selector.NameRegex, field, i, err)
+			}
+			source.nameRegex = re
+		}
+		if selector.Selector != nil {
+			labelSelector, err := metav1.LabelSelectorAsSelector(selector.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector in %s[%d]: %w", field, i, err)
+			}
+			source.selector = labelSelector
+		}
+		compiled = append(compiled, source)
+	}
+	return compiled, nil
+}
+
+func (p *compiledPolicy) selectsWorkload(kind string, workloadLabels map[string]string) bool {
+	if len(p.kinds) > 0 && !slices.Contains(p.kinds, kind) {
+		return false
+	}
+	return p.workloadSelector.Matches(labels.Set(workloadLabels))
+}
+
+func (s sourceSelector) matches(config Config) bool {
+	if s.kind != "" && s.kind != policySourceKinds[config.Type] {
+		return false
+	}
+	if s.name != "" && s.name != config.ResourceName {
+		return false
+	}
+	if s.nameRegex != nil && !s.nameRegex.MatchString(config.ResourceName) {
+		return false
+	}
+	return s.selector == nil || s.selector.Matches(labels.Set(config.Labels))
+}
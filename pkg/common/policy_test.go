@@ -0,0 +1,255 @@
+package common
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stakater/Reloader/internal/pkg/constants"
+	"github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
+)
+
+func setTestReloaderPolicies(t *testing.T, policies ...*v1alpha1.ReloaderPolicy) {
+	t.Helper()
+	for _, policy := range policies {
+		if err := SetReloaderPolicy(policy); err != nil {
+			t.Fatalf("Unexpected error setting ReloaderPolicy %s: %v", policy.Name, err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, policy := range policies {
+			DeleteReloaderPolicy(policy.Namespace, policy.Name)
+		}
+	})
+}
+
+func TestGetPolicyRules(t *testing.T) {
+	setTestReloaderPolicies(t,
+		&v1alpha1.ReloaderPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "b-payments", Namespace: "team-a"},
+			Spec: v1alpha1.ReloaderPolicySpec{
+				WorkloadSelector: v1alpha1.WorkloadSelector{
+					Kinds:    []string{"Deployment"},
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "payments"}},
+				},
+				Strategy:    "restarted-at",
+				PausePeriod: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+		},
+		&v1alpha1.ReloaderPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-all", Namespace: "team-a"},
+			Spec: v1alpha1.ReloaderPolicySpec{
+				Debounce: &metav1.Duration{Duration: 30 * time.Second},
+			},
+		},
+		&v1alpha1.ReloaderPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "team-b"},
+			Spec:       v1alpha1.ReloaderPolicySpec{Strategy: "delete-pods"},
+		},
+	)
+
+	tests := []struct {
+		name             string
+		kind             string
+		labels           map[string]string
+		expectedPolicies []string
+		expectedStrategy string
+		expectedPause    time.Duration
+	}{
+		{
+			name:             "All policies selecting the workload are merged",
+			kind:             "Deployment",
+			labels:           map[string]string{"app": "payments"},
+			expectedPolicies: []string{"a-all", "b-payments"},
+			expectedStrategy: "restarted-at",
+			expectedPause:    5 * time.Minute,
+		},
+		{
+			name:             "Kind not selected",
+			kind:             "StatefulSet",
+			labels:           map[string]string{"app": "payments"},
+			expectedPolicies: []string{"a-all"},
+		},
+		{
+			name:             "Labels not selected",
+			kind:             "Deployment",
+			labels:           map[string]string{"app": "orders"},
+			expectedPolicies: []string{"a-all"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := GetPolicyRules("team-a", tt.kind, tt.labels)
+			if !slices.Equal(rules.Policies, tt.expectedPolicies) {
+				t.Errorf("Expected policies %v, got %v", tt.expectedPolicies, rules.Policies)
+			}
+			if rules.Strategy != tt.expectedStrategy {
+				t.Errorf("Expected strategy %q, got %q", tt.expectedStrategy, rules.Strategy)
+			}
+			if rules.Debounce == nil || rules.Debounce.Duration != 30*time.Second {
+				t.Errorf("Expected debounce of 30s, got %v", rules.Debounce)
+			}
+			var pause time.Duration
+			if rules.PausePeriod != nil {
+				pause = rules.PausePeriod.Duration
+			}
+			if pause != tt.expectedPause {
+				t.Errorf("Expected pause period %v, got %v", tt.expectedPause, pause)
+			}
+		})
+	}
+}
+
+func TestSetReloaderPolicy_InvalidPolicyIsForgotten(t *testing.T) {
+	policy := &v1alpha1.ReloaderPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "team-a"},
+		Spec:       v1alpha1.ReloaderPolicySpec{Strategy: "restarted-at"},
+	}
+	setTestReloaderPolicies(t, policy)
+
+	invalid := policy.DeepCopy()
+	invalid.Spec.Sources = []v1alpha1.SourceSelector{{NameRegex: "payments-("}}
+	if err := SetReloaderPolicy(invalid); err == nil {
+		t.Fatalf("Expected an error for an invalid name regex")
+	}
+
+	if rules := GetPolicyRules("team-a", "Deployment", nil); len(rules.Policies) != 0 {
+		t.Errorf("Expected the invalid policy to be forgotten, got %v", rules.Policies)
+	}
+}
+
+func TestShouldReload_ReloaderPolicy(t *testing.T) {
+	setTestReloaderPolicies(t, &v1alpha1.ReloaderPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "team-a"},
+		Spec: v1alpha1.ReloaderPolicySpec{
+			WorkloadSelector: v1alpha1.WorkloadSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "payments"}},
+			},
+			Sources: []v1alpha1.SourceSelector{
+				{Kind: "ConfigMap", NameRegex: "payments-.*"},
+				{Kind: "Secret", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}},
+			},
+			Exclude: []v1alpha1.SourceSelector{{Kind: "Secret", Name: "payments-cache"}},
+		},
+	})
+
+	opts := &ReloaderOptions{
+		ReloaderAutoAnnotation:          "reloader.stakater.com/auto",
+		ConfigmapReloaderAutoAnnotation: "configmap.reloader.stakater.com/auto",
+		SecretReloaderAutoAnnotation:    "secret.reloader.stakater.com/auto",
+	}
+
+	tests := []struct {
+		name           string
+		config         Config
+		annotations    Map
+		workloadLabels Map
+		shouldReload   bool
+	}{
+		{
+			name:           "Source selected by name regex",
+			config:         Config{Namespace: "team-a", ResourceName: "payments-config", Type: constants.ConfigmapEnvVarPostfix},
+			annotations:    Map{},
+			workloadLabels: Map{"app": "payments"},
+			shouldReload:   true,
+		},
+		{
+			name:           "Source of another kind",
+			config:         Config{Namespace: "team-a", ResourceName: "payments-config", Type: constants.SecretEnvVarPostfix},
+			annotations:    Map{},
+			workloadLabels: Map{"app": "payments"},
+			shouldReload:   false,
+		},
+		{
+			name:           "Source selected by labels",
+			config:         Config{Namespace: "team-a", ResourceName: "db", Type: constants.SecretEnvVarPostfix, Labels: map[string]string{"team": "payments"}},
+			annotations:    Map{},
+			workloadLabels: Map{"app": "payments"},
+			shouldReload:   true,
+		},
+		{
+			name:           "Workload not selected",
+			config:         Config{Namespace: "team-a", ResourceName: "payments-config", Type: constants.ConfigmapEnvVarPostfix},
+			annotations:    Map{},
+			workloadLabels: Map{"app": "orders"},
+			shouldReload:   false,
+		},
+		{
+			name:           "Excluded source named in the reload annotation",
+			config:         Config{Namespace: "team-a", ResourceName: "payments-cache", Type: constants.SecretEnvVarPostfix, Annotation: "secret.reloader.stakater.com/reload"},
+			annotations:    Map{"secret.reloader.stakater.com/reload": "payments-cache"},
+			workloadLabels: Map{"app": "payments"},
+			shouldReload:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ShouldReload(tt.config, "Deployment", tt.annotations, Map{}, tt.workloadLabels, opts)
+			if result.ShouldReload != tt.shouldReload {
+				t.Errorf("Expected ShouldReload=%v, got=%v", tt.shouldReload, result.ShouldReload)
+			}
+			if result.AutoReload {
+				t.Errorf("Expected no auto reload for policy sources")
+			}
+		})
+	}
+}
+
+func TestGetPolicyRules_ClusterReloaderPolicies(t *testing.T) {
+	SetNamespaceLabels("kube-system", map[string]string{"kubernetes.io/metadata.name": "kube-system"})
+	defer DeleteNamespaceLabels("kube-system")
+	setTestReloaderPolicies(t, &v1alpha1.ReloaderPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "team-a"},
+		Spec:       v1alpha1.ReloaderPolicySpec{Strategy: "restarted-at"},
+	})
+	for _, policy := range []*v1alpha1.ClusterReloaderPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "databases"},
+			Spec: clusterPolicySpec(nil, v1alpha1.ReloaderPolicySpec{
+				WorkloadSelector: v1alpha1.WorkloadSelector{Kinds: []string{"StatefulSet"}},
+				Strategy:         "delete-pods",
+				PausePeriod:      &metav1.Duration{Duration: 5 * time.Minute},
+			}),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-system"},
+			Spec: clusterPolicySpec(&metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "kube-system"}}, v1alpha1.ReloaderPolicySpec{
+				Exclude: []v1alpha1.SourceSelector{{}},
+			}),
+		},
+	} {
+		if err := SetClusterReloaderPolicy(policy); err != nil {
+			t.Fatalf("Unexpected error setting ClusterReloaderPolicy %s: %v", policy.Name, err)
+		}
+		defer DeleteClusterReloaderPolicy(policy.Name)
+	}
+
+	rules := GetPolicyRules("team-a", "StatefulSet", nil)
+	if !slices.Equal(rules.Policies, []string{"payments"}) || !slices.Equal(rules.ClusterPolicies, []string{"databases"}) {
+		t.Errorf("Expected policy payments and cluster policy databases, got %v and %v", rules.Policies, rules.ClusterPolicies)
+	}
+	if rules.Strategy != "restarted-at" {
+		t.Errorf("Expected the strategy of the namespace policy to win, got %q", rules.Strategy)
+	}
+	if rules.PausePeriod == nil || rules.PausePeriod.Duration != 5*time.Minute {
+		t.Errorf("Expected the pause period of the cluster policy, got %v", rules.PausePeriod)
+	}
+
+	config := Config{Namespace: "kube-system", ResourceName: "coredns", Type: constants.ConfigmapEnvVarPostfix}
+	if rules := GetPolicyRules("kube-system", "Deployment", nil); !rules.ExcludesSource(config) {
+		t.Errorf("Expected all sources to be excluded in kube-system, got cluster policies %v", rules.ClusterPolicies)
+	}
+	config.Namespace = "team-a"
+	if rules := GetPolicyRules("team-a", "Deployment", nil); rules.ExcludesSource(config) {
+		t.Errorf("Expected no sources to be excluded in team-a, got cluster policies %v", rules.ClusterPolicies)
+	}
+}
+
+// clusterPolicySpec returns the spec of a ClusterReloaderPolicy selecting namespaces by labels
+func clusterPolicySpec(namespaceSelector *metav1.LabelSelector, spec v1alpha1.ReloaderPolicySpec) v1alpha1.ClusterReloaderPolicySpec {
+	return v1alpha1.ClusterReloaderPolicySpec{NamespaceSelector: namespaceSelector, ReloaderPolicySpec: spec}
+}
@@ -9,9 +9,13 @@ import (
 	argorollout "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
 	appsclient "github.com/openshift/client-go/apps/clientset/versioned"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	csiclient "sigs.k8s.io/secrets-store-csi-driver/pkg/client/clientset/versioned"
+
+	reloaderclient "github.com/stakater/Reloader/pkg/client/clientset/versioned"
 )
 
 // Clients struct exposes interfaces for kubernetes as well as openshift if available
@@ -20,6 +24,12 @@ type Clients struct {
 	OpenshiftAppsClient appsclient.Interface
 	ArgoRolloutClient   argorollout.Interface
 	CSIClient           csiclient.Interface
+	// ReloaderClient is used for the custom resources of Reloader, e.g. ReloaderPolicies
+	ReloaderClient reloaderclient.Interface
+	// DynamicClient is used for custom resources without typed clients, e.g. Knative Services
+	DynamicClient dynamic.Interface
+	// RESTConfig is used for requests not covered by the typed clients, e.g. executing commands in pods
+	RESTConfig *rest.Config
 }
 
 var (
@@ -27,6 +37,21 @@ var (
 	IsOpenshift = isOpenshift()
 	// IsCSIEnabled is true if environment has CSI provider installed, otherwise false
 	IsCSIInstalled = isCSIInstalled()
+	// IsKnativeInstalled is true if environment has Knative Serving installed, otherwise false
+	IsKnativeInstalled = isKnativeInstalled()
+	// IsExternalSecretsInstalled is true if environment has External Secrets Operator installed, otherwise false
+	IsExternalSecretsInstalled = isExternalSecretsInstalled()
+	// IsCertManagerInstalled is true if environment has cert-manager installed, otherwise false
+	IsCertManagerInstalled = isCertManagerInstalled()
+	// IsReloaderPolicyInstalled is true if environment has the ReloaderPolicy CRD installed, otherwise false
+	IsReloaderPolicyInstalled = isReloaderPolicyInstalled()
+)
+
+var (
+	// ExternalSecretResource is the API resource of External Secrets Operator ExternalSecrets
+	ExternalSecretResource = schema.GroupVersionResource{Group: "external-secrets.io", Version: "v1", Resource: "externalsecrets"}
+	// CertificateResource is the API resource of cert-manager Certificates
+	CertificateResource = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
 )
 
 // GetClients returns a `Clients` object containing both openshift and kubernetes clients with an openshift identifier
@@ -61,11 +86,41 @@ func GetClients() Clients {
 		}
 	}
 
+	// Declared as the interface so that the field stays nil without the CRD, as checked by the policy handlers
+	var reloaderClient reloaderclient.Interface
+
+	if IsReloaderPolicyInstalled {
+		policyClient, err := GetReloaderClient()
+		if err != nil {
+			logrus.Warnf("Unable to create Reloader client error = %v", err)
+		} else {
+			reloaderClient = policyClient
+		}
+	}
+
+	restConfig, err := getConfig()
+	if err != nil {
+		logrus.Warnf("Unable to create REST config error = %v", err)
+	}
+
+	// Declared as the interface so that the field stays nil without REST config
+	var dynamicClient dynamic.Interface
+
+	if restConfig != nil {
+		dynamicClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			logrus.Warnf("Unable to create dynamic client error = %v", err)
+		}
+	}
+
 	return Clients{
 		KubernetesClient:    client,
 		OpenshiftAppsClient: appsClient,
 		ArgoRolloutClient:   rolloutClient,
 		CSIClient:           csiClient,
+		ReloaderClient:      reloaderClient,
+		DynamicClient:       dynamicClient,
+		RESTConfig:          restConfig,
 	}
 }
 
@@ -99,6 +154,80 @@ func GetCSIClient() (*csiclient.Clientset, error) {
 	return csiclient.NewForConfig(config)
 }
 
+func isKnativeInstalled() bool {
+	client, err := GetKubernetesClient()
+	if err != nil {
+		logrus.Fatalf("Unable to create Kubernetes client error = %v", err)
+	}
+	_, err = client.RESTClient().Get().AbsPath("/apis/serving.knative.dev/v1").Do(context.TODO()).Raw()
+	if err == nil {
+		logrus.Info("Knative Serving is installed")
+		return true
+	}
+	logrus.Info("Knative Serving is not installed")
+	return false
+}
+
+func isExternalSecretsInstalled() bool {
+	client, err := GetKubernetesClient()
+	if err != nil {
+		logrus.Fatalf("Unable to create Kubernetes client error = %v", err)
+	}
+	_, err = client.RESTClient().Get().AbsPath("/apis/external-secrets.io/v1").Do(context.TODO()).Raw()
+	if err == nil {
+		logrus.Info("External Secrets Operator is installed")
+		return true
+	}
+	logrus.Info("External Secrets Operator is not installed")
+	return false
+}
+
+func isCertManagerInstalled() bool {
+	client, err := GetKubernetesClient()
+	if err != nil {
+		logrus.Fatalf("Unable to create Kubernetes client error = %v", err)
+	}
+	_, err = client.RESTClient().Get().AbsPath("/apis/cert-manager.io/v1").Do(context.TODO()).Raw()
+	if err == nil {
+		logrus.Info("cert-manager is installed")
+		return true
+	}
+	logrus.Info("cert-manager is not installed")
+	return false
+}
+
+func isReloaderPolicyInstalled() bool {
+	client, err := GetKubernetesClient()
+	if err != nil {
+		logrus.Fatalf("Unable to create Kubernetes client error = %v", err)
+	}
+	_, err = client.RESTClient().Get().AbsPath("/apis/reloader.stakater.com/v1alpha1").Do(context.TODO()).Raw()
+	if err == nil {
+		logrus.Info("ReloaderPolicy CRD is installed")
+		return true
+	}
+	logrus.Info("ReloaderPolicy CRD is not installed")
+	return false
+}
+
+// GetReloaderClient returns a client for the custom resources of Reloader
+func GetReloaderClient() (*reloaderclient.Clientset, error) {
+	config, err := getConfig()
+	if err != nil {
+		return nil, err
+	}
+	return reloaderclient.NewForConfig(config)
+}
+
+// GetDynamicClient returns a client for custom resources without typed clients
+func GetDynamicClient() (*dynamic.DynamicClient, error) {
+	config, err := getConfig()
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(config)
+}
+
 func isOpenshift() bool {
 	client, err := GetKubernetesClient()
 	if err != nil {
@@ -2,8 +2,11 @@ package kube
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	csiv1 "sigs.k8s.io/secrets-store-csi-driver/apis/v1"
+
+	reloaderv1alpha1 "github.com/stakater/Reloader/pkg/apis/reloader/v1alpha1"
 )
 
 // ResourceMap are resources from where changes are going to be detected
@@ -12,4 +15,8 @@ var ResourceMap = map[string]runtime.Object{
 	"secrets":                        &v1.Secret{},
 	"namespaces":                     &v1.Namespace{},
 	"secretproviderclasspodstatuses": &csiv1.SecretProviderClassPodStatus{},
+	"externalsecrets":                &unstructured.Unstructured{},
+	"certificates":                   &unstructured.Unstructured{},
+	"reloaderpolicies":               &reloaderv1alpha1.ReloaderPolicy{},
+	"clusterreloaderpolicies":        &reloaderv1alpha1.ClusterReloaderPolicy{},
 }